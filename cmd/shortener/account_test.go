@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/serg2014/shortener/internal/app"
+	"github.com/serg2014/shortener/internal/auth"
+	"github.com/serg2014/shortener/internal/config"
+	"github.com/serg2014/shortener/internal/models"
+	"github.com/serg2014/shortener/storage"
+)
+
+func TestAccounts(t *testing.T) {
+	store, err := storage.NewStorageMemory()
+	require.NoError(t, err)
+	a := app.NewApp(store, nil)
+	ts := httptest.NewServer(Router(a, config.TrustedSubnet{}, config.ACL{}, SecurityOptions{}))
+	defer ts.Close()
+
+	newRequest := func(method, url, body string, cookie *http.Cookie) *http.Request {
+		req, err := http.NewRequest(method, ts.URL+url, strings.NewReader(body))
+		require.NoError(t, err)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		return req
+	}
+	// anonymous пользователь создает урл и получает куку
+	anonymous := func(url string) *http.Cookie {
+		resp, _ := testRequest(t, ts, newRequest(http.MethodPost, "/", url, nil))
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+		require.Len(t, resp.Cookies(), 1)
+		return resp.Cookies()[0]
+	}
+	creds := `{"login":"alice","password":"password1"}`
+
+	device1 := anonymous("http://one.ru")
+	resp, body := testRequest(t, ts, newRequest(http.MethodPost, "/api/user/register", creds, device1))
+	require.Equal(t, http.StatusCreated, resp.StatusCode, body)
+	resp, _ = testRequest(t, ts, newRequest(http.MethodPost, "/api/user/register", creds, device1))
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	device2 := anonymous("http://two.ru")
+	resp, _ = testRequest(t, ts, newRequest(http.MethodPost, "/api/user/login", `{"login":"alice","password":"bad password"}`, device2))
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	resp, body = testRequest(t, ts, newRequest(http.MethodPost, "/api/user/login", creds, device2))
+	require.Equal(t, http.StatusOK, resp.StatusCode, body)
+	var token models.ResponseToken
+	require.NoError(t, json.Unmarshal([]byte(body), &token))
+
+	// ссылки обоих устройств принадлежат аккаунту
+	for _, cookie := range []*http.Cookie{device1, {Name: auth.CookieName, Value: token.Token}} {
+		resp, body = testRequest(t, ts, newRequest(http.MethodGet, "/api/user/urls", "", cookie))
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var urls models.ResponseUser
+		require.NoError(t, json.Unmarshal([]byte(body), &urls))
+		assert.Len(t, urls, 2)
+	}
+}
+
+func TestAccounts_expiredToken(t *testing.T) {
+	store, err := storage.NewStorageMemory()
+	require.NoError(t, err)
+	require.NoError(t, store.Set(t.Context(), "abcdefgh", "http://victim.ru", "some_user_id"))
+	a := app.NewApp(store, nil)
+	ts := httptest.NewServer(Router(a, config.TrustedSubnet{}, config.ACL{}, SecurityOptions{}))
+	defer ts.Close()
+
+	// старая кука жертвы
+	expired := &http.Cookie{Name: auth.CookieName, Value: "dev.some_user_id.1700000000.1702592000.8hMkPRa2NiC1OfSiT3Kd1e8qZovDvoQ2Fhit2QJYPjE"}
+	post := func(url, body string, cookie *http.Cookie) (int, *http.Cookie) {
+		req, err := http.NewRequest(http.MethodPost, ts.URL+url, strings.NewReader(body))
+		require.NoError(t, err)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		resp, body := testRequest(t, ts, req)
+		var token models.ResponseToken
+		if resp.StatusCode < http.StatusBadRequest {
+			require.NoError(t, json.Unmarshal([]byte(body), &token))
+		}
+		return resp.StatusCode, &http.Cookie{Name: auth.CookieName, Value: token.Token}
+	}
+	// userURLS count of urls of account
+	userURLS := func(cookie *http.Cookie) int {
+		req, err := http.NewRequest(http.MethodGet, ts.URL+"/api/user/urls", nil)
+		require.NoError(t, err)
+		req.AddCookie(cookie)
+		resp, body := testRequest(t, ts, req)
+		if resp.StatusCode == http.StatusNoContent {
+			return 0
+		}
+		var urls models.ResponseUser
+		require.NoError(t, json.Unmarshal([]byte(body), &urls))
+		return len(urls)
+	}
+
+	code, _ := post("/api/user/register", `{"login":"mallory","password":"password1"}`, nil)
+	require.Equal(t, http.StatusCreated, code)
+
+	for _, grace := range []time.Duration{100 * 365 * 24 * time.Hour, 0} {
+		// в grace токен перевыпускается, после него игнорируется. Ссылки жертвы не переносятся в обоих случаях
+		auth.SetTokenGrace(grace)
+		code, cookie := post("/api/user/login", `{"login":"mallory","password":"password1"}`, expired)
+		require.Equal(t, http.StatusOK, code)
+		assert.Equal(t, 0, userURLS(cookie), grace)
+	}
+
+	auth.SetTokenGrace(100 * 365 * 24 * time.Hour)
+	defer auth.SetTokenGrace(0)
+	// аккаунт не привязывается к userid протухшего токена
+	code, cookie := post("/api/user/register", `{"login":"eve","password":"password1"}`, expired)
+	require.Equal(t, http.StatusCreated, code)
+	assert.Equal(t, 0, userURLS(cookie))
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+
+	"github.com/serg2014/shortener/internal/app"
+	"github.com/serg2014/shortener/internal/config"
+	"github.com/serg2014/shortener/internal/handlers"
+	"github.com/serg2014/shortener/internal/logger"
+	"github.com/serg2014/shortener/internal/metrics"
+)
+
+// adminRealm realm of basic auth of admin listener
+const adminRealm = "shortener admin"
+
+// loopbackNets allowed nets of admin listener without trusted subnets
+var loopbackNets = config.TrustedSubnet{Data: []*net.IPNet{
+	{IP: net.IPv4(127, 0, 0, 0), Mask: net.CIDRMask(8, 32)},
+	{IP: net.IPv6loopback, Mask: net.CIDRMask(128, 128)},
+}}
+
+// AdminRouter set up routes of admin listener: pprof, stats, metrics, log level and health.
+// Listener is allowed from trustedNet (loopback if it is empty), pprof is limited by acl debug if it is set.
+// Basic auth is required if user is not empty
+func AdminRouter(a *app.MyApp, trustedNet config.TrustedSubnet, acl config.ACL, user, password string) chi.Router {
+	if len(trustedNet.Data) == 0 {
+		trustedNet = loopbackNets
+	}
+
+	r := chi.NewRouter()
+	r.Use(ACLMiddleware(trustedNet))
+	if user != "" {
+		// пароль сравнивается за постоянное время
+		r.Use(middleware.BasicAuth(adminRealm, map[string]string{user: password}))
+	}
+
+	r.Route("/debug", func(r chi.Router) {
+		if nets, ok := acl.Get(config.ACLDebug); ok {
+			r.Use(ACLMiddleware(nets))
+		}
+		// add pprof
+		r.Mount("/", middleware.Profiler())
+	})
+
+	r.Group(func(r chi.Router) {
+		r.Use(logger.RequestIDMiddleware)
+		r.Use(logger.WithLogging)
+		r.Get("/api/internal/stats", handlers.InternalStats(a))
+		r.Method(http.MethodGet, "/metrics", metrics.Handler())
+		// GET текущий уровень, PUT {"level":"debug"} меняет уровень
+		r.Method(http.MethodGet, "/log/level", logger.Level)
+		r.Method(http.MethodPut, "/log/level", logger.Level)
+		// свой уровень компонента (app, storage, grpc): GET, PUT {"level":"debug"}, DELETE
+		r.HandleFunc("/log/level/{component}", func(w http.ResponseWriter, r *http.Request) {
+			logger.ComponentLevelHandler(chi.URLParam(r, "component")).ServeHTTP(w, r)
+		})
+		r.Get("/healthz", handlers.Healthz())
+		r.Get("/readyz", handlers.Readyz(a))
+		r.Get("/version", handlers.Version(buildInfo()))
+	})
+	return r
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/serg2014/shortener/internal/app"
+	"github.com/serg2014/shortener/internal/config"
+	"github.com/serg2014/shortener/internal/logger"
+	"github.com/serg2014/shortener/storage"
+)
+
+func TestAdminRouter(t *testing.T) {
+	store, err := storage.NewStorageMemory()
+	require.NoError(t, err)
+	a := app.NewApp(store, nil)
+
+	otherNet := config.TrustedSubnet{Data: []*net.IPNet{{IP: net.IP{10, 0, 0, 0}, Mask: net.CIDRMask(8, 32)}}}
+	tests := []struct {
+		name       string
+		trustedNet config.TrustedSubnet
+		acl        string
+		basicAuth  bool
+		user       string
+		password   string
+		url        string
+		statusCode int
+	}{
+		{name: "pprof from loopback", url: "/debug/pprof/", statusCode: http.StatusOK},
+		{name: "stats from loopback", url: "/api/internal/stats", statusCode: http.StatusOK},
+		{name: "healthz", url: "/healthz", statusCode: http.StatusOK},
+		{name: "readyz", url: "/readyz", statusCode: http.StatusOK},
+		{name: "log level", url: "/log/level", statusCode: http.StatusOK},
+		{name: "from untrusted net", trustedNet: otherNet, url: "/healthz", statusCode: http.StatusForbidden},
+		{name: "pprof from other acl net", acl: "debug=10.0.0.0/8", url: "/debug/pprof/", statusCode: http.StatusForbidden},
+		{name: "pprof from acl net", acl: "debug=10.0.0.0/8,127.0.0.1/32", url: "/debug/pprof/", statusCode: http.StatusOK},
+		{name: "without basic auth", basicAuth: true, url: "/healthz", statusCode: http.StatusUnauthorized},
+		{name: "bad password", basicAuth: true, user: "admin", password: "bad", url: "/healthz", statusCode: http.StatusUnauthorized},
+		{name: "basic auth", basicAuth: true, user: "admin", password: "secret", url: "/healthz", statusCode: http.StatusOK},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var acl config.ACL
+			require.NoError(t, acl.Set(test.acl))
+			user, password := "", ""
+			if test.basicAuth {
+				user, password = "admin", "secret"
+			}
+			ts := httptest.NewServer(AdminRouter(a, test.trustedNet, acl, user, password))
+			defer ts.Close()
+			req, err := http.NewRequest(http.MethodGet, ts.URL+test.url, nil)
+			require.NoError(t, err)
+			if test.user != "" {
+				req.SetBasicAuth(test.user, test.password)
+			}
+			resp, _ := testRequest(t, ts, req)
+			assert.Equal(t, test.statusCode, resp.StatusCode)
+		})
+	}
+}
+
+func TestAdminRouter_logLevel(t *testing.T) {
+	store, err := storage.NewStorageMemory()
+	require.NoError(t, err)
+	a := app.NewApp(store, nil)
+	ts := httptest.NewServer(AdminRouter(a, config.TrustedSubnet{}, config.ACL{}, "", ""))
+	defer ts.Close()
+
+	old := logger.Level.Level()
+	defer logger.Level.SetLevel(old)
+
+	req, err := http.NewRequest(http.MethodPut, ts.URL+"/log/level", strings.NewReader(`{"level":"debug"}`))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	resp, body := testRequest(t, ts, req)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.JSONEq(t, `{"level":"debug"}`, body)
+	assert.Equal(t, zapcore.DebugLevel, logger.Level.Level())
+}
+
+func TestAdminRouter_componentLogLevel(t *testing.T) {
+	store, err := storage.NewStorageMemory()
+	require.NoError(t, err)
+	a := app.NewApp(store, nil)
+	ts := httptest.NewServer(AdminRouter(a, config.TrustedSubnet{}, config.ACL{}, "", ""))
+	defer ts.Close()
+	defer logger.ResetComponentLevel("storage")
+
+	req, err := http.NewRequest(http.MethodPut, ts.URL+"/log/level/storage", strings.NewReader(`{"level":"debug"}`))
+	require.NoError(t, err)
+	resp, body := testRequest(t, ts, req)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.JSONEq(t, `{"level":"debug","own":true}`, body)
+	lvl, own := logger.ComponentLevel("storage")
+	assert.True(t, own)
+	assert.Equal(t, zapcore.DebugLevel, lvl)
+
+	req, err = http.NewRequest(http.MethodDelete, ts.URL+"/log/level/storage", nil)
+	require.NoError(t, err)
+	resp, _ = testRequest(t, ts, req)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	_, own = logger.ComponentLevel("storage")
+	assert.False(t, own)
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/serg2014/shortener/internal/app"
+	"github.com/serg2014/shortener/internal/auth"
+	"github.com/serg2014/shortener/internal/config"
+	"github.com/serg2014/shortener/internal/models"
+	"github.com/serg2014/shortener/storage"
+)
+
+func TestAdminAPI(t *testing.T) {
+	store, err := storage.NewStorageMemory()
+	require.NoError(t, err)
+	// аккаунт админа регистрируется до того, как логин попадает в ADMINS
+	_, err = app.NewApp(store, nil).Register(t.Context(), auth.NewUserID(), models.RequestCredentials{Login: "admin", Password: "password1"})
+	require.NoError(t, err)
+	config.Config.Admins = "admin"
+	defer func() { config.Config.Admins = "" }()
+	a := app.NewApp(store, nil)
+	auth.SetRoleResolver(a)
+	defer auth.SetRoleResolver(nil)
+	ts := httptest.NewServer(Router(a, config.TrustedSubnet{}, config.ACL{}, SecurityOptions{}))
+	defer ts.Close()
+
+	newRequest := func(method, url, body string, cookie *http.Cookie) *http.Request {
+		req, err := http.NewRequest(method, ts.URL+url, strings.NewReader(body))
+		require.NoError(t, err)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		return req
+	}
+	register := func(login string) *http.Cookie {
+		resp, body := testRequest(t, ts, newRequest(http.MethodPost, "/api/user/register", `{"login":"`+login+`","password":"password1"}`, nil))
+		require.Equal(t, http.StatusCreated, resp.StatusCode, body)
+		var token models.ResponseToken
+		require.NoError(t, json.Unmarshal([]byte(body), &token))
+		return &http.Cookie{Name: auth.CookieName, Value: token.Token}
+	}
+	login := func(login string) *http.Cookie {
+		resp, body := testRequest(t, ts, newRequest(http.MethodPost, "/api/user/login", `{"login":"`+login+`","password":"password1"}`, nil))
+		require.Equal(t, http.StatusOK, resp.StatusCode, body)
+		var token models.ResponseToken
+		require.NoError(t, json.Unmarshal([]byte(body), &token))
+		return &http.Cookie{Name: auth.CookieName, Value: token.Token}
+	}
+	admin := login("admin")
+	user := register("alice")
+	// логин из ADMINS нельзя зарегистрировать заново
+	resp, body := testRequest(t, ts, newRequest(http.MethodPost, "/api/user/register", `{"login":"admin","password":"password2"}`, nil))
+	require.Equal(t, http.StatusConflict, resp.StatusCode, body)
+
+	resp, _ = testRequest(t, ts, newRequest(http.MethodPost, "/", "https://example.com/", user))
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	var urls []models.ResponseAdminURL
+
+	tests := []struct {
+		name       string
+		method     string
+		url        string
+		body       string
+		cookie     *http.Cookie
+		statusCode int
+	}{
+		{name: "anonymous", method: http.MethodGet, url: "/api/admin/urls", statusCode: http.StatusForbidden},
+		{name: "not admin", method: http.MethodGet, url: "/api/admin/urls", cookie: user, statusCode: http.StatusForbidden},
+		{name: "search", method: http.MethodGet, url: "/api/admin/urls?domain=example.com", cookie: admin, statusCode: http.StatusOK},
+		{name: "bad limit", method: http.MethodGet, url: "/api/admin/top?limit=x", cookie: admin, statusCode: http.StatusBadRequest},
+		{name: "bad reason", method: http.MethodPost, url: "/api/admin/urls/unknown1/disable", body: `{"reason":"bad"}`, cookie: admin, statusCode: http.StatusBadRequest},
+		{name: "disable unknown", method: http.MethodPost, url: "/api/admin/urls/unknown1/disable", body: `{"reason":"gone"}`, cookie: admin, statusCode: http.StatusNotFound},
+		{name: "ban", method: http.MethodPut, url: "/api/admin/users/some_user/ban", cookie: admin, statusCode: http.StatusNoContent},
+		{name: "unban", method: http.MethodDelete, url: "/api/admin/users/some_user/ban", cookie: admin, statusCode: http.StatusNoContent},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp, body := testRequest(t, ts, newRequest(test.method, test.url, test.body, test.cookie))
+			assert.Equal(t, test.statusCode, resp.StatusCode, body)
+			if test.name == "search" {
+				require.NoError(t, json.Unmarshal([]byte(body), &urls))
+			}
+		})
+	}
+	require.Len(t, urls, 1)
+	key := strings.TrimPrefix(urls[0].ShortURL, config.Config.URL())
+
+	// отключенный урл отдается с кодом причины
+	for reason, code := range map[string]int{storage.DisabledLegal: http.StatusUnavailableForLegalReasons, storage.DisabledGone: http.StatusGone} {
+		resp, _ = testRequest(t, ts, newRequest(http.MethodPost, "/api/admin/urls/"+key+"/disable", `{"reason":"`+reason+`"}`, admin))
+		require.Equal(t, http.StatusNoContent, resp.StatusCode)
+		resp, _ = testRequest(t, ts, newRequest(http.MethodGet, "/"+key, "", nil))
+		assert.Equal(t, code, resp.StatusCode, reason)
+	}
+	resp, _ = testRequest(t, ts, newRequest(http.MethodPost, "/api/admin/urls/"+key+"/enable", "", admin))
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp, _ = testRequest(t, ts, newRequest(http.MethodGet, "/"+key, "", nil))
+	assert.Equal(t, http.StatusTemporaryRedirect, resp.StatusCode)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/serg2014/shortener/internal/app"
+	"github.com/serg2014/shortener/internal/auth"
+	"github.com/serg2014/shortener/internal/config"
+	"github.com/serg2014/shortener/internal/models"
+	"github.com/serg2014/shortener/storage"
+)
+
+func TestAPIKeys(t *testing.T) {
+	store, err := storage.NewStorageMemory()
+	require.NoError(t, err)
+	a := app.NewApp(store, nil)
+	auth.SetAPIKeyVerifier(a)
+	defer auth.SetAPIKeyVerifier(nil)
+	ts := httptest.NewServer(Router(a, config.TrustedSubnet{}, config.ACL{}, SecurityOptions{}))
+	defer ts.Close()
+
+	const cookieVal = "user_id=dev.some_user_id.1700000000.4102444800.//ECEEF7v+4xinw6/gFudevnxKA3a6ASpxtYBfINfwo"
+	newRequest := func(method, url, body string, headers map[string]string) *http.Request {
+		req, err := http.NewRequest(method, ts.URL+url, strings.NewReader(body))
+		require.NoError(t, err)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		return req
+	}
+	createKey := func(scopes ...string) models.ResponseCreateAPIKey {
+		body, err := json.Marshal(models.RequestAPIKey{Name: "backend", Scopes: scopes})
+		require.NoError(t, err)
+		resp, respBody := testRequest(t, ts, newRequest(http.MethodPost, "/api/user/keys", string(body), map[string]string{"cookie": cookieVal}))
+		require.Equal(t, http.StatusCreated, resp.StatusCode, respBody)
+		var key models.ResponseCreateAPIKey
+		require.NoError(t, json.Unmarshal([]byte(respBody), &key))
+		return key
+	}
+
+	createOnly := createKey(auth.ScopeCreate)
+	stats := createKey(auth.ScopeStats)
+
+	// ключ создает урл от имени владельца, кука не выставляется
+	resp, _ := testRequest(t, ts, newRequest(http.MethodPost, "/", "http://one.ru", map[string]string{auth.HeaderAPIKey: createOnly.Key}))
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Empty(t, resp.Cookies())
+	resp, _ = testRequest(t, ts, newRequest(http.MethodGet, "/api/user/urls", "", map[string]string{"cookie": cookieVal}))
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	tests := []struct {
+		name       string
+		method     string
+		url        string
+		key        string
+		statusCode int
+	}{
+		{name: "no scope read", method: http.MethodGet, url: "/api/user/urls", key: createOnly.Key, statusCode: http.StatusForbidden},
+		{name: "no scope delete", method: http.MethodDelete, url: "/api/user/urls", key: createOnly.Key, statusCode: http.StatusForbidden},
+		{name: "keys are managed by user only", method: http.MethodGet, url: "/api/user/keys", key: createOnly.Key, statusCode: http.StatusForbidden},
+		{name: "token is issued to user only", method: http.MethodGet, url: "/api/user/token", key: createOnly.Key, statusCode: http.StatusForbidden},
+		{name: "bad key", method: http.MethodGet, url: "/api/user/urls", key: "sk_bad_key", statusCode: http.StatusUnauthorized},
+		{name: "stats without scope", method: http.MethodGet, url: "/api/internal/stats", key: createOnly.Key, statusCode: http.StatusForbidden},
+		{name: "stats with scope", method: http.MethodGet, url: "/api/internal/stats", key: stats.Key, statusCode: http.StatusOK},
+		{name: "bad key for stats", method: http.MethodGet, url: "/api/internal/stats", key: "sk_bad_key", statusCode: http.StatusUnauthorized},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp, _ := testRequest(t, ts, newRequest(test.method, test.url, "", map[string]string{auth.HeaderAPIKey: test.key}))
+			assert.Equal(t, test.statusCode, resp.StatusCode)
+		})
+	}
+
+	resp, respBody := testRequest(t, ts, newRequest(http.MethodGet, "/api/user/keys", "", map[string]string{"cookie": cookieVal}))
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.NotContains(t, respBody, createOnly.Key)
+	var keys []models.ResponseAPIKey
+	require.NoError(t, json.Unmarshal([]byte(respBody), &keys))
+	require.Len(t, keys, 2)
+
+	// отозванный ключ больше не работает
+	resp, _ = testRequest(t, ts, newRequest(http.MethodDelete, "/api/user/keys/"+createOnly.ID, "", map[string]string{"cookie": cookieVal}))
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp, _ = testRequest(t, ts, newRequest(http.MethodDelete, "/api/user/keys/unknown", "", map[string]string{"cookie": cookieVal}))
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp, _ = testRequest(t, ts, newRequest(http.MethodPost, "/", "http://two.ru", map[string]string{auth.HeaderAPIKey: createOnly.Key}))
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
@@ -1,113 +1,78 @@
 package main
 
 import (
-	"bytes"
-	"crypto/rand"
-	"crypto/rsa"
-	"crypto/x509"
-	"crypto/x509/pkix"
-	"encoding/pem"
-	"fmt"
-	"math/big"
-	"net"
+	"context"
+	"crypto/tls"
+	"net/url"
 	"os"
-	"path/filepath"
+	"os/signal"
+	"syscall"
 	"time"
-)
 
-// createCertTmpDir create tmp dir for certificate and private key
-func createCertTmpDir() (string, error) {
-	tmp, err := os.MkdirTemp("", "cert_and_pk")
-	if err != nil {
-		return "", err
-	}
-	return tmp, nil
-}
+	"go.uber.org/zap"
 
-// deleteCertTmpDir delete dir for certificate and private key
-func deleteCertTmpDir(dirPath string) error {
-	err := os.RemoveAll(dirPath)
-	if err != nil {
-		return err
-	}
-	return nil
-}
-
-// makeCertPK create certificate file and private key file in the dir dirPath
-func makeCertPK(dirPath string) (string, string, error) {
-	// создаём шаблон сертификата
-	cert := &x509.Certificate{
-		// указываем уникальный номер сертификата
-		SerialNumber: big.NewInt(1658),
-		// заполняем базовую информацию о владельце сертификата
-		Subject: pkix.Name{
-			Organization: []string{"Yandex.Praktikum"},
-			Country:      []string{"RU"},
-		},
-		// разрешаем использование сертификата для 127.0.0.1 и ::1
-		IPAddresses: []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
-		// сертификат верен, начиная со времени создания
-		NotBefore: time.Now(),
-		// время жизни сертификата — 10 лет
-		NotAfter:     time.Now().AddDate(10, 0, 0),
-		SubjectKeyId: []byte{1, 2, 3, 4, 6},
-		// устанавливаем использование ключа для цифровой подписи,
-		// а также клиентской и серверной авторизации
-		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
-		KeyUsage:    x509.KeyUsageDigitalSignature,
-	}
+	"github.com/serg2014/shortener/internal/config"
+	"github.com/serg2014/shortener/internal/logger"
+	"github.com/serg2014/shortener/internal/tlscert"
+)
 
-	// создаём новый приватный RSA-ключ длиной 4096 бит
-	// обратите внимание, что для генерации ключа и сертификата
-	// используется rand.Reader в качестве источника случайных данных
-	privateKey, err := rsa.GenerateKey(rand.Reader, 4096)
-	if err != nil {
-		return "", "", err
-	}
+// certWatchInterval how often files of certificates are checked for changes
+const certWatchInterval = 10 * time.Second
 
-	// создаём сертификат x.509
-	certBytes, err := x509.CreateCertificate(rand.Reader, cert, cert, &privateKey.PublicKey, privateKey)
-	if err != nil {
-		return "", "", err
+// httpsReloader return certificate of https server from files or self-signed certificate in dev mode
+func httpsReloader() (*tlscert.Reloader, error) {
+	certFile, keyFile := config.Config.TLSCertFile, config.Config.TLSKeyFile
+	if !config.Config.TLSFiles() {
+		if !config.Config.TLSDevCert {
+			return nil, config.ErrNoTLSCert
+		}
+		dir := config.Config.TLSDevDir
+		if dir == "" {
+			dir = tlscert.DefaultDevDir()
+		}
+		var baseHost string
+		if u, err := url.Parse(config.Config.BaseURL); err == nil {
+			baseHost = u.Hostname()
+		}
+		var err error
+		certFile, keyFile, err = tlscert.DevCert(dir, tlscert.DevHosts(config.Config.ServerAddress.Host, baseHost))
+		if err != nil {
+			return nil, err
+		}
+		logger.Log.Warn("https uses self-signed dev certificate", zap.String("cert", certFile))
 	}
+	return tlscert.NewReloader(certFile, keyFile)
+}
 
-	// кодируем сертификат и ключ в формате PEM, который
-	// используется для хранения и обмена криптографическими ключами
-	var certPEM bytes.Buffer
-	pem.Encode(&certPEM, &pem.Block{
-		Type:  "CERTIFICATE",
-		Bytes: certBytes,
-	})
-
-	var privateKeyPEM bytes.Buffer
-	pem.Encode(&privateKeyPEM, &pem.Block{
-		Type:  "RSA PRIVATE KEY",
-		Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
-	})
-
-	certPath := filepath.Join(dirPath, "cert")
-	err = os.WriteFile(certPath, certPEM.Bytes(), 0600)
-	if err != nil {
-		return "", "", err
-	}
-	pkPath := filepath.Join(dirPath, "pk")
-	err = os.WriteFile(pkPath, privateKeyPEM.Bytes(), 0600)
-	if err != nil {
-		return "", "", err
+// httpsTLSConfig tls config of https server. On single port client certificates of grpc are verified if given,
+// config does not allow to require them there
+func httpsTLSConfig(cert *tlscert.Reloader, singlePort bool) (*tls.Config, error) {
+	if singlePort {
+		return grpcTLSConfig(cert, config.Config.GRPCClientCA, config.Config.GRPCRequireClientCert)
 	}
-	return certPath, pkPath, nil
+	return &tls.Config{
+		GetCertificate: cert.GetCertificate,
+		MinVersion:     tls.VersionTLS12,
+	}, nil
 }
 
-// getCertPK return path to certificate file and private key
-func getCertPK() (string, string, func() error, error) {
-	tmpDir, err := createCertTmpDir()
-	if err != nil {
-		return "", "", nil, fmt.Errorf("can not create tmp dir: %w", err)
-	}
-	cert, pk, err := makeCertPK(tmpDir)
-	if err != nil {
-		return "", "", nil, fmt.Errorf("tls error: %w", err)
+// reloadOnSignal reload certificates on SIGHUP until ctx is done
+func reloadOnSignal(ctx context.Context, reloaders []*tlscert.Reloader) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGHUP)
+	defer signal.Stop(ch)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ch:
+			for _, r := range reloaders {
+				if err := r.Reload(); err != nil {
+					logger.Log.Error("can not reload certificate", zap.Error(err))
+					continue
+				}
+			}
+			logger.Log.Info("certificates are reloaded by SIGHUP")
+		}
 	}
-	fn := func() error { return deleteCertTmpDir(tmpDir) }
-	return cert, pk, fn, nil
 }
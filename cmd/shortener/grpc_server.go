@@ -8,9 +8,10 @@ import (
 	pb "github.com/serg2014/shortener/cmd/shortener/proto"
 	"github.com/serg2014/shortener/internal/app"
 	"github.com/serg2014/shortener/internal/auth"
+	"github.com/serg2014/shortener/internal/handlers"
 	"github.com/serg2014/shortener/internal/logger"
 	"github.com/serg2014/shortener/internal/models"
-	"github.com/serg2014/shortener/internal/storage"
+	"github.com/serg2014/shortener/storage"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
@@ -18,6 +19,9 @@ import (
 	"google.golang.org/grpc/status"
 )
 
+// componentGRPC name of component in logs of grpc server
+const componentGRPC = "grpc"
+
 // GrpcServer struct for grpc server
 type GrpcServer struct {
 	pb.UnimplementedShortenerServiceServer
@@ -25,6 +29,29 @@ type GrpcServer struct {
 	app *app.MyApp
 }
 
+// grpcMethodScopes scopes of api key required for methods
+var grpcMethodScopes = map[string]string{
+	"/shortener.ShortenerService/ShortURLS":      auth.ScopeCreate,
+	"/shortener.ShortenerService/GetUserURLS":    auth.ScopeRead,
+	"/shortener.ShortenerService/DeleteUserURLS": auth.ScopeDelete,
+	"/shortener.ShortenerService/GetUserToken":   auth.ScopeUser,
+	"/shortener.ShortenerService/Register":       auth.ScopeUser,
+	"/shortener.ShortenerService/Login":          auth.ScopeUser,
+}
+
+// grpcFreshTokenMethods methods which issue new token for userid, they require fresh token
+var grpcFreshTokenMethods = map[string]bool{
+	"/shortener.ShortenerService/GetUserToken": true,
+}
+
+// grpcMethodRoles roles of user required for methods
+var grpcMethodRoles = map[string]string{
+	"/shortener.ShortenerService/AdminSearchURLS": auth.RoleAdmin,
+	"/shortener.ShortenerService/AdminDisableURL": auth.RoleAdmin,
+	"/shortener.ShortenerService/AdminBanUser":    auth.RoleAdmin,
+	"/shortener.ShortenerService/AdminTopURLS":    auth.RoleAdmin,
+}
+
 func metadataInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
 	resp, err := handler(ctx, req)
 
@@ -39,14 +66,18 @@ func metadataInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInf
 func (s *GrpcServer) InternalStats(ctx context.Context, request *pb.InternalStatsRequest) (*pb.InternalStatsResponse, error) {
 	data, err := s.app.InternalStats(ctx)
 	if err != nil {
-		// TODO нужен логер чтобы сам делала префикс grpc
-		logger.Log.Error("InternalStats", zap.Error(err))
+		logger.Component(ctx, componentGRPC).Error("InternalStats", zap.Error(err))
 		code := codes.Internal
 		return nil, status.Error(code, code.String())
 	}
 	return &pb.InternalStatsResponse{
-		Urls:  uint32(data.Urls),
-		Users: uint32(data.Users),
+		Urls:          uint32(data.Urls),
+		Users:         uint32(data.Users),
+		CacheHits:     data.CacheHits,
+		CacheMisses:   data.CacheMisses,
+		BloomBits:     data.BloomBits,
+		BloomFpRate:   data.BloomFPRate,
+		BloomRejected: data.BloomRejected,
 	}, nil
 }
 
@@ -56,7 +87,7 @@ func (s *GrpcServer) Ping(ctx context.Context, request *pb.PingRequest) (*pb.Pin
 	defer cancel()
 
 	if err := s.app.Ping(ctx); err != nil {
-		logger.Log.Error("ping", zap.Error(err))
+		logger.Component(ctx, componentGRPC).Error("ping", zap.Error(err))
 		code := codes.Internal
 		return nil, status.Error(code, code.String())
 	}
@@ -67,14 +98,23 @@ func (s *GrpcServer) Ping(ctx context.Context, request *pb.PingRequest) (*pb.Pin
 func (s *GrpcServer) DeleteUserURLS(ctx context.Context, request *pb.DeleteUserURLSRequest) (*pb.DeleteUserURLSResponse, error) {
 	userID, err := auth.GetUserID(ctx)
 	if err != nil {
-		logger.Log.Error("can not find userid", zap.Error(err))
+		logger.Component(ctx, componentGRPC).Error("can not find userid", zap.Error(err))
 		code := codes.Internal
 		return nil, status.Error(code, code.String())
 	}
 	req := models.RequestForDeleteURLS(request.Shorts)
 	err = s.app.DeleteUserURLS(ctx, req, userID)
+	if errors.Is(err, app.ErrBadDeleteRequest) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if errors.Is(err, app.ErrDeleteQueueFull) {
+		logger.Component(ctx, componentGRPC).Warn("DeleteUserURLS", zap.Error(err))
+		grpc.SetHeader(ctx, metadata.Pairs("retry-after", handlers.RetryAfter(s.app.DeleteRetryAfter())))
+		code := codes.ResourceExhausted
+		return nil, status.Error(code, code.String())
+	}
 	if err != nil {
-		logger.Log.Error("can not find userid", zap.Error(err))
+		logger.Component(ctx, componentGRPC).Error("DeleteUserURLS", zap.Error(err))
 		code := codes.Internal
 		return nil, status.Error(code, code.String())
 	}
@@ -85,20 +125,22 @@ func (s *GrpcServer) DeleteUserURLS(ctx context.Context, request *pb.DeleteUserU
 // GetURL
 func (s *GrpcServer) GetURL(ctx context.Context, request *pb.GetURLRequest) (*pb.GetURLResponse, error) {
 	origURL, ok, err := s.app.Get(ctx, request.Short)
-	var code codes.Code
 	if err != nil {
+		// удаленные и отключенные ссылки как 410/451 в REST, это не ошибка сервиса
 		switch {
 		case errors.Is(err, storage.ErrDeleted):
-			// TODO http.StatusGone
-			code = codes.Internal
-		default:
-			code = codes.Internal
+			logger.Component(ctx, componentGRPC).Debug("short url is deleted", zap.Error(err))
+			return nil, status.Error(codes.NotFound, err.Error())
+		case errors.Is(err, storage.ErrDisabled), errors.Is(err, storage.ErrDisabledLegal):
+			logger.Component(ctx, componentGRPC).Debug("short url is disabled", zap.Error(err))
+			return nil, status.Error(codes.FailedPrecondition, err.Error())
 		}
-		logger.Log.Error("error in a.Get", zap.Error(err))
+		logger.Component(ctx, componentGRPC).Error("error in a.Get", zap.Error(err))
+		code := codes.Internal
 		return nil, status.Error(code, code.String())
 	}
 	if !ok {
-		return nil, status.Error(codes.InvalidArgument, "bad short")
+		return nil, status.Error(codes.NotFound, "short url not found")
 	}
 
 	return &pb.GetURLResponse{Url: origURL}, nil
@@ -108,7 +150,7 @@ func (s *GrpcServer) GetURL(ctx context.Context, request *pb.GetURLRequest) (*pb
 func (s *GrpcServer) ShortURLS(ctx context.Context, request *pb.ShortURLSRequest) (*pb.ShortURLSResponse, error) {
 	userID, err := auth.GetUserID(ctx)
 	if err != nil {
-		logger.Log.Error("can not find userid", zap.Error(err))
+		logger.Component(ctx, componentGRPC).Error("can not find userid", zap.Error(err))
 		code := codes.Internal
 		return nil, status.Error(code, code.String())
 	}
@@ -122,7 +164,10 @@ func (s *GrpcServer) ShortURLS(ctx context.Context, request *pb.ShortURLSRequest
 
 	resp, err := s.app.GenerateShortURLBatch(ctx, req, userID)
 	if err != nil {
-		logger.Log.Error(
+		if errors.Is(err, app.ErrUserBanned) {
+			return nil, status.Error(codes.PermissionDenied, err.Error())
+		}
+		logger.Component(ctx, componentGRPC).Error(
 			"can not generate short batch",
 			zap.Error(err),
 		)
@@ -146,13 +191,13 @@ func (s *GrpcServer) ShortURLS(ctx context.Context, request *pb.ShortURLSRequest
 func (s *GrpcServer) GetUserURLS(ctx context.Context, request *pb.GetUserURLSRequest) (*pb.GetUserURLSResponse, error) {
 	userID, err := auth.GetUserID(ctx)
 	if err != nil {
-		logger.Log.Error("can not find userid", zap.Error(err))
+		logger.Component(ctx, componentGRPC).Error("can not find userid", zap.Error(err))
 		code := codes.Internal
 		return nil, status.Error(code, code.String())
 	}
 	data, err := s.app.GetUserURLS(ctx, userID)
 	if err != nil {
-		logger.Log.Error("GetUserURLS", zap.Error(err))
+		logger.Component(ctx, componentGRPC).Error("GetUserURLS", zap.Error(err))
 		code := codes.Internal
 		return nil, status.Error(code, code.String())
 	}
@@ -175,3 +220,156 @@ func (s *GrpcServer) GetUserURLS(ctx context.Context, request *pb.GetUserURLSReq
 	}
 	return &response, nil
 }
+
+// GetUserToken return new token of the user. Use it in meta authorization: Bearer
+func (s *GrpcServer) GetUserToken(ctx context.Context, request *pb.GetUserTokenRequest) (*pb.GetUserTokenResponse, error) {
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		logger.Component(ctx, componentGRPC).Error("can not find userid", zap.Error(err))
+		code := codes.Internal
+		return nil, status.Error(code, code.String())
+	}
+	token, expiresAt := auth.IssueToken(userID)
+	return &pb.GetUserTokenResponse{Token: token, ExpiresAt: expiresAt.Unix()}, nil
+}
+
+// Register create account with login and password and return token of its user
+func (s *GrpcServer) Register(ctx context.Context, request *pb.CredentialsRequest) (*pb.GetUserTokenResponse, error) {
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		logger.Component(ctx, componentGRPC).Error("can not find userid", zap.Error(err))
+		code := codes.Internal
+		return nil, status.Error(code, code.String())
+	}
+	req := models.RequestCredentials{Login: request.GetLogin(), Password: request.GetPassword()}
+	userID, err = s.app.Register(ctx, userID, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, app.ErrBadAccountRequest):
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		case errors.Is(err, app.ErrLoginTaken):
+			return nil, status.Error(codes.AlreadyExists, err.Error())
+		case errors.Is(err, storage.ErrNotSupported):
+			return nil, status.Error(codes.Unimplemented, err.Error())
+		}
+		logger.Component(ctx, componentGRPC).Error("Register", zap.Error(err))
+		code := codes.Internal
+		return nil, status.Error(code, code.String())
+	}
+	token, expiresAt := auth.IssueToken(userID)
+	return &pb.GetUserTokenResponse{Token: token, ExpiresAt: expiresAt.Unix()}, nil
+}
+
+// Login check login and password and return token of user of account.
+// Links of anonymous user are moved to the account
+func (s *GrpcServer) Login(ctx context.Context, request *pb.CredentialsRequest) (*pb.GetUserTokenResponse, error) {
+	userID, err := auth.GetUserID(ctx)
+	if err != nil {
+		logger.Component(ctx, componentGRPC).Error("can not find userid", zap.Error(err))
+		code := codes.Internal
+		return nil, status.Error(code, code.String())
+	}
+	req := models.RequestCredentials{Login: request.GetLogin(), Password: request.GetPassword()}
+	userID, err = s.app.Login(ctx, userID, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, app.ErrBadCredentials):
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		case errors.Is(err, storage.ErrNotSupported):
+			return nil, status.Error(codes.Unimplemented, err.Error())
+		}
+		logger.Component(ctx, componentGRPC).Error("Login", zap.Error(err))
+		code := codes.Internal
+		return nil, status.Error(code, code.String())
+	}
+	token, expiresAt := auth.IssueToken(userID)
+	return &pb.GetUserTokenResponse{Token: token, ExpiresAt: expiresAt.Unix()}, nil
+}
+
+// adminStatus convert error of admin api to grpc status
+func adminStatus(ctx context.Context, name string, err error) error {
+	switch {
+	case errors.Is(err, app.ErrBadAdminRequest):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, storage.ErrNotFound):
+		return status.Error(codes.NotFound, "short url not found")
+	case errors.Is(err, storage.ErrNotSupported):
+		return status.Error(codes.Unimplemented, err.Error())
+	}
+	logger.Component(ctx, componentGRPC).Error(name, zap.Error(err))
+	code := codes.Internal
+	return status.Error(code, code.String())
+}
+
+func toAdminURLSResponse(urls []models.ResponseAdminURL) *pb.AdminURLSResponse {
+	resp := &pb.AdminURLSResponse{Urls: make([]*pb.AdminURL, len(urls))}
+	for i, url := range urls {
+		resp.Urls[i] = &pb.AdminURL{
+			ShortUrl:    url.ShortURL,
+			OriginalUrl: url.OriginalURL,
+			UserId:      url.UserID,
+			IsDeleted:   url.Deleted,
+			Disabled:    url.Disabled,
+			Hits:        url.Hits,
+		}
+	}
+	return resp
+}
+
+// AdminSearchURLS find urls of all users by domain and user
+func (s *GrpcServer) AdminSearchURLS(ctx context.Context, request *pb.AdminSearchURLSRequest) (*pb.AdminURLSResponse, error) {
+	adminID, err := auth.GetUserID(ctx)
+	if err != nil {
+		logger.Component(ctx, componentGRPC).Error("can not find userid", zap.Error(err))
+		code := codes.Internal
+		return nil, status.Error(code, code.String())
+	}
+	urls, err := s.app.AdminSearchURLS(ctx, adminID, request.GetDomain(), request.GetUserId(), int(request.GetLimit()))
+	if err != nil {
+		return nil, adminStatus(ctx, "AdminSearchURLS", err)
+	}
+	return toAdminURLSResponse(urls), nil
+}
+
+// AdminDisableURL disable url with reason gone or legal. Empty reason enables url
+func (s *GrpcServer) AdminDisableURL(ctx context.Context, request *pb.AdminDisableURLRequest) (*pb.AdminDisableURLResponse, error) {
+	adminID, err := auth.GetUserID(ctx)
+	if err != nil {
+		logger.Component(ctx, componentGRPC).Error("can not find userid", zap.Error(err))
+		code := codes.Internal
+		return nil, status.Error(code, code.String())
+	}
+	if err := s.app.AdminDisableURL(ctx, adminID, request.GetShort(), request.GetReason()); err != nil {
+		return nil, adminStatus(ctx, "AdminDisableURL", err)
+	}
+	return &pb.AdminDisableURLResponse{}, nil
+}
+
+// AdminBanUser ban or unban user
+func (s *GrpcServer) AdminBanUser(ctx context.Context, request *pb.AdminBanUserRequest) (*pb.AdminBanUserResponse, error) {
+	adminID, err := auth.GetUserID(ctx)
+	if err != nil {
+		logger.Component(ctx, componentGRPC).Error("can not find userid", zap.Error(err))
+		code := codes.Internal
+		return nil, status.Error(code, code.String())
+	}
+	if err := s.app.AdminBanUser(ctx, adminID, request.GetUserId(), request.GetBanned()); err != nil {
+		return nil, adminStatus(ctx, "AdminBanUser", err)
+	}
+	return &pb.AdminBanUserResponse{}, nil
+}
+
+// AdminTopURLS return urls with max count of redirects
+func (s *GrpcServer) AdminTopURLS(ctx context.Context, request *pb.AdminTopURLSRequest) (*pb.AdminURLSResponse, error) {
+	adminID, err := auth.GetUserID(ctx)
+	if err != nil {
+		logger.Component(ctx, componentGRPC).Error("can not find userid", zap.Error(err))
+		code := codes.Internal
+		return nil, status.Error(code, code.String())
+	}
+	urls, err := s.app.AdminTopURLS(ctx, adminID, int(request.GetLimit()))
+	if err != nil {
+		return nil, adminStatus(ctx, "AdminTopURLS", err)
+	}
+	return toAdminURLSResponse(urls), nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/serg2014/shortener/cmd/shortener/proto"
+	"github.com/serg2014/shortener/internal/app"
+	"github.com/serg2014/shortener/storage"
+	"github.com/serg2014/shortener/storage/mock"
+)
+
+func TestGrpcServer_GetURL(t *testing.T) {
+	tests := []struct {
+		name string
+		url  string
+		ok   bool
+		err  error
+		code codes.Code
+	}{
+		{name: "ok", url: "http://some.long/url", ok: true, code: codes.OK},
+		{name: "not found", code: codes.NotFound},
+		// как 410 в REST
+		{name: "deleted", err: storage.ErrDeleted, code: codes.NotFound},
+		{name: "disabled", err: storage.ErrDisabled, code: codes.FailedPrecondition},
+		{name: "disabled legal", err: storage.ErrDisabledLegal, code: codes.FailedPrecondition},
+		{name: "storage error", err: errors.New("some error"), code: codes.Internal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			store := mock.NewMockStorager(ctrl)
+			store.EXPECT().Get(gomock.Any(), "a1234567").Return(tt.url, tt.ok, tt.err)
+			s := &GrpcServer{app: app.NewApp(store, nil)}
+
+			resp, err := s.GetURL(t.Context(), &pb.GetURLRequest{Short: "a1234567"})
+			assert.Equal(t, tt.code, status.Code(err))
+			assert.Equal(t, tt.url, resp.GetUrl())
+		})
+	}
+}
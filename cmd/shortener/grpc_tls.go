@@ -0,0 +1,56 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	"github.com/serg2014/shortener/internal/tlscert"
+)
+
+// ErrBadClientCA error when file of client ca has no certificates
+var ErrBadClientCA = errors.New("no certificates in client ca")
+
+// grpcTLSConfig tls config of grpc server with reloadable certificate.
+// With clientCA client certificates are verified, require rejects clients without certificate
+func grpcTLSConfig(cert *tlscert.Reloader, clientCA string, require bool) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		GetCertificate: cert.GetCertificate,
+		MinVersion:     tls.VersionTLS12,
+	}
+	if clientCA == "" {
+		return tlsConfig, nil
+	}
+
+	data, err := os.ReadFile(clientCA)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("%w: %s", ErrBadClientCA, clientCA)
+	}
+	tlsConfig.ClientCAs = pool
+	tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	if require {
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tlsConfig, nil
+}
+
+// grpcCreds option of grpc server with tls. Without cert server works in plaintext
+func grpcCreds(cert *tlscert.Reloader, clientCA string, require bool) ([]grpc.ServerOption, error) {
+	if cert == nil {
+		return nil, nil
+	}
+	tlsConfig, err := grpcTLSConfig(cert, clientCA, require)
+	if err != nil {
+		return nil, err
+	}
+	return []grpc.ServerOption{grpc.Creds(credentials.NewTLS(tlsConfig))}, nil
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/serg2014/shortener/cmd/shortener/proto"
+	"github.com/serg2014/shortener/internal/app"
+	"github.com/serg2014/shortener/internal/auth"
+	"github.com/serg2014/shortener/internal/config"
+	"github.com/serg2014/shortener/internal/tlscert"
+	"github.com/serg2014/shortener/storage"
+)
+
+// testCA ca for certificates of tests
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCA{cert: cert, key: key}
+}
+
+// issue create certificate signed by ca and write it with key into dir
+func (ca *testCA) issue(t *testing.T, dir, name string, tmpl *x509.Certificate) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+	tmpl.SerialNumber = serial
+	tmpl.NotBefore = time.Now().Add(-time.Hour)
+	tmpl.NotAfter = time.Now().Add(time.Hour)
+	tmpl.KeyUsage = x509.KeyUsageDigitalSignature
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certPath := filepath.Join(dir, name+".crt")
+	keyPath := filepath.Join(dir, name+".key")
+	require.NoError(t, os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+	return certPath, keyPath
+}
+
+// writeCA write certificate of ca into dir
+func (ca *testCA) write(t *testing.T, dir string) string {
+	path := filepath.Join(dir, "ca.crt")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw}), 0600))
+	return path
+}
+
+func TestGrpcMTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	caPath := ca.write(t, dir)
+	serverCert, serverKey := ca.issue(t, dir, "server", &x509.Certificate{
+		IPAddresses: []net.IP{net.IPv4(127, 0, 0, 1)},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+	statsCert, statsKey := ca.issue(t, dir, "billing", &x509.Certificate{
+		DNSNames:    []string{"billing.internal"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	otherCert, otherKey := ca.issue(t, dir, "other", &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "other.internal"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+
+	require.NoError(t, auth.SetServiceRoles(map[string]string{"billing.internal": auth.RoleStats}))
+	defer auth.SetServiceRoles(nil)
+
+	store, err := storage.NewStorageMemory()
+	require.NoError(t, err)
+	cert, err := tlscert.NewReloader(serverCert, serverKey)
+	require.NoError(t, err)
+	opts, err := grpcCreds(cert, caPath, false)
+	require.NoError(t, err)
+	srv := grpc.NewServer(append(opts, grpc.ChainUnaryInterceptor(
+		auth.CertInterceptor,
+		auth.AuthInterceptor,
+		trustedInterceptor(config.TrustedSubnet{}),
+	))...)
+	pb.RegisterShortenerServiceServer(srv, &GrpcServer{app: app.NewApp(store, nil)})
+	listen, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go srv.Serve(listen)
+	defer srv.Stop()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	tests := []struct {
+		name     string
+		certPath string
+		keyPath  string
+		code     codes.Code
+	}{
+		{name: "service with role stats", certPath: statsCert, keyPath: statsKey, code: codes.OK},
+		{name: "service without role", certPath: otherCert, keyPath: otherKey, code: codes.PermissionDenied},
+		{name: "without client cert", code: codes.PermissionDenied},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tlsConfig := &tls.Config{RootCAs: roots}
+			if test.certPath != "" {
+				cert, err := tls.LoadX509KeyPair(test.certPath, test.keyPath)
+				require.NoError(t, err)
+				tlsConfig.Certificates = []tls.Certificate{cert}
+			}
+			conn, err := grpc.NewClient(listen.Addr().String(), grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+			require.NoError(t, err)
+			defer conn.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_, err = pb.NewShortenerServiceClient(conn).InternalStats(ctx, &pb.InternalStatsRequest{})
+			assert.Equal(t, test.code, status.Code(err))
+		})
+	}
+}
+
+func TestGrpcTLSConfig_requireClientCert(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	caPath := ca.write(t, dir)
+	serverCert, serverKey := ca.issue(t, dir, "server", &x509.Certificate{DNSNames: []string{"localhost"}})
+
+	cert, err := tlscert.NewReloader(serverCert, serverKey)
+	require.NoError(t, err)
+	tlsConfig, err := grpcTLSConfig(cert, caPath, true)
+	require.NoError(t, err)
+	assert.Equal(t, tls.RequireAndVerifyClientCert, tlsConfig.ClientAuth)
+
+	_, err = grpcTLSConfig(cert, serverKey, false)
+	assert.ErrorIs(t, err, ErrBadClientCA)
+}
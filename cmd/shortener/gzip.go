@@ -8,7 +8,10 @@ import (
 	"strings"
 	"sync"
 
+	"go.uber.org/zap"
+
 	"github.com/serg2014/shortener/internal/logger"
+	"github.com/serg2014/shortener/internal/metrics"
 )
 
 // compressWriter реализует интерфейс http.ResponseWriter и позволяет прозрачно для сервера
@@ -17,6 +20,21 @@ type compressWriter struct {
 	w           http.ResponseWriter
 	zw          *gzip.Writer
 	compression bool
+	// size размер данных до сжатия
+	size int
+}
+
+// countWriter считает размер записанных данных
+type countWriter struct {
+	w    io.Writer
+	size int
+}
+
+// Write implement Write from io.Writer
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.size += n
+	return n, err
 }
 
 func newCompressWriter(gzw *gzip.Writer, w http.ResponseWriter) *compressWriter {
@@ -35,7 +53,9 @@ func (c *compressWriter) Header() http.Header {
 // Write implement Write from http.ResponseWriter
 func (c *compressWriter) Write(p []byte) (int, error) {
 	if c.compression {
-		return c.zw.Write(p)
+		n, err := c.zw.Write(p)
+		c.size += n
+		return n, err
 	}
 	return c.w.Write(p)
 }
@@ -101,10 +121,12 @@ func gzipMiddleware(pool *sync.Pool) func(http.Handler) http.Handler {
 			// проверяем, что клиент умеет получать от сервера сжатые данные в формате gzip
 			acceptEncoding := strings.Split(r.Header.Get("Accept-Encoding"), ", ")
 			supportsGzip := slices.Index(acceptEncoding, "gzip") != -1
-			logger.Log.Sugar().Infof("acceptEncoding: %s, supportsGzip: %t", acceptEncoding, supportsGzip)
+			logger.FromContext(r.Context()).Debug("gzip", zap.Strings("acceptEncoding", acceptEncoding), zap.Bool("supportsGzip", supportsGzip))
 			if supportsGzip {
 				gzw := pool.Get().(*gzip.Writer)
-				gzw.Reset(w)
+				// считаем размер сжатых данных для метрики степени сжатия
+				compressed := &countWriter{w: w}
+				gzw.Reset(compressed)
 				// оборачиваем оригинальный http.ResponseWriter новым с поддержкой сжатия
 				cw := newCompressWriter(gzw, w)
 				// меняем оригинальный http.ResponseWriter на новый
@@ -113,6 +135,9 @@ func gzipMiddleware(pool *sync.Pool) func(http.Handler) http.Handler {
 				defer func() {
 					// не забываем отправить клиенту все сжатые данные после завершения middleware
 					cw.Close()
+					if cw.compression && cw.size > 0 {
+						metrics.GzipRatio.Observe(float64(compressed.size) / float64(cw.size))
+					}
 					// вернуть в буфер
 					pool.Put(gzw)
 				}()
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	pb "github.com/serg2014/shortener/cmd/shortener/proto"
+	"github.com/serg2014/shortener/internal/app"
+	"github.com/serg2014/shortener/internal/logger"
+	"github.com/serg2014/shortener/internal/models"
+)
+
+// readinessInterval how often readiness of app is checked for grpc health
+const readinessInterval = 5 * time.Second
+
+// buildInfo build info of binary
+func buildInfo() models.BuildInfo {
+	return models.BuildInfo{
+		Version: valueOrNA(buildVersion),
+		Date:    valueOrNA(buildDate),
+		Commit:  valueOrNA(buildCommit),
+	}
+}
+
+// updateHealth set status of grpc health by readiness of app.
+// Empty service is the whole server, ShortenerService depends on storage too
+func updateHealth(ctx context.Context, a *app.MyApp, hs *health.Server) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	status := healthpb.HealthCheckResponse_SERVING
+	if err := a.Ready(ctx); err != nil {
+		logger.Log.Warn("not ready", zap.Error(err))
+		status = healthpb.HealthCheckResponse_NOT_SERVING
+	}
+	hs.SetServingStatus("", status)
+	hs.SetServingStatus(pb.ShortenerService_ServiceDesc.ServiceName, status)
+}
+
+// watchReadiness update grpc health every interval until ctx is done
+func watchReadiness(ctx context.Context, a *app.MyApp, hs *health.Server, interval time.Duration) {
+	updateHealth(ctx, a, hs)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			updateHealth(ctx, a, hs)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	pb "github.com/serg2014/shortener/cmd/shortener/proto"
+	"github.com/serg2014/shortener/internal/app"
+	"github.com/serg2014/shortener/internal/config"
+	"github.com/serg2014/shortener/internal/models"
+	"github.com/serg2014/shortener/storage"
+)
+
+func TestRouter_health(t *testing.T) {
+	store, err := storage.NewStorageMemory()
+	require.NoError(t, err)
+	a := app.NewApp(store, nil)
+	ts := httptest.NewServer(Router(a, config.TrustedSubnet{}, config.ACL{}, SecurityOptions{}))
+	defer ts.Close()
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL+"/healthz", nil)
+	require.NoError(t, err)
+	resp, _ := testRequest(t, ts, req)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	req, err = http.NewRequest(http.MethodGet, ts.URL+"/readyz", nil)
+	require.NoError(t, err)
+	resp, _ = testRequest(t, ts, req)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	req, err = http.NewRequest(http.MethodGet, ts.URL+"/version", nil)
+	require.NoError(t, err)
+	resp, body := testRequest(t, ts, req)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var info models.BuildInfo
+	require.NoError(t, json.Unmarshal([]byte(body), &info))
+	assert.Equal(t, buildInfo(), info)
+
+	// при остановке readyz сообщает о неготовности, а healthz нет
+	a.SetShuttingDown()
+	req, err = http.NewRequest(http.MethodGet, ts.URL+"/readyz", nil)
+	require.NoError(t, err)
+	resp, body = testRequest(t, ts, req)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, app.ErrShuttingDown.Error(), body)
+
+	req, err = http.NewRequest(http.MethodGet, ts.URL+"/healthz", nil)
+	require.NoError(t, err)
+	resp, _ = testRequest(t, ts, req)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestUpdateHealth(t *testing.T) {
+	store, err := storage.NewStorageMemory()
+	require.NoError(t, err)
+	a := app.NewApp(store, nil)
+	hs := health.NewServer()
+
+	check := func(service string) healthpb.HealthCheckResponse_ServingStatus {
+		resp, err := hs.Check(t.Context(), &healthpb.HealthCheckRequest{Service: service})
+		require.NoError(t, err)
+		return resp.GetStatus()
+	}
+
+	updateHealth(t.Context(), a, hs)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, check(""))
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, check(pb.ShortenerService_ServiceDesc.ServiceName))
+
+	a.SetShuttingDown()
+	updateHealth(t.Context(), a, hs)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, check(""))
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, check(pb.ShortenerService_ServiceDesc.ServiceName))
+}
@@ -0,0 +1,39 @@
+//go:build !windows
+
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/serg2014/shortener/internal/logger"
+)
+
+// levelOnSignal change level of log until ctx is done: SIGUSR1 - more verbose, SIGUSR2 - less verbose
+func levelOnSignal(ctx context.Context) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGUSR1, syscall.SIGUSR2)
+	defer signal.Stop(ch)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case sig := <-ch:
+			prev := logger.Level.Level()
+			var lvl zapcore.Level
+			if sig == syscall.SIGUSR1 {
+				lvl = logger.MoreVerbose()
+			} else {
+				lvl = logger.LessVerbose()
+			}
+			// пишется с уровнем warn, чтобы смена была видна и при повышенном уровне
+			logger.Log.Warn("log level is changed by signal", zap.String("signal", sig.String()),
+				zap.Stringer("from", prev), zap.Stringer("to", lvl))
+		}
+	}
+}
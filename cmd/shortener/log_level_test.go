@@ -0,0 +1,42 @@
+//go:build !windows
+
+package main
+
+import (
+	"context"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/serg2014/shortener/internal/logger"
+)
+
+func TestLevelOnSignal(t *testing.T) {
+	old := logger.Level.Level()
+	defer logger.Level.SetLevel(old)
+	logger.Level.SetLevel(zapcore.InfoLevel)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		levelOnSignal(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+	// обработчик сигнала регистрируется в горутине
+	time.Sleep(50 * time.Millisecond)
+
+	require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGUSR1))
+	assert.Eventually(t, func() bool { return logger.Level.Level() == zapcore.DebugLevel }, time.Second, 10*time.Millisecond)
+	require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGUSR2))
+	assert.Eventually(t, func() bool { return logger.Level.Level() == zapcore.InfoLevel }, time.Second, 10*time.Millisecond)
+	require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGUSR2))
+	assert.Eventually(t, func() bool { return logger.Level.Level() == zapcore.WarnLevel }, time.Second, 10*time.Millisecond)
+}
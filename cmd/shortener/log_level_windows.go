@@ -0,0 +1,6 @@
+package main
+
+import "context"
+
+// levelOnSignal on windows there are no SIGUSR1 and SIGUSR2, level is changed by admin api only
+func levelOnSignal(ctx context.Context) {}
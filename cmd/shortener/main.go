@@ -5,6 +5,7 @@ package main
 import (
 	"compress/gzip"
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"net"
@@ -14,32 +15,37 @@ import (
 	"syscall"
 	"time"
 
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/chi/v5/middleware"
 	_ "github.com/jackc/pgx/v5/stdlib"
 
 	"github.com/serg2014/shortener/internal/app"
 	"github.com/serg2014/shortener/internal/auth"
+	"github.com/serg2014/shortener/internal/clientip"
 	"github.com/serg2014/shortener/internal/config"
 	"github.com/serg2014/shortener/internal/handlers"
 	"github.com/serg2014/shortener/internal/logger"
-	"github.com/serg2014/shortener/internal/storage"
+	"github.com/serg2014/shortener/internal/metrics"
+	"github.com/serg2014/shortener/internal/tlscert"
+	"github.com/serg2014/shortener/internal/tracing"
+	"github.com/serg2014/shortener/storage"
 
 	pb "github.com/serg2014/shortener/cmd/shortener/proto"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/reflection"
 )
 
-// TODO вынести в конфиг
-// poolSize кол-во горутин обрабатывающих удаление урлов.
-const poolSize = 10
-
 // waitSecBeforeShutdown how many seconds wait before force shutdown
 const waitSecBeforeShutdown = 5 * time.Second
 
+// tracingShutdownTimeout time for sending the last spans
+const tracingShutdownTimeout = 5 * time.Second
+
 var (
 	buildVersion string
 	buildDate    string
@@ -62,13 +68,11 @@ func main() {
 	}
 }
 
-// Router set up routes and return chi.Router
-func Router(a *app.MyApp, trustedNet config.TrustedSubnet) chi.Router {
+// Router set up routes and return chi.Router. pprof is served by AdminRouter.
+// Stats are allowed from trustedNet or from acl stats, admin api is limited by acl if it is set.
+// Route groups redirect, api and admin get security headers of sec
+func Router(a *app.MyApp, trustedNet config.TrustedSubnet, acl config.ACL, sec SecurityOptions) chi.Router {
 	r := chi.NewRouter()
-	r.Route("/debug", func(r chi.Router) {
-		// add pprof
-		r.Mount("/", middleware.Profiler())
-	})
 
 	pool := &sync.Pool{
 		New: func() any {
@@ -77,24 +81,65 @@ func Router(a *app.MyApp, trustedNet config.TrustedSubnet) chi.Router {
 	}
 
 	r.Route("/", func(r chi.Router) {
+		r.Use(logger.RequestIDMiddleware)
+		r.Use(tracing.Middleware)
 		r.Use(logger.WithLogging)
 		r.Use(gzipMiddleware(pool))
 
 		r.Group(func(r chi.Router) {
-			r.Use(TrustedNetsMiddleware(trustedNet))
+			r.Use(sec.Middleware(config.SecurityAPI))
+			r.Get("/healthz", handlers.Healthz())
+			r.Get("/readyz", handlers.Readyz(a))
+			r.Get("/version", handlers.Version(buildInfo()))
+		})
+		r.Group(func(r chi.Router) {
+			r.Use(sec.Middleware(config.SecurityAPI))
+			r.Use(auth.APIKeyMiddleware)
+			r.Use(TrustedNetsMiddleware(acl.GetOr(config.ACLStats, trustedNet)))
 			r.Get("/api/internal/stats", handlers.InternalStats(a))
-
+			r.Method(http.MethodGet, "/metrics", metrics.Handler())
 		})
 		r.Group(func(r chi.Router) {
 			r.Use(auth.AuthMiddleware)
 
-			r.Post("/", handlers.CreateURL(a))
-			r.Get("/{key}", handlers.GetURL(a))
-			r.Post("/api/shorten", handlers.CreateURLJson(a))
-			r.Get("/ping", handlers.Ping(a))
-			r.Post("/api/shorten/batch", handlers.CreateURLBatch(a))
-			r.Get("/api/user/urls", handlers.GetUserURLS(a))
-			r.Delete("/api/user/urls", handlers.DeleteUserURLS(a))
+			r.With(sec.Middleware(config.SecurityRedirect)).Get("/{key}", handlers.GetURL(a))
+			r.Group(func(r chi.Router) {
+				r.Use(sec.Middleware(config.SecurityAPI))
+				r.Get("/ping", handlers.Ping(a))
+				// api ключу нужны права, пользователю доступно все
+				r.With(auth.RequireScope(auth.ScopeCreate)).Post("/", handlers.CreateURL(a))
+				r.With(auth.RequireScope(auth.ScopeCreate)).Post("/api/shorten", handlers.CreateURLJson(a))
+				r.With(auth.RequireScope(auth.ScopeCreate)).Post("/api/shorten/batch", handlers.CreateURLBatch(a))
+				r.With(auth.RequireScope(auth.ScopeRead)).Get("/api/user/urls", handlers.GetUserURLS(a))
+				r.With(auth.RequireScope(auth.ScopeDelete)).Delete("/api/user/urls", handlers.DeleteUserURLS(a))
+				r.Group(func(r chi.Router) {
+					r.Use(auth.RequireScope(auth.ScopeUser))
+					r.Post("/api/user/register", handlers.Register(a))
+					r.Post("/api/user/login", handlers.Login(a))
+					r.Group(func(r chi.Router) {
+						// новые токены и ключи выдаются только по свежему токену
+						r.Use(auth.RequireFreshToken)
+						r.Get("/api/user/token", handlers.GetUserToken())
+						r.Post("/api/user/keys", handlers.CreateAPIKey(a))
+						r.Get("/api/user/keys", handlers.GetUserAPIKeys(a))
+						r.Delete("/api/user/keys/{id}", handlers.RevokeAPIKey(a))
+					})
+				})
+			})
+			r.Route("/api/admin", func(r chi.Router) {
+				r.Use(sec.Middleware(config.SecurityAdmin))
+				r.Use(auth.RequireFreshToken)
+				if nets, ok := acl.Get(config.ACLAdmin); ok {
+					r.Use(ACLMiddleware(nets))
+				}
+				r.Use(auth.RequireRole(auth.RoleAdmin))
+				r.Get("/urls", handlers.AdminSearchURLS(a))
+				r.Post("/urls/{key}/disable", handlers.AdminDisableURL(a))
+				r.Post("/urls/{key}/enable", handlers.AdminEnableURL(a))
+				r.Put("/users/{id}/ban", handlers.AdminBanUser(a))
+				r.Delete("/users/{id}/ban", handlers.AdminBanUser(a))
+				r.Get("/top", handlers.AdminTopURLS(a))
+			})
 		})
 	})
 	return r
@@ -106,46 +151,177 @@ func run() error {
 		return err
 	}
 
-	if err := logger.Initialize(config.Config.LogLevel); err != nil {
+	// уровни компонентов проверены в InitConfig
+	componentLevels, _ := config.Config.ComponentLogLevels()
+	closeLog, err := logger.Init(logger.Options{
+		Level:           config.Config.LogLevel,
+		ComponentLevels: componentLevels,
+		Encoding:        config.Config.LogEncoding,
+		Outputs:         config.Config.LogOutputs(),
+		Rotation: logger.Rotation{
+			MaxSize:    config.Config.LogMaxSize,
+			Interval:   config.Config.LogRotateInterval.Duration(),
+			MaxBackups: config.Config.LogMaxBackups,
+			MaxAge:     config.Config.LogMaxAge.Duration(),
+			Compress:   config.Config.LogCompress,
+		},
+		AccessOutputs: config.Config.AccessLogOutputs(),
+		AccessFormat:  config.Config.AccessLogFormat,
+		AccessSample:  config.Config.AccessLogSample,
+		Privacy:       config.Config.LogPrivacy,
+		RedactParams:  config.Config.LogRedactParamList(),
+	})
+	if err != nil {
+		return err
+	}
+	// логи закрываются последними
+	defer closeLog()
+	if err := setSigningKeys(config.Config.SigningKeys); err != nil {
+		return err
+	}
+	shutdownTracing, err := tracing.Init(context.Background(), tracing.Options{
+		Exporter:    config.Config.TracingExporter,
+		Endpoint:    config.Config.TracingEndpoint,
+		Insecure:    config.Config.TracingInsecure,
+		File:        config.Config.TracingFile,
+		SampleRatio: config.Config.TracingSampleRatio,
+		Version:     buildInfo().Version,
+	})
+	if err != nil {
+		return err
+	}
+	// вызывается последним, после завершения фоновых горутин
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			logger.Log.Error("can not flush traces", zap.Error(err))
+		}
+	}()
+	auth.SetTokenLifetime(config.Config.TokenTTL.Duration(), config.Config.TokenRefresh.Duration())
+	auth.SetTokenGrace(config.Config.TokenGrace.Duration())
+	auth.SetLegacyTokens(config.Config.LegacyTokens)
+	clientip.SetTrustedProxies(config.Config.TrustedProxies.Data)
+	if err := auth.SetServiceRoles(config.Config.ServiceRoles.Roles); err != nil {
 		return err
 	}
 
-	ctx := context.Background()
-	store, err := storage.NewStorage(ctx, config.Config.FileStoragePath, config.Config.DatabaseDSN)
+	// контекст фоновых горутин, в том числе подписок кеша и bloom фильтра
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	store, err := newStore(ctx)
 	if err != nil {
 		return err
 	}
+	// закрываются обертки (подписки кеша и bloom фильтра), затем хранилище
 	defer store.Close()
 
 	app := app.NewApp(store, nil)
+	auth.SetAPIKeyVerifier(app)
+	auth.SetRoleResolver(app)
 
 	srv := http.Server{
-		Addr:    config.Config.ServerAddress.String(),
-		Handler: Router(app, config.Config.TrustedSubnet),
+		Addr: config.Config.ServerAddress.String(),
+		Handler: Router(app, config.Config.TrustedSubnet, config.Config.ACL, SecurityOptions{
+			HSTS:    hstsValue(config.Config.HSTSMaxAge.Duration(), config.Config.HSTSIncludeSubdomains),
+			Headers: config.Config.SecurityHeaders,
+		}),
 	}
 
-	// порт берем +1 от конфига
-	listen, err := net.Listen("tcp", fmt.Sprintf("%s:%d", config.Config.ServerAddress.Host, config.Config.ServerAddress.Port+1))
+	// перенаправление с http на https
+	var redirectSrv *http.Server
+	if config.Config.HTTPRedirectAddress != "" {
+		redirectSrv = &http.Server{
+			Addr:    config.Config.HTTPRedirectAddress,
+			Handler: HTTPSRedirect(config.Config.ServerAddress.Port, config.Config.BaseURL),
+		}
+	}
+
+	// admin listener включается только при заданном адресе
+	var adminSrv *http.Server
+	if config.Config.AdminAddress != "" {
+		adminSrv = &http.Server{
+			Addr: config.Config.AdminAddress,
+			Handler: AdminRouter(
+				app,
+				config.Config.TrustedSubnet,
+				config.Config.ACL,
+				config.Config.AdminUser,
+				config.Config.AdminPassword,
+			),
+		}
+	}
+
+	// сертификаты перечитываются по SIGHUP и при изменении файлов
+	var reloaders []*tlscert.Reloader
+	var httpsCert *tlscert.Reloader
+	if config.Config.HTTPS {
+		httpsCert, err = httpsReloader()
+		if err != nil {
+			return err
+		}
+		reloaders = append(reloaders, httpsCert)
+	}
+	var grpcCert *tlscert.Reloader
+	if config.Config.GRPCTLS() {
+		grpcCert, err = tlscert.NewReloader(config.Config.GRPCTLSCert, config.Config.GRPCTLSKey)
+		if err != nil {
+			return err
+		}
+		reloaders = append(reloaders, grpcCert)
+	}
+	grpcOpts, err := grpcCreds(grpcCert, config.Config.GRPCClientCA, config.Config.GRPCRequireClientCert)
 	if err != nil {
 		return err
 	}
-
 	// создаём gRPC-сервер без зарегистрированной службы
-	grpcSrv := grpc.NewServer(
+	grpcSrv := grpc.NewServer(append(grpcOpts,
+		// span на каждый вызов, контекст трассировки берется из metadata
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
 		// Chain interceptors
 		grpc.ChainUnaryInterceptor(
+			logger.RequestIDInterceptor,
 			logger.LoggerInterceptor,
-			trustedInterceptor(config.Config.TrustedSubnet),
+			auth.CertInterceptor,
 			auth.AuthInterceptor,
+			auth.FreshTokenInterceptor(grpcFreshTokenMethods),
+			trustedInterceptor(config.Config.ACL.GetOr(config.ACLStats, config.Config.TrustedSubnet)),
+			aclInterceptor(grpcMethodNets(config.Config.ACL)),
+			auth.ScopeInterceptor(grpcMethodScopes),
+			auth.RoleInterceptor(grpcMethodRoles),
 			metadataInterceptor,
 		),
-	)
+	)...)
 	// регистрируем сервис
 	pb.RegisterShortenerServiceServer(grpcSrv, &GrpcServer{app: app})
 	reflection.Register(grpcSrv) // Enable reflection for tools like grpcurl
+	// статус сервисов обновляется по готовности приложения
+	healthSrv := health.NewServer()
+	healthpb.RegisterHealthServer(grpcSrv, healthSrv)
+
+	singlePort := config.Config.SinglePort
+	var listen net.Listener
+	if singlePort {
+		// grpc и http на одном порту, grpc отличаем по content-type
+		srv.Handler = grpcMux(grpcSrv, srv.Handler)
+		if httpsCert == nil {
+			srv.Protocols = h2cProtocols()
+		}
+	} else {
+		listen, err = net.Listen("tcp", config.Config.GRPCListenAddress())
+		if err != nil {
+			return err
+		}
+	}
+	var httpsTLS *tls.Config
+	if httpsCert != nil {
+		httpsTLS, err = httpsTLSConfig(httpsCert, singlePort)
+		if err != nil {
+			return err
+		}
+	}
 
 	var wg sync.WaitGroup
-	ctx, cancel := context.WithCancel(context.Background())
 	defer func() {
 		// это нужно если ошибка возникает в ListenAndServe
 		// чтобы по возможности корректно завершить горутины
@@ -153,7 +329,7 @@ func run() error {
 		wg.Wait()
 	}()
 
-	for i := 0; i < poolSize; i++ {
+	for i := 0; i < app.DeleteWorkers(); i++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
@@ -162,6 +338,40 @@ func run() error {
 		}()
 	}
 
+	for _, r := range reloaders {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.Watch(ctx, certWatchInterval)
+		}()
+	}
+	if len(reloaders) != 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reloadOnSignal(ctx, reloaders)
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		levelOnSignal(ctx)
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		watchReadiness(ctx, app, healthSrv, readinessInterval)
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		app.FlushHitsBackground(ctx)
+		logger.Log.Info("Stop hits gorutine")
+	}()
+
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
@@ -173,6 +383,13 @@ func run() error {
 		// 	ждем сигнала от ОС
 		case <-ctxS.Done():
 			logger.Log.Info("catch signal")
+			// балансировщик должен увидеть not ready и снять трафик до остановки серверов
+			app.SetShuttingDown()
+			healthSrv.Shutdown()
+			if delay := config.Config.ShutdownDelay.Duration(); delay > 0 {
+				logger.Log.Info("wait before shutdown", zap.Duration("delay", delay))
+				time.Sleep(delay)
+			}
 		// ждем отмены контекста
 		case <-ctx.Done():
 			logger.Log.Info("stop")
@@ -184,27 +401,47 @@ func run() error {
 		grp := new(errgroup.Group)
 		grp.Go(func() error {
 			logger.Log.Info("Gracefull shutdown http(s) server")
-			if err := srv.Shutdown(ctxT); err != nil {
+			err := srv.Shutdown(ctxT)
+			if err != nil {
 				logger.Log.Info("Server forced to shutdown", zap.Error(err))
 			}
-			return nil
-		})
-		grp.Go(func() error {
-			logger.Log.Info("Gracefull shutdown grpc server")
-			// GracefulStop блокирующая операция
-			grpcSrv.GracefulStop()
-			// отменяем таймаут
-			cancelT()
-			return nil
-		})
-		grp.Go(func() error {
-			<-ctxT.Done()
-			if ctxT.Err() == context.DeadlineExceeded {
-				logger.Log.Info("Force shutdown grpc server")
-				grpcSrv.Stop()
+			if singlePort {
+				// grpc вызовы идут через ServeHTTP и ожидаются в srv.Shutdown.
+				// GracefulStop паникует на активных вызовах ServeHTTP (Drain не реализован),
+				// поэтому он безопасен только если http сервер завершился сам
+				if err != nil {
+					logger.Log.Info("Force shutdown grpc server")
+					grpcSrv.Stop()
+				} else {
+					gracefulStopGrpc(ctxT, grpcSrv)
+				}
 			}
 			return nil
 		})
+		if redirectSrv != nil {
+			grp.Go(func() error {
+				logger.Log.Info("Gracefull shutdown redirect server")
+				if err := redirectSrv.Shutdown(ctxT); err != nil {
+					logger.Log.Info("Redirect server forced to shutdown", zap.Error(err))
+				}
+				return nil
+			})
+		}
+		if adminSrv != nil {
+			grp.Go(func() error {
+				logger.Log.Info("Gracefull shutdown admin server")
+				if err := adminSrv.Shutdown(ctxT); err != nil {
+					logger.Log.Info("Admin server forced to shutdown", zap.Error(err))
+				}
+				return nil
+			})
+		}
+		if !singlePort {
+			grp.Go(func() error {
+				gracefulStopGrpc(ctxT, grpcSrv)
+				return nil
+			})
+		}
 		// ожидаем завершения работы серверов
 		grp.Wait()
 	}()
@@ -216,21 +453,49 @@ func run() error {
 			zap.String("address", config.Config.ServerAddress.String()),
 			zap.String("storage", fmt.Sprintf("%T", store)),
 			zap.Bool("https", config.Config.HTTPS),
+			zap.Bool("single_port", singlePort),
 		)
-		err = ListenAndServe(&srv, config.Config.HTTPS)
+		err = ListenAndServe(&srv, httpsTLS)
 		if err != nil && !errors.Is(err, http.ErrServerClosed) {
 			return fmt.Errorf("ListenAndServe: %w", err)
 		}
 		return nil
 	})
+	// run redirect server
+	if redirectSrv != nil {
+		grp.Go(func() error {
+			logger.Log.Info("Try running redirect server", zap.String("address", redirectSrv.Addr))
+			err := redirectSrv.ListenAndServe()
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				return fmt.Errorf("redirect ListenAndServe: %w", err)
+			}
+			return nil
+		})
+	}
+	// run admin server
+	if adminSrv != nil {
+		grp.Go(func() error {
+			logger.Log.Info("Try running admin server", zap.String("address", adminSrv.Addr))
+			err := adminSrv.ListenAndServe()
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				return fmt.Errorf("admin ListenAndServe: %w", err)
+			}
+			return nil
+		})
+	}
 	// run grpc server
-	grp.Go(func() error {
-		logger.Log.Info("Try running grpc server")
-		if err := grpcSrv.Serve(listen); err != nil {
-			return err
-		}
-		return nil
-	})
+	if !singlePort {
+		grp.Go(func() error {
+			logger.Log.Info("Try running grpc server",
+				zap.String("address", listen.Addr().String()),
+				zap.Bool("tls", config.Config.GRPCTLS()),
+			)
+			if err := grpcSrv.Serve(listen); err != nil {
+				return err
+			}
+			return nil
+		})
+	}
 
 	// ожидаем завершения работы серверов
 	if err := grp.Wait(); err != nil {
@@ -245,19 +510,73 @@ func run() error {
 	return nil
 }
 
-// ListenAndServe - srv.ListenAndServe or srv.ListenAndServeTLS
-func ListenAndServe(srv *http.Server, isHTTPS bool) error {
+// newStore create storage from config wrapped by metrics, cache, bloom filter and tracing.
+// Subscriptions of wrappers to events of other instances are stopped when ctx is done
+func newStore(ctx context.Context) (storage.Storager, error) {
+	store, err := storage.NewStorage(ctx, config.Config.FileStoragePath, config.Config.DatabaseDSN)
+	if err != nil {
+		return nil, err
+	}
+	store = storage.NewMetricsStorage(store)
+	if config.Config.CacheSize > 0 {
+		store = storage.NewCachedStorage(
+			ctx,
+			store,
+			config.Config.CacheSize,
+			config.Config.CacheTTL.Duration(),
+			config.Config.CacheNegativeTTL.Duration(),
+		)
+	}
+	if config.Config.BloomFilter {
+		bloom, err := storage.NewBloomStorage(ctx, store, config.Config.BloomCapacity, config.Config.BloomFPRate)
+		if err != nil {
+			store.Close()
+			return nil, err
+		}
+		store = bloom
+	}
+	// span на каждый вызов хранилища, в том числе обслуженный кешем
+	return storage.NewTracedStorage(store), nil
+}
+
+// ListenAndServe - srv.ListenAndServe or srv.ListenAndServeTLS with tlsConfig
+func ListenAndServe(srv *http.Server, tlsConfig *tls.Config) error {
 	// http
-	if !isHTTPS {
+	if tlsConfig == nil {
 		return srv.ListenAndServe()
 	}
 	// https
-	cert, pk, callback, err := getCertPK()
-	if err != nil {
-		return err
+	srv.TLSConfig = tlsConfig
+	return srv.ListenAndServeTLS("", "")
+}
+
+// gracefulStopGrpc wait for grpc calls until ctx is done, then stop grpc server by force
+func gracefulStopGrpc(ctx context.Context, grpcSrv *grpc.Server) {
+	logger.Log.Info("Gracefull shutdown grpc server")
+	done := make(chan struct{})
+	go func() {
+		// GracefulStop блокирующая операция
+		grpcSrv.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		logger.Log.Info("Force shutdown grpc server")
+		grpcSrv.Stop()
+		<-done
 	}
-	defer callback()
-	logger.Log.Info("Paths", zap.String("cert path", cert), zap.String("pk path", pk))
+}
 
-	return srv.ListenAndServeTLS(cert, pk)
+// setSigningKeys pass keys from config to auth. Without keys auth uses auth.DevKey
+func setSigningKeys(sk config.SigningKeys) error {
+	if len(sk.Keys) == 0 {
+		logger.Log.Warn("signing keys are not set, user tokens are signed by dev key")
+		return nil
+	}
+	keys := make([]auth.Key, 0, len(sk.Keys))
+	for _, key := range sk.Keys {
+		keys = append(keys, auth.Key{ID: key.ID, Secret: []byte(key.Secret)})
+	}
+	return auth.SetKeys(keys)
 }
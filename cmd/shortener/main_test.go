@@ -16,8 +16,8 @@ import (
 	appmock "github.com/serg2014/shortener/internal/app/mock"
 	"github.com/serg2014/shortener/internal/auth"
 	"github.com/serg2014/shortener/internal/config"
-	"github.com/serg2014/shortener/internal/storage"
-	"github.com/serg2014/shortener/internal/storage/mock"
+	"github.com/serg2014/shortener/storage"
+	"github.com/serg2014/shortener/storage/mock"
 )
 
 //lint:ignore U1000 тренируемся отключать проверки
@@ -41,7 +41,7 @@ func TestGetURL(t *testing.T) {
 	require.NoError(t, err)
 
 	a := app.NewApp(store, nil)
-	ts := httptest.NewServer(Router(a, config.TrustedSubnet{}))
+	ts := httptest.NewServer(Router(a, config.TrustedSubnet{}, config.ACL{}, SecurityOptions{}))
 	defer ts.Close()
 
 	type want struct {
@@ -70,8 +70,8 @@ func TestGetURL(t *testing.T) {
 		{
 			name: "test 1",
 			want: want{
-				statusCode:  http.StatusBadRequest,
-				response:    "bad short url\n",
+				statusCode:  http.StatusNotFound,
+				response:    "Not Found\n",
 				contentType: "text/plain; charset=utf-8",
 			},
 			reqParam: reqParam{
@@ -98,6 +98,25 @@ func TestGetURL(t *testing.T) {
 			},
 			store: kv{"abcdefgh", "http://some.ru/123"},
 		},
+		{
+			name: "expired cookie",
+			want: want{
+				statusCode:  http.StatusTemporaryRedirect,
+				location:    "http://some.ru/123",
+				response:    "",
+				contentType: "text/plain",
+			},
+			reqParam: reqParam{
+				method: http.MethodGet,
+				url:    "/abcdefgh",
+				body:   nil,
+				setHeaders: map[string]string{
+					"Accept-Encoding": "",
+					"cookie":          "user_id=dev.some_user_id.1700000000.1702592000.8hMkPRa2NiC1OfSiT3Kd1e8qZovDvoQ2Fhit2QJYPjE",
+				},
+			},
+			store: kv{"abcdefgh", "http://some.ru/123"},
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -142,12 +161,31 @@ func TestGetURL(t *testing.T) {
 	}
 }
 
+func TestRouter_expiredToken(t *testing.T) {
+	store, err := storage.NewStorageMemory()
+	require.NoError(t, err)
+	require.NoError(t, store.Set(t.Context(), "abcdefgh", "http://some.ru/123", "some_user_id"))
+	ts := httptest.NewServer(Router(app.NewApp(store, nil), config.TrustedSubnet{}, config.ACL{}, SecurityOptions{}))
+	defer ts.Close()
+
+	// токен протух давно: запрос работает от нового пользователя
+	req, err := http.NewRequest(http.MethodGet, ts.URL+"/api/user/urls", nil)
+	require.NoError(t, err)
+	req.Header.Set("cookie", "user_id=dev.some_user_id.1700000000.1702592000.8hMkPRa2NiC1OfSiT3Kd1e8qZovDvoQ2Fhit2QJYPjE")
+	resp, _ := testRequest(t, ts, req)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	require.Len(t, resp.Cookies(), 1)
+	userID, err := auth.GetUserIDFromCookie(&http.Request{Header: http.Header{"Cookie": {resp.Cookies()[0].String()}}})
+	require.NoError(t, err)
+	assert.NotEqual(t, auth.UserID("some_user_id"), userID)
+}
+
 func TestCreateURL(t *testing.T) {
 	store, err := storage.NewStorageMemory()
 	require.NoError(t, err)
 
 	a := app.NewApp(store, nil)
-	ts := httptest.NewServer(Router(a, config.TrustedSubnet{}))
+	ts := httptest.NewServer(Router(a, config.TrustedSubnet{}, config.ACL{}, SecurityOptions{}))
 	defer ts.Close()
 
 	type want struct {
@@ -298,7 +336,7 @@ func Test_CreateURL_database(t *testing.T) {
 	store := mock.NewMockStorager(ctrl)
 	gen := appmock.NewMockGenerator(ctrl)
 	a := app.NewApp(store, gen)
-	ts := httptest.NewServer(Router(a, config.TrustedSubnet{}))
+	ts := httptest.NewServer(Router(a, config.TrustedSubnet{}, config.ACL{}, SecurityOptions{}))
 	defer ts.Close()
 
 	// отключить принудительное выставление content-encoding: gzip
@@ -308,7 +346,7 @@ func Test_CreateURL_database(t *testing.T) {
 		return http.ErrUseLastResponse
 	}
 
-	const cookieVal = "user_id=some_user_id.kJusbumVnkwQSAX+zsXQscI83JIE1VVQcfrDpbXB7FQ"
+	const cookieVal = "user_id=dev.some_user_id.1700000000.4102444800.//ECEEF7v+4xinw6/gFudevnxKA3a6ASpxtYBfINfwo"
 	tests := []testsReqItem{
 		{
 			name: "ok",
@@ -464,7 +502,7 @@ func TestGetURL_database(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	store := mock.NewMockStorager(ctrl)
 	a := app.NewApp(store, nil)
-	ts := httptest.NewServer(Router(a, config.TrustedSubnet{}))
+	ts := httptest.NewServer(Router(a, config.TrustedSubnet{}, config.ACL{}, SecurityOptions{}))
 	defer ts.Close()
 
 	// отключить принудительное выставление content-encoding: gzip
@@ -474,7 +512,7 @@ func TestGetURL_database(t *testing.T) {
 		return http.ErrUseLastResponse
 	}
 
-	const cookieVal = "user_id=some_user_id.kJusbumVnkwQSAX+zsXQscI83JIE1VVQcfrDpbXB7FQ"
+	const cookieVal = "user_id=dev.some_user_id.1700000000.4102444800.//ECEEF7v+4xinw6/gFudevnxKA3a6ASpxtYBfINfwo"
 	tests := []testsReqItem{
 		{
 			name: "ok",
@@ -544,7 +582,7 @@ func TestCreateURLJson_database(t *testing.T) {
 	store := mock.NewMockStorager(ctrl)
 	gen := appmock.NewMockGenerator(ctrl)
 	a := app.NewApp(store, gen)
-	ts := httptest.NewServer(Router(a, config.TrustedSubnet{}))
+	ts := httptest.NewServer(Router(a, config.TrustedSubnet{}, config.ACL{}, SecurityOptions{}))
 	defer ts.Close()
 
 	// отключить принудительное выставление content-encoding: gzip
@@ -554,7 +592,7 @@ func TestCreateURLJson_database(t *testing.T) {
 		return http.ErrUseLastResponse
 	}
 
-	const cookieVal = "user_id=some_user_id.kJusbumVnkwQSAX+zsXQscI83JIE1VVQcfrDpbXB7FQ"
+	const cookieVal = "user_id=dev.some_user_id.1700000000.4102444800.//ECEEF7v+4xinw6/gFudevnxKA3a6ASpxtYBfINfwo"
 	tests := []testsReqItem{
 		{
 			name: "ok",
@@ -672,7 +710,7 @@ func TestPing_database(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	store := mock.NewMockStorager(ctrl)
 	a := app.NewApp(store, nil)
-	ts := httptest.NewServer(Router(a, config.TrustedSubnet{}))
+	ts := httptest.NewServer(Router(a, config.TrustedSubnet{}, config.ACL{}, SecurityOptions{}))
 	defer ts.Close()
 
 	// отключить принудительное выставление content-encoding: gzip
@@ -682,7 +720,7 @@ func TestPing_database(t *testing.T) {
 		return http.ErrUseLastResponse
 	}
 
-	const cookieVal = "user_id=some_user_id.kJusbumVnkwQSAX+zsXQscI83JIE1VVQcfrDpbXB7FQ"
+	const cookieVal = "user_id=dev.some_user_id.1700000000.4102444800.//ECEEF7v+4xinw6/gFudevnxKA3a6ASpxtYBfINfwo"
 	tests := []testsReqItem{
 		{
 			name: "ok",
@@ -772,7 +810,7 @@ func TestCreateURLBatch_database(t *testing.T) {
 	store := mock.NewMockStorager(ctrl)
 	gen := appmock.NewMockGenerator(ctrl)
 	a := app.NewApp(store, gen)
-	ts := httptest.NewServer(Router(a, config.TrustedSubnet{}))
+	ts := httptest.NewServer(Router(a, config.TrustedSubnet{}, config.ACL{}, SecurityOptions{}))
 	defer ts.Close()
 
 	// отключить принудительное выставление content-encoding: gzip
@@ -782,7 +820,7 @@ func TestCreateURLBatch_database(t *testing.T) {
 		return http.ErrUseLastResponse
 	}
 
-	const cookieVal = "user_id=some_user_id.kJusbumVnkwQSAX+zsXQscI83JIE1VVQcfrDpbXB7FQ"
+	const cookieVal = "user_id=dev.some_user_id.1700000000.4102444800.//ECEEF7v+4xinw6/gFudevnxKA3a6ASpxtYBfINfwo"
 	tests := []testsReqItem{
 		{
 			name: "ok",
@@ -872,7 +910,7 @@ func TestGetUserURLS_database(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	store := mock.NewMockStorager(ctrl)
 	a := app.NewApp(store, nil)
-	ts := httptest.NewServer(Router(a, config.TrustedSubnet{}))
+	ts := httptest.NewServer(Router(a, config.TrustedSubnet{}, config.ACL{}, SecurityOptions{}))
 	defer ts.Close()
 
 	// отключить принудительное выставление content-encoding: gzip
@@ -882,7 +920,7 @@ func TestGetUserURLS_database(t *testing.T) {
 		return http.ErrUseLastResponse
 	}
 
-	const cookieVal = "user_id=some_user_id.kJusbumVnkwQSAX+zsXQscI83JIE1VVQcfrDpbXB7FQ"
+	const cookieVal = "user_id=dev.some_user_id.1700000000.4102444800.//ECEEF7v+4xinw6/gFudevnxKA3a6ASpxtYBfINfwo"
 	tests := []testsReqItem{
 		{
 			name: "ok",
@@ -1003,7 +1041,7 @@ func TestDeleteUserURLS_database(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	store := mock.NewMockStorager(ctrl)
 	a := app.NewApp(store, nil)
-	ts := httptest.NewServer(Router(a, config.TrustedSubnet{}))
+	ts := httptest.NewServer(Router(a, config.TrustedSubnet{}, config.ACL{}, SecurityOptions{}))
 	defer ts.Close()
 
 	// отключить принудительное выставление content-encoding: gzip
@@ -1013,7 +1051,7 @@ func TestDeleteUserURLS_database(t *testing.T) {
 		return http.ErrUseLastResponse
 	}
 
-	const cookieVal = "user_id=some_user_id.kJusbumVnkwQSAX+zsXQscI83JIE1VVQcfrDpbXB7FQ"
+	const cookieVal = "user_id=dev.some_user_id.1700000000.4102444800.//ECEEF7v+4xinw6/gFudevnxKA3a6ASpxtYBfINfwo"
 	tests := []testsReqItem{
 		{
 			name: "ok",
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/serg2014/shortener/internal/app"
+	"github.com/serg2014/shortener/internal/config"
+	"github.com/serg2014/shortener/internal/metrics"
+	"github.com/serg2014/shortener/storage"
+)
+
+func TestRouter_metrics(t *testing.T) {
+	store, err := storage.NewStorageMemory()
+	require.NoError(t, err)
+	a := app.NewApp(storage.NewMetricsStorage(store), nil)
+	trusted := config.TrustedSubnet{Data: []*net.IPNet{{IP: net.IPv4(127, 0, 0, 0), Mask: net.CIDRMask(8, 32)}}}
+	ts := httptest.NewServer(Router(a, trusted, config.ACL{}, SecurityOptions{}))
+	defer ts.Close()
+
+	created := metrics.HTTPRequests.WithLabelValues("/api/shorten", http.MethodPost, "201")
+	before := testutil.ToFloat64(created)
+	req, err := http.NewRequest(http.MethodPost, ts.URL+"/api/shorten", strings.NewReader(`{"url":"https://example.com/"}`))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp, _ := testRequest(t, ts, req)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	// метрика по шаблону роута
+	assert.Equal(t, before+1, testutil.ToFloat64(created))
+
+	req, err = http.NewRequest(http.MethodGet, ts.URL+"/metrics", nil)
+	require.NoError(t, err)
+	resp, body := testRequest(t, ts, req)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, body, `shortener_http_requests_total{method="POST",route="/api/shorten",status="201"}`)
+	assert.Contains(t, body, `shortener_storage_operation_duration_seconds_count{backend="memory",operation="set",result="ok"}`)
+	assert.Contains(t, body, "shortener_gzip_compression_ratio_count")
+
+	// из недоверенной сети метрики недоступны
+	ts2 := httptest.NewServer(Router(a, config.TrustedSubnet{}, config.ACL{}, SecurityOptions{}))
+	defer ts2.Close()
+	req, err = http.NewRequest(http.MethodGet, ts2.URL+"/metrics", nil)
+	require.NoError(t, err)
+	resp, _ = testRequest(t, ts2, req)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	pb "github.com/serg2014/shortener/cmd/shortener/proto"
+	"github.com/serg2014/shortener/internal/app"
+	"github.com/serg2014/shortener/internal/auth"
+	"github.com/serg2014/shortener/internal/config"
+	"github.com/serg2014/shortener/storage"
+)
+
+func TestGrpcMux_h2c(t *testing.T) {
+	store, err := storage.NewStorageMemory()
+	require.NoError(t, err)
+	a := app.NewApp(store, nil)
+
+	grpcSrv := grpc.NewServer(grpc.ChainUnaryInterceptor(auth.AuthInterceptor))
+	pb.RegisterShortenerServiceServer(grpcSrv, &GrpcServer{app: a})
+	srv := &http.Server{
+		Handler:   grpcMux(grpcSrv, Router(a, config.TrustedSubnet{}, config.ACL{}, SecurityOptions{})),
+		Protocols: h2cProtocols(),
+	}
+	listen, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	done := make(chan error, 1)
+	go func() { done <- srv.Serve(listen) }()
+	addr := listen.Addr().String()
+
+	// http/1
+	resp, err := http.Get("http://" + addr + "/ping")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// grpc по h2c на том же порту
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	client := pb.NewShortenerServiceClient(conn)
+	short, err := client.ShortURLS(ctx, &pb.ShortURLSRequest{Items: []*pb.ShortURLSRequest_Item{{CorrelationId: "1", OriginalUrl: "https://example.com/"}}})
+	require.NoError(t, err)
+	require.Len(t, short.GetItems(), 1)
+
+	// остановка как в run: сначала http сервер, потом grpc
+	require.NoError(t, srv.Shutdown(ctx))
+	grpcSrv.Stop()
+	assert.ErrorIs(t, <-done, http.ErrServerClosed)
+}
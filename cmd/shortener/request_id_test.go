@@ -0,0 +1,34 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/serg2014/shortener/internal/app"
+	"github.com/serg2014/shortener/internal/config"
+	"github.com/serg2014/shortener/internal/logger"
+	"github.com/serg2014/shortener/storage"
+)
+
+func TestRouter_requestID(t *testing.T) {
+	store, err := storage.NewStorageMemory()
+	require.NoError(t, err)
+	a := app.NewApp(store, nil)
+	ts := httptest.NewServer(Router(a, config.TrustedSubnet{}, config.ACL{}, SecurityOptions{}))
+	defer ts.Close()
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL+"/healthz", nil)
+	require.NoError(t, err)
+	req.Header.Set(logger.RequestIDHeader, "abc-123")
+	resp, _ := testRequest(t, ts, req)
+	assert.Equal(t, "abc-123", resp.Header.Get(logger.RequestIDHeader))
+
+	req, err = http.NewRequest(http.MethodGet, ts.URL+"/healthz", nil)
+	require.NoError(t, err)
+	resp, _ = testRequest(t, ts, req)
+	assert.Len(t, resp.Header.Get(logger.RequestIDHeader), 32)
+}
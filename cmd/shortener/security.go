@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/serg2014/shortener/internal/config"
+)
+
+// defaultHSTSMaxAge max-age of Strict-Transport-Security by default
+const defaultHSTSMaxAge = 365 * 24 * time.Hour
+
+// disabledHeader value of policy which disables header
+const disabledHeader = "-"
+
+// defaultSecurityPolicy policy of route group without own values
+var defaultSecurityPolicy = config.SecurityPolicy{
+	// короткая ссылка не должна утекать на целевой сайт
+	ReferrerPolicy: "no-referrer",
+	CSP:            "default-src 'none'; frame-ancestors 'none'",
+}
+
+// SecurityOptions security headers of Router
+type SecurityOptions struct {
+	// HSTS value of Strict-Transport-Security sent over https. Empty - header is not sent
+	HSTS string
+	// Headers policies of route groups
+	Headers config.SecurityHeaders
+}
+
+// hstsValue return value of Strict-Transport-Security. 0 - default max-age, negative - empty value
+func hstsValue(maxAge time.Duration, includeSubdomains bool) string {
+	if maxAge < 0 {
+		return ""
+	}
+	if maxAge == 0 {
+		maxAge = defaultHSTSMaxAge
+	}
+	value := fmt.Sprintf("max-age=%d", int64(maxAge.Seconds()))
+	if includeSubdomains {
+		value += "; includeSubDomains"
+	}
+	return value
+}
+
+// policy return policy of route group with default values
+func (o SecurityOptions) policy(group string) config.SecurityPolicy {
+	policy := o.Headers.Get(group)
+	if policy.ReferrerPolicy == "" {
+		policy.ReferrerPolicy = defaultSecurityPolicy.ReferrerPolicy
+	}
+	if policy.CSP == "" {
+		policy.CSP = defaultSecurityPolicy.CSP
+	}
+	return policy
+}
+
+// Middleware return SecurityHeadersMiddleware with policy of route group
+func (o SecurityOptions) Middleware(group string) func(h http.Handler) http.Handler {
+	return SecurityHeadersMiddleware(o.HSTS, o.policy(group))
+}
+
+// securityWriter set headers depended on response before status is written
+type securityWriter struct {
+	http.ResponseWriter
+	policy      config.SecurityPolicy
+	wroteHeader bool
+}
+
+// WriteHeader implement WriteHeader of http.ResponseWriter
+func (w *securityWriter) WriteHeader(statusCode int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		header := w.Header()
+		if statusCode >= 300 && statusCode < 400 && w.policy.ReferrerPolicy != disabledHeader {
+			header.Set("Referrer-Policy", w.policy.ReferrerPolicy)
+		}
+		if strings.HasPrefix(header.Get("Content-Type"), "text/html") && w.policy.CSP != disabledHeader {
+			header.Set("Content-Security-Policy", w.policy.CSP)
+		}
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write implement Write of http.ResponseWriter
+func (w *securityWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// SecurityHeadersMiddleware set X-Content-Type-Options to all responses, hsts to responses over https,
+// Referrer-Policy to redirects and Content-Security-Policy to html pages
+func SecurityHeadersMiddleware(hsts string, policy config.SecurityPolicy) func(h http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Content-Type-Options", "nosniff")
+			if hsts != "" && r.TLS != nil {
+				w.Header().Set("Strict-Transport-Security", hsts)
+			}
+			h.ServeHTTP(&securityWriter{ResponseWriter: w, policy: policy}, r)
+		})
+	}
+}
+
+// HTTPSRedirect handler redirect request to the same url over https.
+// Host is taken from baseURL if it is https, else from request with httpsPort
+func HTTPSRedirect(httpsPort uint64, baseURL string) http.HandlerFunc {
+	var baseHost string
+	if u, err := url.Parse(baseURL); err == nil && u.Scheme == "https" {
+		baseHost = u.Host
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		host := baseHost
+		if host == "" {
+			host = strings.Trim(r.Host, "[]")
+			if h, _, err := net.SplitHostPort(r.Host); err == nil {
+				host = h
+			}
+			switch {
+			case httpsPort != 443:
+				host = net.JoinHostPort(host, fmt.Sprint(httpsPort))
+			case strings.Contains(host, ":"):
+				// ipv6
+				host = "[" + host + "]"
+			}
+		}
+		target := "https://" + host + r.URL.RequestURI()
+		// 308 сохраняет метод и тело запроса
+		code := http.StatusPermanentRedirect
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			code = http.StatusMovedPermanently
+		}
+		http.Redirect(w, r, target, code)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/serg2014/shortener/internal/app"
+	"github.com/serg2014/shortener/internal/config"
+	"github.com/serg2014/shortener/storage"
+)
+
+func TestHstsValue(t *testing.T) {
+	assert.Equal(t, "max-age=31536000", hstsValue(0, false))
+	assert.Equal(t, "max-age=3600; includeSubDomains", hstsValue(3600e9, true))
+	assert.Equal(t, "", hstsValue(-1, false))
+}
+
+func TestRouter_securityHeaders(t *testing.T) {
+	store, err := storage.NewStorageMemory()
+	require.NoError(t, err)
+	a := app.NewApp(store, nil)
+	require.NoError(t, store.Set(t.Context(), "abc", "https://example.com/", "user"))
+
+	var headers config.SecurityHeaders
+	require.NoError(t, headers.Set(`{"redirect":{"referrer_policy":"origin"},"api":{"csp":"-"}}`))
+	ts := httptest.NewTLSServer(Router(a, config.TrustedSubnet{}, config.ACL{}, SecurityOptions{
+		HSTS:    hstsValue(0, false),
+		Headers: headers,
+	}))
+	defer ts.Close()
+	client := ts.Client()
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	resp, err := client.Get(ts.URL + "/abc")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusTemporaryRedirect, resp.StatusCode)
+	assert.Equal(t, "nosniff", resp.Header.Get("X-Content-Type-Options"))
+	assert.Equal(t, "max-age=31536000", resp.Header.Get("Strict-Transport-Security"))
+	assert.Equal(t, "origin", resp.Header.Get("Referrer-Policy"))
+
+	resp, err = client.Post(ts.URL+"/api/shorten", "application/json", strings.NewReader(`{"url":"https://example.org/"}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, "nosniff", resp.Header.Get("X-Content-Type-Options"))
+	assert.Empty(t, resp.Header.Get("Referrer-Policy"))
+	assert.Empty(t, resp.Header.Get("Content-Security-Policy"))
+}
+
+func TestSecurityHeadersMiddleware_html(t *testing.T) {
+	h := SecurityHeadersMiddleware("max-age=1", defaultSecurityPolicy)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte("<p>hi</p>"))
+	}))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://localhost/", nil))
+	resp := w.Result()
+	defer resp.Body.Close()
+	assert.Equal(t, defaultSecurityPolicy.CSP, resp.Header.Get("Content-Security-Policy"))
+	// по http hsts не отправляется
+	assert.Empty(t, resp.Header.Get("Strict-Transport-Security"))
+}
+
+func TestHTTPSRedirect(t *testing.T) {
+	tests := []struct {
+		name     string
+		port     uint64
+		baseURL  string
+		method   string
+		target   string
+		code     int
+		location string
+	}{
+		{name: "get", port: 8443, method: http.MethodGet, target: "http://short.local:8080/abc?x=1", code: http.StatusMovedPermanently, location: "https://short.local:8443/abc?x=1"},
+		{name: "post keeps method", port: 443, method: http.MethodPost, target: "http://short.local/api/shorten", code: http.StatusPermanentRedirect, location: "https://short.local/api/shorten"},
+		{name: "ipv6", port: 443, method: http.MethodGet, target: "http://[::1]:80/abc", code: http.StatusMovedPermanently, location: "https://[::1]/abc"},
+		{name: "base url", port: 8443, baseURL: "https://sho.rt/", method: http.MethodGet, target: "http://other/abc", code: http.StatusMovedPermanently, location: "https://sho.rt/abc"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			HTTPSRedirect(test.port, test.baseURL)(w, httptest.NewRequest(test.method, test.target, nil))
+			resp := w.Result()
+			defer resp.Body.Close()
+			assert.Equal(t, test.code, resp.StatusCode)
+			assert.Equal(t, test.location, resp.Header.Get("Location"))
+		})
+	}
+}
@@ -4,18 +4,24 @@ import (
 	"context"
 	"net/http"
 
+	"github.com/serg2014/shortener/internal/auth"
+	"github.com/serg2014/shortener/internal/clientip"
 	"github.com/serg2014/shortener/internal/config"
-	"github.com/serg2014/shortener/internal/logger"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
-// TrustedNetsMiddleware middleware for checking request from trusted net
+// statsByAPIKey request is made with api key with scope stats
+func statsByAPIKey(ctx context.Context) bool {
+	return auth.IsAPIKey(ctx) && auth.HasScope(ctx, auth.ScopeStats)
+}
+
+// TrustedNetsMiddleware middleware for checking request from trusted net or with api key with scope stats
 func TrustedNetsMiddleware(trustedNet config.TrustedSubnet) func(h http.Handler) http.Handler {
 	return func(h http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			if !trustedNet.IsTrusted(r.Header.Get("X-Real-IP")) {
+			if !trustedNet.IsTrusted(clientip.FromRequest(r)) && !statsByAPIKey(r.Context()) {
 				code := http.StatusForbidden
 				http.Error(w, http.StatusText(code), code)
 				return
@@ -26,13 +32,54 @@ func TrustedNetsMiddleware(trustedNet config.TrustedSubnet) func(h http.Handler)
 	}
 }
 
-// trustedInterceptor
+// ACLMiddleware middleware for checking request from allowed nets
+func ACLMiddleware(nets config.TrustedSubnet) func(h http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !nets.IsTrusted(clientip.FromRequest(r)) {
+				code := http.StatusForbidden
+				http.Error(w, http.StatusText(code), code)
+				return
+			}
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
+// aclInterceptor check that methods are called from allowed nets. Methods without acl are allowed.
+// Service with role of method from client certificate is allowed from any net
+func aclInterceptor(methodNets map[string]config.TrustedSubnet) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		nets, ok := methodNets[info.FullMethod]
+		if ok && !nets.IsTrusted(clientip.FromContext(ctx)) && !auth.HasServiceRole(ctx, grpcMethodRoles[info.FullMethod]) {
+			code := codes.PermissionDenied
+			return nil, status.Error(code, code.String())
+		}
+		return handler(ctx, req)
+	}
+}
+
+// grpcMethodNets allowed nets of grpc methods from acl. Admin methods are limited by acl admin
+func grpcMethodNets(acl config.ACL) map[string]config.TrustedSubnet {
+	result := make(map[string]config.TrustedSubnet)
+	if nets, ok := acl.Get(config.ACLAdmin); ok {
+		for method, role := range grpcMethodRoles {
+			if role == auth.RoleAdmin {
+				result[method] = nets
+			}
+		}
+	}
+	return result
+}
+
+// trustedInterceptor allow InternalStats from trustedNet, with api key with scope stats
+// or for service with role stats
 func trustedInterceptor(trustedNet config.TrustedSubnet) func(context.Context, any, *grpc.UnaryServerInfo, grpc.UnaryHandler) (any, error) {
 	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
 		// выполняем действия перед вызовом метода
 		if info.FullMethod == "/shortener.ShortenerService/InternalStats" {
-			// TODO getIP define in logging. is it true way?
-			trust := trustedNet.IsTrusted(logger.GetIP(ctx))
+			trust := trustedNet.IsTrusted(clientip.FromContext(ctx)) || statsByAPIKey(ctx) ||
+				auth.HasServiceRole(ctx, auth.RoleStats)
 			if !trust {
 				code := codes.PermissionDenied
 				return nil, status.Error(code, code.String())
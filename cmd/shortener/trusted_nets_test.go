@@ -7,7 +7,10 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/serg2014/shortener/internal/app"
+	"github.com/serg2014/shortener/internal/clientip"
 	"github.com/serg2014/shortener/internal/config"
+	"github.com/serg2014/shortener/storage"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -21,6 +24,7 @@ func TestTrustedNetsMiddleware(t *testing.T) {
 	tests := []struct {
 		headers       map[string]string
 		name          string
+		remoteAddr    string
 		TrustedSubnet config.TrustedSubnet
 		expect        expect
 	}{
@@ -58,10 +62,10 @@ func TestTrustedNetsMiddleware(t *testing.T) {
 		{
 			name: "11",
 			TrustedSubnet: config.TrustedSubnet{
-				Data: &net.IPNet{
+				Data: []*net.IPNet{{
 					IP:   net.IP([]byte{0x7f, 0x0, 0x0, 0x0}),
 					Mask: net.IPMask([]byte{0xff, 0xff, 0xff, 0x0}),
-				},
+				}},
 			},
 			expect: expect{
 				StatusCode: http.StatusForbidden,
@@ -74,10 +78,10 @@ func TestTrustedNetsMiddleware(t *testing.T) {
 				"X-Real-IP": "not valid",
 			},
 			TrustedSubnet: config.TrustedSubnet{
-				Data: &net.IPNet{
+				Data: []*net.IPNet{{
 					IP:   net.IP([]byte{0x7f, 0x0, 0x0, 0x0}),
 					Mask: net.IPMask([]byte{0xff, 0xff, 0xff, 0x0}),
-				},
+				}},
 			},
 			expect: expect{
 				StatusCode: http.StatusForbidden,
@@ -90,10 +94,10 @@ func TestTrustedNetsMiddleware(t *testing.T) {
 				"X-Real-IP": "192.168.0.1",
 			},
 			TrustedSubnet: config.TrustedSubnet{
-				Data: &net.IPNet{
+				Data: []*net.IPNet{{
 					IP:   net.IP([]byte{0x7f, 0x0, 0x0, 0x0}),
 					Mask: net.IPMask([]byte{0xff, 0xff, 0xff, 0x0}),
-				},
+				}},
 			},
 			expect: expect{
 				StatusCode: http.StatusForbidden,
@@ -101,15 +105,47 @@ func TestTrustedNetsMiddleware(t *testing.T) {
 			},
 		},
 		{
-			name: "31",
+			// заголовок от недоверенного пира не учитывается
+			name: "spoofed header",
 			headers: map[string]string{
 				"X-Real-IP": "127.0.0.1",
 			},
 			TrustedSubnet: config.TrustedSubnet{
-				Data: &net.IPNet{
+				Data: []*net.IPNet{{
+					IP:   net.IP([]byte{0x7f, 0x0, 0x0, 0x0}),
+					Mask: net.IPMask([]byte{0xff, 0xff, 0xff, 0x0}),
+				}},
+			},
+			expect: expect{
+				StatusCode: http.StatusForbidden,
+				data:       http.StatusText(http.StatusForbidden) + "\n",
+			},
+		},
+		{
+			name:       "trusted peer",
+			remoteAddr: "127.0.0.1:1234",
+			TrustedSubnet: config.TrustedSubnet{
+				Data: []*net.IPNet{{
 					IP:   net.IP([]byte{0x7f, 0x0, 0x0, 0x0}),
 					Mask: net.IPMask([]byte{0xff, 0xff, 0xff, 0x0}),
-				},
+				}},
+			},
+			expect: expect{
+				StatusCode: http.StatusOK,
+				data:       data,
+			},
+		},
+		{
+			name:       "header from trusted proxy",
+			remoteAddr: "10.0.0.1:1234",
+			headers: map[string]string{
+				"X-Forwarded-For": "127.0.0.1, 10.0.0.2",
+			},
+			TrustedSubnet: config.TrustedSubnet{
+				Data: []*net.IPNet{{
+					IP:   net.IP([]byte{0x7f, 0x0, 0x0, 0x0}),
+					Mask: net.IPMask([]byte{0xff, 0xff, 0xff, 0x0}),
+				}},
 			},
 			expect: expect{
 				StatusCode: http.StatusOK,
@@ -117,6 +153,8 @@ func TestTrustedNetsMiddleware(t *testing.T) {
 			},
 		},
 	}
+	clientip.SetTrustedProxies([]*net.IPNet{{IP: net.IP{10, 0, 0, 0}, Mask: net.CIDRMask(8, 32)}})
+	defer clientip.SetTrustedProxies(nil)
 
 	nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -130,6 +168,9 @@ func TestTrustedNetsMiddleware(t *testing.T) {
 			handlerToTest := tsn(nextHandler)
 			// create a mock request to use
 			req := httptest.NewRequest("GET", "http://localhost/api/internal/stats", nil)
+			if test.remoteAddr != "" {
+				req.RemoteAddr = test.remoteAddr
+			}
 			if len(test.headers) != 0 {
 				for k := range test.headers {
 					req.Header.Add(k, test.headers[k])
@@ -149,3 +190,32 @@ func TestTrustedNetsMiddleware(t *testing.T) {
 		})
 	}
 }
+
+func TestRouter_acl(t *testing.T) {
+	store, err := storage.NewStorageMemory()
+	require.NoError(t, err)
+	a := app.NewApp(store, nil)
+
+	tests := []struct {
+		name       string
+		acl        string
+		url        string
+		statusCode int
+	}{
+		{name: "no debug on public router", url: "/debug/pprof/", statusCode: http.StatusNotFound},
+		{name: "stats without acl and trusted net", url: "/api/internal/stats", statusCode: http.StatusForbidden},
+		{name: "stats from allowed ipv6 or ipv4 net", acl: "stats=::1/128,127.0.0.0/8", url: "/api/internal/stats", statusCode: http.StatusOK},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var acl config.ACL
+			require.NoError(t, acl.Set(test.acl))
+			ts := httptest.NewServer(Router(a, config.TrustedSubnet{}, acl, SecurityOptions{}))
+			defer ts.Close()
+			req, err := http.NewRequest(http.MethodGet, ts.URL+test.url, nil)
+			require.NoError(t, err)
+			resp, _ := testRequest(t, ts, req)
+			assert.Equal(t, test.statusCode, resp.StatusCode)
+		})
+	}
+}
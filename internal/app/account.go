@@ -0,0 +1,120 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/serg2014/shortener/internal/auth"
+	"github.com/serg2014/shortener/internal/models"
+	"github.com/serg2014/shortener/storage"
+)
+
+const (
+	// loginMinLen, loginMaxLen limits of length of login
+	loginMinLen = 3
+	loginMaxLen = 64
+	// passwordMinLen min length of password
+	passwordMinLen = 8
+	// passwordMaxLen bcrypt uses only first 72 bytes of password
+	passwordMaxLen = 72
+)
+
+// ErrBadAccountRequest error for bad login or password of new account
+var ErrBadAccountRequest = errors.New("bad account request")
+
+// ErrLoginTaken error when login is already registered
+var ErrLoginTaken = errors.New("login is already taken")
+
+// ErrLoginReserved error when login of admin from config is registered by user.
+// Admin account is registered before its login is added to config
+var ErrLoginReserved = fmt.Errorf("%w: login is reserved", ErrLoginTaken)
+
+// ErrBadCredentials error for unknown login or wrong password
+var ErrBadCredentials = errors.New("bad login or password")
+
+// dummyHash is compared with password of unknown login, so answer takes the same time
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+
+// Register create account with login and password. Links of current user are kept:
+// account gets userID if it is fresh (see auth.FreshToken) and does not belong to other account yet.
+// Return userid of account
+func (a *MyApp) Register(ctx context.Context, userID auth.UserID, req models.RequestCredentials) (auth.UserID, error) {
+	if a.accounts == nil {
+		return "", storage.ErrNotSupported
+	}
+	if len(req.Login) < loginMinLen || len(req.Login) > loginMaxLen {
+		return "", fmt.Errorf("%w: login must have %d..%d chars", ErrBadAccountRequest, loginMinLen, loginMaxLen)
+	}
+	if len(req.Password) < passwordMinLen || len(req.Password) > passwordMaxLen {
+		return "", fmt.Errorf("%w: password must have %d..%d bytes", ErrBadAccountRequest, passwordMinLen, passwordMaxLen)
+	}
+	if slices.Contains(a.admins, req.Login) {
+		// иначе роль admin получит любой, кто первым зарегистрирует логин из конфига
+		return "", ErrLoginReserved
+	}
+	_, hasAccount, err := a.accounts.GetAccountByUserID(ctx, string(userID))
+	if err != nil {
+		return "", err
+	}
+	if hasAccount || !auth.FreshToken(ctx) {
+		// пользователь уже владеет аккаунтом, новому аккаунту нужен новый userid.
+		// userid протухшего токена мог быть украден, к нему аккаунт не привязывается
+		userID = auth.NewUserID()
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	account := storage.Account{
+		Login:        req.Login,
+		UserID:       string(userID),
+		PasswordHash: string(hash),
+		CreatedAt:    time.Now().UTC().Truncate(time.Microsecond),
+	}
+	if err := a.accounts.CreateAccount(ctx, account); err != nil {
+		if errors.Is(err, storage.ErrConflict) {
+			return "", ErrLoginTaken
+		}
+		return "", err
+	}
+	return userID, nil
+}
+
+// Login check login and password and return userid of account.
+// Links of anonymous current user with fresh token are moved to the account
+func (a *MyApp) Login(ctx context.Context, userID auth.UserID, req models.RequestCredentials) (auth.UserID, error) {
+	if a.accounts == nil {
+		return "", storage.ErrNotSupported
+	}
+	account, ok, err := a.accounts.GetAccount(ctx, req.Login)
+	if err != nil {
+		return "", err
+	}
+	hash := dummyHash
+	if ok {
+		hash = []byte(account.PasswordHash)
+	}
+	if err := bcrypt.CompareHashAndPassword(hash, []byte(req.Password)); err != nil || !ok {
+		return "", ErrBadCredentials
+	}
+
+	// ссылки переносятся только по свежему токену: протухший мог быть украден
+	if userID != "" && string(userID) != account.UserID && auth.FreshToken(ctx) {
+		// ссылки другого аккаунта не переносим
+		_, hasAccount, err := a.accounts.GetAccountByUserID(ctx, string(userID))
+		if err != nil {
+			return "", err
+		}
+		if !hasAccount {
+			if err := a.accounts.MergeUserURLS(ctx, string(userID), account.UserID); err != nil {
+				return "", err
+			}
+		}
+	}
+	return auth.UserID(account.UserID), nil
+}
@@ -0,0 +1,84 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/serg2014/shortener/internal/auth"
+	"github.com/serg2014/shortener/internal/models"
+	"github.com/serg2014/shortener/storage"
+)
+
+func TestAccount(t *testing.T) {
+	store, err := storage.NewStorageMemory()
+	require.NoError(t, err)
+	a := NewApp(store, nil)
+	creds := models.RequestCredentials{Login: "alice", Password: "password1"}
+
+	// аккаунт получает userid текущего пользователя вместе с его ссылками
+	require.NoError(t, store.Set(t.Context(), "a1234567", "http://one.ru", "device1"))
+	userID, err := a.Register(t.Context(), "device1", creds)
+	require.NoError(t, err)
+	assert.Equal(t, auth.UserID("device1"), userID)
+
+	_, err = a.Register(t.Context(), "device2", creds)
+	assert.ErrorIs(t, err, ErrLoginTaken)
+
+	// вход с другого устройства переносит анонимные ссылки в аккаунт
+	require.NoError(t, store.Set(t.Context(), "b1234567", "http://two.ru", "device2"))
+	userID, err = a.Login(t.Context(), "device2", creds)
+	require.NoError(t, err)
+	assert.Equal(t, auth.UserID("device1"), userID)
+	urls, err := a.GetUserURLS(t.Context(), "device1")
+	require.NoError(t, err)
+	assert.Len(t, urls, 2)
+
+	for _, bad := range []models.RequestCredentials{
+		{Login: "alice", Password: "password2"},
+		{Login: "bob", Password: "password1"},
+	} {
+		_, err = a.Login(t.Context(), "device3", bad)
+		assert.ErrorIs(t, err, ErrBadCredentials, bad.Login)
+	}
+}
+
+func TestAccount_otherAccount(t *testing.T) {
+	store, err := storage.NewStorageMemory()
+	require.NoError(t, err)
+	a := NewApp(store, nil)
+
+	alice := models.RequestCredentials{Login: "alice", Password: "password1"}
+	bob := models.RequestCredentials{Login: "bob", Password: "password2"}
+	_, err = a.Register(t.Context(), "user1", alice)
+	require.NoError(t, err)
+	require.NoError(t, store.Set(t.Context(), "a1234567", "http://one.ru", "user1"))
+
+	// пользователь с аккаунтом регистрирует второй аккаунт под новым userid
+	bobID, err := a.Register(t.Context(), "user1", bob)
+	require.NoError(t, err)
+	assert.NotEqual(t, auth.UserID("user1"), bobID)
+
+	// ссылки аккаунта не переносятся в другой аккаунт
+	_, err = a.Login(t.Context(), "user1", bob)
+	require.NoError(t, err)
+	urls, err := a.GetUserURLS(t.Context(), bobID)
+	require.NoError(t, err)
+	assert.Empty(t, urls)
+}
+
+func TestRegister_badRequest(t *testing.T) {
+	store, err := storage.NewStorageMemory()
+	require.NoError(t, err)
+	a := NewApp(store, nil)
+
+	for _, req := range []models.RequestCredentials{
+		{Login: "al", Password: "password1"},
+		{Login: "alice", Password: "short"},
+		{Login: "alice", Password: string(make([]byte, 73))},
+	} {
+		_, err := a.Register(t.Context(), "user1", req)
+		assert.ErrorIs(t, err, ErrBadAccountRequest)
+	}
+}
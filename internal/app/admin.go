@@ -0,0 +1,167 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"go.uber.org/zap"
+
+	"github.com/serg2014/shortener/internal/auth"
+	"github.com/serg2014/shortener/internal/logger"
+	"github.com/serg2014/shortener/internal/models"
+	"github.com/serg2014/shortener/storage"
+)
+
+const (
+	// defaultAdminLimit count of urls in answer of admin api by default
+	defaultAdminLimit = 100
+	// maxAdminLimit max count of urls in answer of admin api
+	maxAdminLimit = 1000
+)
+
+// ErrBadAdminRequest error for bad params of admin api
+var ErrBadAdminRequest = errors.New("bad admin request")
+
+// ErrUserBanned error when banned user creates urls
+var ErrUserBanned = errors.New("user is banned")
+
+// UserRole return role of user: admin for accounts from config, user for other accounts,
+// empty for anonymous. Logins from config can not be registered, so admin accounts are the ones
+// registered before their logins were added to config.
+// Without accounts in storage all users are anonymous. Implements auth.RoleResolver
+func (a *MyApp) UserRole(ctx context.Context, userID auth.UserID) (string, error) {
+	if a.accounts == nil {
+		return "", nil
+	}
+	account, ok, err := a.accounts.GetAccountByUserID(ctx, string(userID))
+	if err != nil || !ok {
+		return "", err
+	}
+	if slices.Contains(a.admins, account.Login) {
+		return auth.RoleAdmin, nil
+	}
+	return auth.RoleUser, nil
+}
+
+// audit log action of admin. Every call of admin api is audited
+func (a *MyApp) audit(ctx context.Context, adminID auth.UserID, action string, err error, fields ...zap.Field) {
+	fields = append(fields, logger.UserIDField("admin", string(adminID)))
+	if err != nil {
+		fields = append(fields, zap.Error(err))
+	}
+	logger.Component(ctx, componentApp).Named("audit").Info(action, fields...)
+}
+
+// adminLimit check limit of admin api. 0 - default limit
+func adminLimit(limit int) (int, error) {
+	if limit == 0 {
+		return defaultAdminLimit, nil
+	}
+	if limit < 0 || limit > maxAdminLimit {
+		return 0, fmt.Errorf("%w: limit must be 1..%d", ErrBadAdminRequest, maxAdminLimit)
+	}
+	return limit, nil
+}
+
+func toResponseAdminURLS(urls []storage.URLInfo) []models.ResponseAdminURL {
+	result := make([]models.ResponseAdminURL, len(urls))
+	for i, url := range urls {
+		result[i] = models.ResponseAdminURL{
+			ShortURL:    URLTemplate(url.ShortURL),
+			OriginalURL: url.OriginalURL,
+			UserID:      url.UserID,
+			Deleted:     url.Deleted,
+			Disabled:    url.Disabled,
+			Hits:        url.Hits,
+		}
+	}
+	return result
+}
+
+// AdminSearchURLS find urls of all users by domain of orig url and user. Empty params match all urls
+func (a *MyApp) AdminSearchURLS(ctx context.Context, adminID auth.UserID, domain, userID string, limit int) (result []models.ResponseAdminURL, err error) {
+	defer func() {
+		a.audit(ctx, adminID, "search urls", err, zap.String("domain", domain), logger.UserIDField("user_id", userID), zap.Int("found", len(result)))
+	}()
+	if a.moderation == nil {
+		return nil, storage.ErrNotSupported
+	}
+	limit, err = adminLimit(limit)
+	if err != nil {
+		return nil, err
+	}
+	urls, err := a.moderation.SearchURLS(ctx, storage.URLFilter{Domain: domain, UserID: userID, Limit: limit})
+	if err != nil {
+		return nil, err
+	}
+	return toResponseAdminURLS(urls), nil
+}
+
+// AdminDisableURL disable url with reason storage.DisabledGone or storage.DisabledLegal.
+// Empty reason enables url. Return storage.ErrNotFound for unknown url
+func (a *MyApp) AdminDisableURL(ctx context.Context, adminID auth.UserID, key, reason string) (err error) {
+	defer func() {
+		a.audit(ctx, adminID, "disable url", err, zap.String("short_url", key), zap.String("reason", reason))
+	}()
+	switch reason {
+	case "", storage.DisabledGone, storage.DisabledLegal:
+	default:
+		return fmt.Errorf("%w: reason must be %s or %s", ErrBadAdminRequest, storage.DisabledGone, storage.DisabledLegal)
+	}
+	if a.moderation == nil {
+		return storage.ErrNotSupported
+	}
+	return a.moderation.DisableURL(ctx, key, reason)
+}
+
+// AdminBanUser ban or unban user. Banned user can not create urls
+func (a *MyApp) AdminBanUser(ctx context.Context, adminID auth.UserID, userID string, banned bool) (err error) {
+	defer func() {
+		a.audit(ctx, adminID, "ban user", err, logger.UserIDField("user_id", userID), zap.Bool("banned", banned))
+	}()
+	if userID == "" {
+		return fmt.Errorf("%w: empty user", ErrBadAdminRequest)
+	}
+	if a.moderation == nil {
+		return storage.ErrNotSupported
+	}
+	return a.moderation.SetUserBanned(ctx, userID, banned)
+}
+
+// AdminTopURLS return urls with max count of redirects
+func (a *MyApp) AdminTopURLS(ctx context.Context, adminID auth.UserID, limit int) (result []models.ResponseAdminURL, err error) {
+	defer func() {
+		a.audit(ctx, adminID, "top urls", err, zap.Int("limit", limit))
+	}()
+	if a.hitsStore == nil {
+		return nil, storage.ErrNotSupported
+	}
+	limit, err = adminLimit(limit)
+	if err != nil {
+		return nil, err
+	}
+	// отдаем свежие счетчики
+	a.FlushHits(ctx)
+	urls, err := a.hitsStore.TopURLS(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+	return toResponseAdminURLS(urls), nil
+}
+
+// checkBanned return ErrUserBanned for banned user. Storage without moderation has no banned users
+func (a *MyApp) checkBanned(ctx context.Context, userID auth.UserID) error {
+	if a.moderation == nil {
+		return nil
+	}
+	banned, err := a.moderation.IsUserBanned(ctx, string(userID))
+	if err != nil {
+		return err
+	}
+	if banned {
+		return ErrUserBanned
+	}
+	return nil
+}
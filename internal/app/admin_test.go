@@ -0,0 +1,123 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/serg2014/shortener/internal/auth"
+	"github.com/serg2014/shortener/internal/models"
+	"github.com/serg2014/shortener/storage"
+	"github.com/serg2014/shortener/storage/mock"
+)
+
+func TestUserRole(t *testing.T) {
+	store, err := storage.NewStorageMemory()
+	require.NoError(t, err)
+	a := NewApp(store, nil)
+
+	// аккаунт админа регистрируется до того, как логин попадает в конфиг
+	_, err = a.Register(t.Context(), "user1", models.RequestCredentials{Login: "admin", Password: "password1"})
+	require.NoError(t, err)
+	a.admins = []string{"admin"}
+	_, err = a.Register(t.Context(), "user2", models.RequestCredentials{Login: "alice", Password: "password1"})
+	require.NoError(t, err)
+
+	for userID, expect := range map[auth.UserID]string{"user1": auth.RoleAdmin, "user2": auth.RoleUser, "anon": ""} {
+		role, err := a.UserRole(t.Context(), userID)
+		require.NoError(t, err)
+		assert.Equal(t, expect, role, userID)
+	}
+}
+
+func TestUserRole_registerAdminLogin(t *testing.T) {
+	store, err := storage.NewStorageMemory()
+	require.NoError(t, err)
+	a := NewApp(store, nil)
+	a.admins = []string{"admin"}
+
+	_, err = a.Register(t.Context(), "user1", models.RequestCredentials{Login: "admin", Password: "password1"})
+	require.ErrorIs(t, err, ErrLoginReserved)
+	assert.ErrorIs(t, err, ErrLoginTaken)
+
+	role, err := a.UserRole(t.Context(), "user1")
+	require.NoError(t, err)
+	assert.NotEqual(t, auth.RoleAdmin, role)
+}
+
+func TestAdmin(t *testing.T) {
+	store, err := storage.NewStorageMemory()
+	require.NoError(t, err)
+	a := NewApp(store, nil)
+
+	require.NoError(t, store.Set(t.Context(), "a1234567", "https://example.com/one", "user1"))
+	require.NoError(t, store.Set(t.Context(), "a1234568", "https://other.ru/", "user2"))
+
+	urls, err := a.AdminSearchURLS(t.Context(), "admin", "example.com", "", 0)
+	require.NoError(t, err)
+	require.Len(t, urls, 1)
+	assert.Equal(t, URLTemplate("a1234567"), urls[0].ShortURL)
+	assert.Equal(t, "user1", urls[0].UserID)
+	_, err = a.AdminSearchURLS(t.Context(), "admin", "", "", maxAdminLimit+1)
+	assert.ErrorIs(t, err, ErrBadAdminRequest)
+
+	// переходы считаются и попадают в топ
+	for range 2 {
+		_, ok, err := a.Get(t.Context(), "a1234568")
+		require.NoError(t, err)
+		require.True(t, ok)
+	}
+	_, _, err = a.Get(t.Context(), "a1234567")
+	require.NoError(t, err)
+	top, err := a.AdminTopURLS(t.Context(), "admin", 1)
+	require.NoError(t, err)
+	require.Len(t, top, 1)
+	assert.Equal(t, URLTemplate("a1234568"), top[0].ShortURL)
+	assert.Equal(t, uint64(2), top[0].Hits)
+
+	assert.ErrorIs(t, a.AdminDisableURL(t.Context(), "admin", "a1234567", "bad"), ErrBadAdminRequest)
+	assert.ErrorIs(t, a.AdminDisableURL(t.Context(), "admin", "unknown1", storage.DisabledGone), storage.ErrNotFound)
+	require.NoError(t, a.AdminDisableURL(t.Context(), "admin", "a1234567", storage.DisabledLegal))
+	_, _, err = a.Get(t.Context(), "a1234567")
+	assert.ErrorIs(t, err, storage.ErrDisabledLegal)
+	require.NoError(t, a.AdminDisableURL(t.Context(), "admin", "a1234567", ""))
+	_, _, err = a.Get(t.Context(), "a1234567")
+	assert.NoError(t, err)
+
+	// забаненный пользователь не создает урлы
+	require.NoError(t, a.AdminBanUser(t.Context(), "admin", "user1", true))
+	_, err = a.GenerateShortURL(t.Context(), "http://new.ru", "user1")
+	assert.ErrorIs(t, err, ErrUserBanned)
+	_, err = a.GenerateShortURLBatch(t.Context(), models.RequestBatch{{CorrelationID: "1", OriginalURL: "http://new.ru"}}, "user1")
+	assert.ErrorIs(t, err, ErrUserBanned)
+	require.NoError(t, a.AdminBanUser(t.Context(), "admin", "user1", false))
+	_, err = a.GenerateShortURL(t.Context(), "http://new.ru", "user1")
+	assert.NoError(t, err)
+}
+
+func TestOptionalStorage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	// в моке только методы Storager: ни аккаунтов, ни ключей, ни модерации
+	store := mock.NewMockStorager(ctrl)
+	store.EXPECT().Set(gomock.Any(), gomock.Any(), "http://new.ru", "user1").Return(nil)
+	a := NewApp(store, nil)
+
+	_, err := a.Register(t.Context(), "user1", models.RequestCredentials{Login: "alice", Password: "password1"})
+	assert.ErrorIs(t, err, storage.ErrNotSupported)
+	_, err = a.CreateAPIKey(t.Context(), "user1", models.RequestAPIKey{Name: "backend", Scopes: []string{auth.ScopeRead}})
+	assert.ErrorIs(t, err, storage.ErrNotSupported)
+	_, _, err = a.VerifyAPIKey(t.Context(), "sk_k1_secret")
+	assert.ErrorIs(t, err, auth.ErrNoAPIKeys)
+	_, err = a.AdminTopURLS(t.Context(), "admin", 0)
+	assert.ErrorIs(t, err, storage.ErrNotSupported)
+	assert.ErrorIs(t, a.AdminBanUser(t.Context(), "admin", "user2", true), storage.ErrNotSupported)
+
+	role, err := a.UserRole(t.Context(), "user1")
+	require.NoError(t, err)
+	assert.Empty(t, role)
+	// без модерации баны не проверяются
+	_, err = a.GenerateShortURL(t.Context(), "http://new.ru", "user1")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,151 @@
+package app
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/serg2014/shortener/internal/auth"
+	"github.com/serg2014/shortener/internal/logger"
+	"github.com/serg2014/shortener/internal/models"
+	"github.com/serg2014/shortener/storage"
+)
+
+const (
+	// apiKeyPrefix prefix of api key: sk_<id>_<secret>
+	apiKeyPrefix = "sk"
+	// apiKeyIDBytes random bytes of id. Id is saved in storage as is
+	apiKeyIDBytes = 8
+	// apiKeySecretBytes random bytes of secret. Only hash of secret is saved
+	apiKeySecretBytes = 32
+	// apiKeyNameMaxLen max length of name of api key
+	apiKeyNameMaxLen = 100
+	// apiKeyTouchInterval last usage of api key is saved not often than this
+	apiKeyTouchInterval = time.Minute
+)
+
+// ErrBadAPIKeyRequest error for bad name or scopes of new api key
+var ErrBadAPIKeyRequest = errors.New("bad api key request")
+
+func hashAPIKeySecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func toResponseAPIKey(key storage.APIKey) models.ResponseAPIKey {
+	return models.ResponseAPIKey{
+		ID:         key.ID,
+		Name:       key.Name,
+		Scopes:     key.Scopes,
+		CreatedAt:  key.CreatedAt,
+		LastUsedAt: key.LastUsedAt,
+		RevokedAt:  key.RevokedAt,
+	}
+}
+
+// CreateAPIKey create api key of user with scopes. Key is returned only here, storage keeps its hash
+func (a *MyApp) CreateAPIKey(ctx context.Context, userID auth.UserID, req models.RequestAPIKey) (models.ResponseCreateAPIKey, error) {
+	if a.apiKeys == nil {
+		return models.ResponseCreateAPIKey{}, storage.ErrNotSupported
+	}
+	if req.Name == "" || len(req.Name) > apiKeyNameMaxLen {
+		return models.ResponseCreateAPIKey{}, fmt.Errorf("%w: name must have 1..%d chars", ErrBadAPIKeyRequest, apiKeyNameMaxLen)
+	}
+	if len(req.Scopes) == 0 {
+		return models.ResponseCreateAPIKey{}, fmt.Errorf("%w: no scopes", ErrBadAPIKeyRequest)
+	}
+	for _, scope := range req.Scopes {
+		if !auth.ValidScope(scope) {
+			return models.ResponseCreateAPIKey{}, fmt.Errorf("%w: unknown scope %q", ErrBadAPIKeyRequest, scope)
+		}
+	}
+
+	id := make([]byte, apiKeyIDBytes)
+	secret := make([]byte, apiKeySecretBytes)
+	rand.Read(id)
+	rand.Read(secret)
+	key := storage.APIKey{
+		ID:        hex.EncodeToString(id),
+		UserID:    string(userID),
+		Name:      req.Name,
+		Scopes:    slices.Compact(slices.Sorted(slices.Values(req.Scopes))),
+		CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+	}
+	secretStr := base64.RawURLEncoding.EncodeToString(secret)
+	key.Hash = hashAPIKeySecret(secretStr)
+	if err := a.apiKeys.CreateAPIKey(ctx, key); err != nil {
+		return models.ResponseCreateAPIKey{}, err
+	}
+	return models.ResponseCreateAPIKey{
+		ResponseAPIKey: toResponseAPIKey(key),
+		Key:            strings.Join([]string{apiKeyPrefix, key.ID, secretStr}, "_"),
+	}, nil
+}
+
+// GetUserAPIKeys return api keys of user without secrets
+func (a *MyApp) GetUserAPIKeys(ctx context.Context, userID auth.UserID) ([]models.ResponseAPIKey, error) {
+	if a.apiKeys == nil {
+		return nil, storage.ErrNotSupported
+	}
+	keys, err := a.apiKeys.GetUserAPIKeys(ctx, string(userID))
+	if err != nil {
+		return nil, err
+	}
+	result := make([]models.ResponseAPIKey, len(keys))
+	for i := range keys {
+		result[i] = toResponseAPIKey(keys[i])
+	}
+	return result, nil
+}
+
+// RevokeAPIKey revoke api key of user. Return storage.ErrNotFound for unknown key
+func (a *MyApp) RevokeAPIKey(ctx context.Context, userID auth.UserID, id string) error {
+	if a.apiKeys == nil {
+		return storage.ErrNotSupported
+	}
+	return a.apiKeys.RevokeAPIKey(ctx, string(userID), id, time.Now().UTC().Truncate(time.Microsecond))
+}
+
+// VerifyAPIKey check api key and return its owner and scopes. Implements auth.APIKeyVerifier
+func (a *MyApp) VerifyAPIKey(ctx context.Context, value string) (auth.UserID, []string, error) {
+	if a.apiKeys == nil {
+		return "", nil, auth.ErrNoAPIKeys
+	}
+	// секрет в base64url может содержать "_", поэтому делим не больше чем на 3 части
+	items := strings.SplitN(value, "_", 3)
+	if len(items) != 3 || items[0] != apiKeyPrefix {
+		return "", nil, auth.ErrBadAPIKey
+	}
+	key, ok, err := a.apiKeys.GetAPIKey(ctx, items[1])
+	if err != nil {
+		return "", nil, err
+	}
+	hash := hashAPIKeySecret(items[2])
+	if !ok || key.Revoked() || subtle.ConstantTimeCompare([]byte(hash), []byte(key.Hash)) != 1 {
+		return "", nil, auth.ErrBadAPIKey
+	}
+	a.touchAPIKey(ctx, key.ID)
+	return auth.UserID(key.UserID), key.Scopes, nil
+}
+
+// touchAPIKey save last usage of api key. Storage is updated not often than apiKeyTouchInterval
+func (a *MyApp) touchAPIKey(ctx context.Context, id string) {
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	if last, ok := a.apiKeyTouched.Load(id); ok && now.Sub(last.(time.Time)) < apiKeyTouchInterval {
+		return
+	}
+	a.apiKeyTouched.Store(id, now)
+	if err := a.apiKeys.TouchAPIKey(ctx, id, now); err != nil {
+		logger.Component(ctx, componentApp).Error("touch api key", zap.Error(err), zap.String("id", id))
+	}
+}
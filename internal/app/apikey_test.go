@@ -0,0 +1,77 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/serg2014/shortener/internal/auth"
+	"github.com/serg2014/shortener/internal/models"
+	"github.com/serg2014/shortener/storage"
+)
+
+func TestAPIKey(t *testing.T) {
+	store, err := storage.NewStorageMemory()
+	require.NoError(t, err)
+	a := NewApp(store, nil)
+
+	resp, err := a.CreateAPIKey(t.Context(), "user1", models.RequestAPIKey{
+		Name:   "backend",
+		Scopes: []string{auth.ScopeRead, auth.ScopeCreate, auth.ScopeRead},
+	})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(resp.Key, "sk_"+resp.ID+"_"))
+	assert.Equal(t, []string{auth.ScopeCreate, auth.ScopeRead}, resp.Scopes)
+
+	// секрет не хранится
+	saved, ok, err := store.(storage.APIKeyStorage).GetAPIKey(t.Context(), resp.ID)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.NotContains(t, resp.Key, saved.Hash)
+
+	userID, scopes, err := a.VerifyAPIKey(t.Context(), resp.Key)
+	require.NoError(t, err)
+	assert.Equal(t, auth.UserID("user1"), userID)
+	assert.Equal(t, []string{auth.ScopeCreate, auth.ScopeRead}, scopes)
+
+	keys, err := a.GetUserAPIKeys(t.Context(), "user1")
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.False(t, keys[0].LastUsedAt.IsZero())
+
+	for _, bad := range []string{"", "sk_" + resp.ID, "sk_" + resp.ID + "_bad", "xx_" + resp.ID + resp.Key[len("sk_"+resp.ID):]} {
+		_, _, err = a.VerifyAPIKey(t.Context(), bad)
+		assert.ErrorIs(t, err, auth.ErrBadAPIKey, bad)
+	}
+
+	// чужой ключ не отозвать
+	assert.ErrorIs(t, a.RevokeAPIKey(t.Context(), "user2", resp.ID), storage.ErrNotFound)
+	require.NoError(t, a.RevokeAPIKey(t.Context(), "user1", resp.ID))
+	_, _, err = a.VerifyAPIKey(t.Context(), resp.Key)
+	assert.ErrorIs(t, err, auth.ErrBadAPIKey)
+}
+
+func TestCreateAPIKey_badRequest(t *testing.T) {
+	store, err := storage.NewStorageMemory()
+	require.NoError(t, err)
+	a := NewApp(store, nil)
+
+	tests := []struct {
+		name string
+		req  models.RequestAPIKey
+	}{
+		{name: "no name", req: models.RequestAPIKey{Scopes: []string{auth.ScopeRead}}},
+		{name: "long name", req: models.RequestAPIKey{Name: strings.Repeat("a", 101), Scopes: []string{auth.ScopeRead}}},
+		{name: "no scopes", req: models.RequestAPIKey{Name: "backend"}},
+		{name: "unknown scope", req: models.RequestAPIKey{Name: "backend", Scopes: []string{"admin"}}},
+		{name: "user scope", req: models.RequestAPIKey{Name: "backend", Scopes: []string{auth.ScopeUser}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := a.CreateAPIKey(t.Context(), "user1", test.req)
+			assert.ErrorIs(t, err, ErrBadAPIKeyRequest)
+		})
+	}
+}
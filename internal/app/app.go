@@ -4,45 +4,111 @@ import (
 	"context"
 	"errors"
 	"fmt"
-
-	"go.uber.org/zap"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/serg2014/shortener/internal/auth"
 	"github.com/serg2014/shortener/internal/config"
 	"github.com/serg2014/shortener/internal/logger"
+	"github.com/serg2014/shortener/internal/metrics"
 	"github.com/serg2014/shortener/internal/models"
-	"github.com/serg2014/shortener/internal/storage"
+	"github.com/serg2014/shortener/storage"
+	"go.uber.org/zap"
 )
 
+// componentApp name of component in logs of app
+const componentApp = "app"
+
+// maxKeyAttempts max count of generated keys for one url
+const maxKeyAttempts = 3
+
 // MyApp type for application
 type MyApp struct {
 	store storage.Storager
+	// optional interfaces of store, nil if store does not implement them
+	apiKeys    storage.APIKeyStorage
+	accounts   storage.AccountStorage
+	moderation storage.ModerationStorage
+	hitsStore  storage.HitsStorage
 	// канал для отложенной отправки новых сообщений
 	msgChan chan storage.Message
 	gen     Generator
+	// deleteWorkers count of goroutines which run DeleteUserURLsBackground
+	deleteWorkers int
+	// deleteBatchSize max count of urls in one batch for delete
+	deleteBatchSize int
+	// deleteFlushInterval max time while message waits in the batch
+	deleteFlushInterval time.Duration
+	// apiKeyTouched time of last saved usage of api key by id
+	apiKeyTouched sync.Map
+	// admins logins of accounts with role admin
+	admins []string
+	// hits counts of redirects not saved into storage yet
+	hits   map[string]uint64
+	hitsMu sync.Mutex
+	// shuttingDown app is stopping, it is not ready for new requests
+	shuttingDown atomic.Bool
 }
 
-// NewApp constructor of *MyApp
+// NewApp constructor of *MyApp. Settings of delete are taken from config.Config
 func NewApp(store storage.Storager, gen Generator) *MyApp {
+	return newApp(store, gen, deleteOptions{
+		workers:       config.Config.DeleteWorkers,
+		queueSize:     config.Config.DeleteQueueSize,
+		batchSize:     config.Config.DeleteBatchSize,
+		flushInterval: config.Config.DeleteFlushInterval.Duration(),
+	})
+}
+
+// deleteOptions settings of background delete. Zero values - default values
+type deleteOptions struct {
+	workers       int
+	queueSize     int
+	batchSize     int
+	flushInterval time.Duration
+}
+
+// newApp constructor of *MyApp with explicit settings of delete
+func newApp(store storage.Storager, gen Generator, opts deleteOptions) *MyApp {
 	if gen == nil {
 		gen = &Generate{}
 	}
+	if opts.workers <= 0 {
+		opts.workers = defaultDeleteWorkers
+	}
+	if opts.queueSize <= 0 {
+		opts.queueSize = defaultDeleteQueueSize
+	}
+	if opts.batchSize <= 0 {
+		opts.batchSize = defaultDeleteBatchSize
+	}
+	if opts.flushInterval <= 0 {
+		opts.flushInterval = defaultDeleteFlushInterval
+	}
 	app := &MyApp{
-		store:   store,
-		msgChan: make(chan storage.Message, 1024),
-		gen:     gen,
+		store:               store,
+		msgChan:             make(chan storage.Message, opts.queueSize),
+		gen:                 gen,
+		deleteWorkers:       opts.workers,
+		deleteBatchSize:     opts.batchSize,
+		deleteFlushInterval: opts.flushInterval,
+		admins:              config.Config.AdminLogins(),
+		hits:                make(map[string]uint64),
 	}
+	app.apiKeys, _ = storage.Find[storage.APIKeyStorage](store)
+	app.accounts, _ = storage.Find[storage.AccountStorage](store)
+	app.moderation, _ = storage.Find[storage.ModerationStorage](store)
+	app.hitsStore, _ = storage.Find[storage.HitsStorage](store)
 	return app
 }
 
 // GenerateShortURL create short url
 func (a *MyApp) GenerateShortURL(ctx context.Context, origURL string, userID auth.UserID) (string, error) {
-	shortURL, err := a.gen.GenerateShortKey()
-	if err != nil {
-		return "", fmt.Errorf("GenerateShortKey: %w", err)
+	if err := a.checkBanned(ctx, userID); err != nil {
+		return "", err
 	}
-
-	err = a.store.Set(ctx, shortURL, origURL, string(userID))
+	shortURL, err := a.setNewKey(ctx, origURL, userID)
 	if err != nil {
 		if !errors.Is(err, storage.ErrConflict) {
 			return "", err
@@ -52,7 +118,7 @@ func (a *MyApp) GenerateShortURL(ctx context.Context, origURL string, userID aut
 			return "", err
 		}
 		if !ok {
-			return "", fmt.Errorf("can not find origurl %s", origURL)
+			return "", fmt.Errorf("can not find origurl %s", logger.RedactURL(origURL))
 		}
 		return URLTemplate(shortURL), storage.ErrConflict
 	}
@@ -60,6 +126,26 @@ func (a *MyApp) GenerateShortURL(ctx context.Context, origURL string, userID aut
 	return URLTemplate(shortURL), nil
 }
 
+// setNewKey save origURL with generated short key. On collision of keys new key is generated,
+// after maxKeyAttempts collisions storage.ErrKeyExists is returned
+func (a *MyApp) setNewKey(ctx context.Context, origURL string, userID auth.UserID) (string, error) {
+	var err error
+	for range maxKeyAttempts {
+		var shortURL string
+		shortURL, err = a.gen.GenerateShortKey()
+		if err != nil {
+			return "", fmt.Errorf("GenerateShortKey: %w", err)
+		}
+		err = a.store.Set(ctx, shortURL, origURL, string(userID))
+		if !errors.Is(err, storage.ErrKeyExists) {
+			return shortURL, err
+		}
+		metrics.KeyCollisions.Inc()
+		logger.Component(ctx, componentApp).Debug("collision of short key", zap.String("short_url", shortURL))
+	}
+	return "", err
+}
+
 // URLTemplate return url for getting orig url by short
 func URLTemplate(id string) string {
 	return fmt.Sprintf("%s%s", config.Config.URL(), id)
@@ -67,6 +153,9 @@ func URLTemplate(id string) string {
 
 // GenerateShortURLBatch save records in storage
 func (a *MyApp) GenerateShortURLBatch(ctx context.Context, req models.RequestBatch, userID auth.UserID) (models.ResponseBatch, error) {
+	if err := a.checkBanned(ctx, userID); err != nil {
+		return nil, err
+	}
 	resp := make(models.ResponseBatch, len(req))
 	short2orig := make(map[string]string, len(req))
 	for i := range req {
@@ -97,35 +186,13 @@ func (a *MyApp) GetUserURLS(ctx context.Context, userID auth.UserID) (models.Res
 	return resp, nil
 }
 
-// DeleteUserURLS send records for delete into chan
-func (a *MyApp) DeleteUserURLS(ctx context.Context, req models.RequestForDeleteURLS, userID auth.UserID) error {
-	// TODO uniq input
-	// TODO req может быть большим, можно побить на чанки
-	a.msgChan <- storage.Message{
-		UserID:   string(userID),
-		ShortURL: req,
-	}
-	return nil
-}
-
-// DeleteUserURLsBackground set delete flag for record in the backgroud. Data for delete get from chan
-func (a *MyApp) DeleteUserURLsBackground(ctx context.Context) {
-	for {
-		select {
-		case mes := <-a.msgChan:
-			err := a.store.DeleteUserURLS(ctx, mes.ShortURL, mes.UserID)
-			if err != nil {
-				logger.Log.Error("problem with DeleteUserURLS", zap.Error(err))
-			}
-		case <-ctx.Done():
-			return
-		}
-	}
-}
-
-// Get get record from storage
+// Get get record from storage. Found records are counted as redirects
 func (a *MyApp) Get(ctx context.Context, id string) (string, bool, error) {
-	return a.store.Get(ctx, id)
+	value, ok, err := a.store.Get(ctx, id)
+	if err == nil && ok {
+		a.countHit(id)
+	}
+	return value, ok, err
 }
 
 // Set save record in storage
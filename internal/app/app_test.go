@@ -0,0 +1,40 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	appmock "github.com/serg2014/shortener/internal/app/mock"
+	"github.com/serg2014/shortener/internal/metrics"
+	"github.com/serg2014/shortener/storage"
+)
+
+func TestGenerateShortURL_keyCollision(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	gen := appmock.NewMockGenerator(ctrl)
+	store, err := storage.NewStorageMemory()
+	require.NoError(t, err)
+	require.NoError(t, store.Set(t.Context(), "a1234567", "http://one.ru", "user1"))
+	a := NewApp(store, gen)
+
+	// первый ключ занят, берется следующий
+	gomock.InOrder(
+		gen.EXPECT().GenerateShortKey().Return("a1234567", nil),
+		gen.EXPECT().GenerateShortKey().Return("b1234567", nil),
+	)
+	before := testutil.ToFloat64(metrics.KeyCollisions)
+	short, err := a.GenerateShortURL(t.Context(), "http://two.ru", "user1")
+	require.NoError(t, err)
+	assert.Equal(t, URLTemplate("b1234567"), short)
+	assert.Equal(t, before+1, testutil.ToFloat64(metrics.KeyCollisions))
+
+	// все попытки заняты
+	gen.EXPECT().GenerateShortKey().Return("a1234567", nil).Times(maxKeyAttempts)
+	_, err = a.GenerateShortURL(t.Context(), "http://three.ru", "user1")
+	assert.ErrorIs(t, err, storage.ErrKeyExists)
+	assert.Equal(t, before+1+maxKeyAttempts, testutil.ToFloat64(metrics.KeyCollisions))
+}
@@ -0,0 +1,204 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+
+	"github.com/serg2014/shortener/internal/auth"
+	"github.com/serg2014/shortener/internal/logger"
+	"github.com/serg2014/shortener/internal/metrics"
+	"github.com/serg2014/shortener/internal/models"
+	"github.com/serg2014/shortener/internal/tracing"
+	"github.com/serg2014/shortener/storage"
+)
+
+const (
+	// defaultDeleteWorkers кол-во горутин обрабатывающих удаление урлов.
+	defaultDeleteWorkers = 10
+	// defaultDeleteQueueSize емкость очереди запросов на удаление
+	defaultDeleteQueueSize = 1024
+	// defaultDeleteBatchSize max count of urls in one batch
+	defaultDeleteBatchSize = 100
+	// defaultDeleteFlushInterval max time while url waits in the batch
+	defaultDeleteFlushInterval = time.Second
+	// deleteShutdownTimeout time for deleting the last batch after ctx is done
+	deleteShutdownTimeout = time.Second
+)
+
+// ErrDeleteQueueFull use this error when delete queue is full. Client should retry later
+var ErrDeleteQueueFull = errors.New("delete queue is full")
+
+// ErrBadDeleteRequest error for short urls which can not be generated by the app
+var ErrBadDeleteRequest = errors.New("bad delete request")
+
+// DeleteWorkers return count of goroutines which should run DeleteUserURLsBackground
+func (a *MyApp) DeleteWorkers() int {
+	return a.deleteWorkers
+}
+
+// deleteBatch accumulate urls for delete grouped by user
+type deleteBatch struct {
+	users map[string]map[string]struct{}
+	size  int
+}
+
+func newDeleteBatch() *deleteBatch {
+	return &deleteBatch{users: make(map[string]map[string]struct{})}
+}
+
+// add append urls of message into batch until it has limit urls. Duplicate urls are skipped.
+// Return urls which do not fit into batch
+func (b *deleteBatch) add(mes storage.Message, limit int) []string {
+	keys, ok := b.users[mes.UserID]
+	if !ok {
+		keys = make(map[string]struct{}, min(len(mes.ShortURL), limit))
+		b.users[mes.UserID] = keys
+	}
+	for i, key := range mes.ShortURL {
+		if b.size >= limit {
+			return mes.ShortURL[i:]
+		}
+		if _, ok := keys[key]; ok {
+			continue
+		}
+		keys[key] = struct{}{}
+		b.size++
+	}
+	return nil
+}
+
+// messages return one message per user
+func (b *deleteBatch) messages() []storage.Message {
+	result := make([]storage.Message, 0, len(b.users))
+	for userID, keys := range b.users {
+		mes := storage.Message{
+			UserID:   userID,
+			ShortURL: make([]string, 0, len(keys)),
+		}
+		for key := range keys {
+			mes.ShortURL = append(mes.ShortURL, key)
+		}
+		result = append(result, mes)
+	}
+	return result
+}
+
+func (b *deleteBatch) reset() {
+	clear(b.users)
+	b.size = 0
+}
+
+// DeleteUserURLS send records for delete into chan.
+// Does not block: return ErrDeleteQueueFull if queue is full
+func (a *MyApp) DeleteUserURLS(ctx context.Context, req models.RequestForDeleteURLS, userID auth.UserID) error {
+	if len(req) == 0 {
+		return nil
+	}
+	// ключи другой длины не могут существовать, в очередь их не берем
+	for _, key := range req {
+		if len(key) != storage.KeyLength {
+			return fmt.Errorf("%w: short url %q must have %d chars", ErrBadDeleteRequest, key, storage.KeyLength)
+		}
+	}
+	select {
+	case a.msgChan <- storage.Message{
+		UserID:   string(userID),
+		ShortURL: req,
+	}:
+		metrics.DeleteQueueDepth.Set(float64(len(a.msgChan)))
+		return nil
+	default:
+		metrics.DeleteQueueRejected.Inc()
+		return ErrDeleteQueueFull
+	}
+}
+
+// DeleteRetryAfter return time after which client can retry request rejected with ErrDeleteQueueFull
+func (a *MyApp) DeleteRetryAfter() time.Duration {
+	return a.deleteFlushInterval
+}
+
+// DeleteUserURLsBackground set delete flag for record in the backgroud. Data for delete get from chan.
+// Messages of different users are coalesced in the batch, which is sent to storage
+// when it has deleteBatchSize urls or every deleteFlushInterval
+func (a *MyApp) DeleteUserURLsBackground(ctx context.Context) {
+	batch := newDeleteBatch()
+	ticker := time.NewTicker(a.deleteFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case mes := <-a.msgChan:
+			metrics.DeleteQueueDepth.Set(float64(len(a.msgChan)))
+			// большое сообщение делится, чтобы батч не превышал deleteBatchSize
+			for {
+				mes.ShortURL = batch.add(mes, a.deleteBatchSize)
+				if batch.size >= a.deleteBatchSize {
+					a.flushDeletes(ctx, batch)
+				}
+				if len(mes.ShortURL) == 0 {
+					break
+				}
+			}
+		case <-ticker.C:
+			a.flushDeletes(ctx, batch)
+		case <-ctx.Done():
+			// контекст уже отменен, поэтому для последнего батча нужен свой
+			ctxT, cancel := context.WithTimeout(context.Background(), deleteShutdownTimeout)
+			a.flushDeletes(ctxT, batch)
+			cancel()
+			return
+		}
+	}
+}
+
+// flushDeletes send batch into storage and reset it
+func (a *MyApp) flushDeletes(ctx context.Context, batch *deleteBatch) {
+	if batch.size == 0 {
+		return
+	}
+	// батч удаления - корневой span, запросы пользователей к этому времени уже завершены
+	ctx, span := tracing.Tracer().Start(ctx, "delete batch", trace.WithAttributes(
+		attribute.Int("delete.urls", batch.size),
+		attribute.Int("delete.users", len(batch.users)),
+	))
+	messages := batch.messages()
+	err := a.store.DeleteUserURLS(ctx, messages)
+	failed := 0
+	if err != nil {
+		failed = batch.size
+		if len(messages) > 1 {
+			// ошибка общего батча не должна терять удаления остальных пользователей
+			logger.Component(ctx, componentApp).Warn("batch delete failed, delete per user", zap.Error(err), zap.Int("urls", batch.size))
+			failed, err = a.deletePerUser(ctx, messages)
+		}
+	}
+	tracing.End(span, err)
+	if err != nil {
+		logger.Component(ctx, componentApp).Error("problem with DeleteUserURLS", zap.Error(err), zap.Int("urls", failed))
+	}
+	metrics.DeletedURLs.WithLabelValues(metrics.Result(nil)).Add(float64(batch.size - failed))
+	metrics.DeletedURLs.WithLabelValues(metrics.Result(err)).Add(float64(failed))
+	batch.reset()
+}
+
+// deletePerUser send every message into storage by own call. Return count of not deleted urls
+func (a *MyApp) deletePerUser(ctx context.Context, messages []storage.Message) (int, error) {
+	failed := 0
+	var errs []error
+	for _, mes := range messages {
+		if err := a.store.DeleteUserURLS(ctx, []storage.Message{mes}); err != nil {
+			failed += len(mes.ShortURL)
+			errs = append(errs, err)
+			logger.Component(ctx, componentApp).Error("problem with DeleteUserURLS of user", zap.Error(err),
+				logger.UserIDField("user_id", mes.UserID), zap.Int("urls", len(mes.ShortURL)))
+		}
+	}
+	return failed, errors.Join(errs...)
+}
@@ -0,0 +1,139 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/serg2014/shortener/storage"
+	"github.com/serg2014/shortener/storage/mock"
+)
+
+func TestDeleteUserURLS_queueFull(t *testing.T) {
+	a := newApp(nil, nil, deleteOptions{queueSize: 1})
+	err := a.DeleteUserURLS(t.Context(), []string{"a1234567"}, "user1")
+	require.NoError(t, err)
+	err = a.DeleteUserURLS(t.Context(), []string{"a1234568"}, "user1")
+	assert.ErrorIs(t, err, ErrDeleteQueueFull)
+	// пустой запрос в очередь не попадает
+	err = a.DeleteUserURLS(t.Context(), nil, "user1")
+	assert.NoError(t, err)
+}
+
+func TestDeleteUserURLS_badKey(t *testing.T) {
+	a := newApp(nil, nil, deleteOptions{queueSize: 1})
+	for _, key := range []string{"a123456", "a12345678", ""} {
+		err := a.DeleteUserURLS(t.Context(), []string{"a1234567", key}, "user1")
+		assert.ErrorIs(t, err, ErrBadDeleteRequest, key)
+	}
+	// запрос с плохим ключом не занимает место в очереди
+	assert.NoError(t, a.DeleteUserURLS(t.Context(), []string{"a1234567"}, "user1"))
+}
+
+func TestDeleteUserURLsBackground_coalesce(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mock.NewMockStorager(ctrl)
+	a := newApp(store, nil, deleteOptions{batchSize: 4, flushInterval: time.Hour})
+
+	var got []storage.Message
+	store.EXPECT().DeleteUserURLS(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, batch []storage.Message) error {
+			got = batch
+			return nil
+		})
+
+	require.NoError(t, a.DeleteUserURLS(t.Context(), []string{"a0000001", "a0000002"}, "user1"))
+	require.NoError(t, a.DeleteUserURLS(t.Context(), []string{"a0000001"}, "user1"))
+	require.NoError(t, a.DeleteUserURLS(t.Context(), []string{"a0000002", "b0000001"}, "user2"))
+
+	ctx, cancel := context.WithCancel(t.Context())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		a.DeleteUserURLsBackground(ctx)
+	}()
+	require.Eventually(t, func() bool { return len(a.msgChan) == 0 }, time.Second, 10*time.Millisecond)
+	cancel()
+	wg.Wait()
+
+	// 4 уникальных урла: батч отправлен одним вызовом
+	require.Len(t, got, 2)
+	byUser := make(map[string][]string)
+	for _, mes := range got {
+		byUser[mes.UserID] = mes.ShortURL
+	}
+	assert.ElementsMatch(t, []string{"a0000001", "a0000002"}, byUser["user1"])
+	assert.ElementsMatch(t, []string{"a0000002", "b0000001"}, byUser["user2"])
+}
+
+// runDeletes run DeleteUserURLsBackground until queue is empty
+func runDeletes(t *testing.T, a *MyApp) {
+	ctx, cancel := context.WithCancel(t.Context())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		a.DeleteUserURLsBackground(ctx)
+	}()
+	require.Eventually(t, func() bool { return len(a.msgChan) == 0 }, time.Second, 10*time.Millisecond)
+	cancel()
+	wg.Wait()
+}
+
+func TestDeleteUserURLsBackground_split(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mock.NewMockStorager(ctrl)
+	a := newApp(store, nil, deleteOptions{batchSize: 2, flushInterval: time.Hour})
+
+	var sizes []int
+	store.EXPECT().DeleteUserURLS(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, batch []storage.Message) error {
+			require.Len(t, batch, 1)
+			sizes = append(sizes, len(batch[0].ShortURL))
+			return nil
+		}).Times(3)
+
+	require.NoError(t, a.DeleteUserURLS(t.Context(), []string{"a0000001", "a0000002", "a0000003", "a0000004", "a0000005"}, "user1"))
+	runDeletes(t, a)
+
+	// сообщение больше батча делится, батч не превышает deleteBatchSize
+	assert.Equal(t, []int{2, 2, 1}, sizes)
+}
+
+func TestDeleteUserURLsBackground_perUserFallback(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mock.NewMockStorager(ctrl)
+	a := newApp(store, nil, deleteOptions{batchSize: 4, flushInterval: time.Hour})
+
+	var deleted []string
+	store.EXPECT().DeleteUserURLS(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, batch []storage.Message) error {
+			if len(batch) > 1 {
+				return errors.New("batch failed")
+			}
+			if batch[0].UserID == "user2" {
+				return errors.New("user failed")
+			}
+			deleted = append(deleted, batch[0].UserID)
+			return nil
+		}).Times(3)
+
+	require.NoError(t, a.DeleteUserURLS(t.Context(), []string{"a0000001", "a0000002"}, "user1"))
+	require.NoError(t, a.DeleteUserURLS(t.Context(), []string{"b0000001", "b0000002"}, "user2"))
+	runDeletes(t, a)
+
+	// ошибка удаления user2 не теряет удаления user1
+	assert.Equal(t, []string{"user1"}, deleted)
+}
+
+func TestDeleteWorkers(t *testing.T) {
+	assert.Equal(t, defaultDeleteWorkers, newApp(nil, nil, deleteOptions{}).DeleteWorkers())
+	assert.Equal(t, 3, newApp(nil, nil, deleteOptions{workers: 3}).DeleteWorkers())
+}
@@ -5,7 +5,7 @@ import (
 
 	"github.com/serg2014/shortener/internal/app"
 	"github.com/serg2014/shortener/internal/config"
-	"github.com/serg2014/shortener/internal/storage"
+	"github.com/serg2014/shortener/storage"
 )
 
 func Example() {
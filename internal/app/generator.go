@@ -5,7 +5,7 @@ import (
 	"crypto/rand"
 	"math/big"
 
-	"github.com/serg2014/shortener/internal/storage"
+	"github.com/serg2014/shortener/storage"
 )
 
 // Generate type
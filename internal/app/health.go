@@ -0,0 +1,39 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// deleteQueueSaturation percent of filled delete queue after which app is not ready
+const deleteQueueSaturation = 90
+
+// ErrShuttingDown app is stopping and does not accept new requests
+var ErrShuttingDown = errors.New("app is shutting down")
+
+// ErrDeleteQueueSaturated delete queue is almost full
+var ErrDeleteQueueSaturated = errors.New("delete queue is saturated")
+
+// SetShuttingDown mark app as stopping. After it Ready returns ErrShuttingDown
+func (a *MyApp) SetShuttingDown() {
+	a.shuttingDown.Store(true)
+}
+
+// Ready check that app can serve requests: it is not stopping, storage is reachable,
+// migrations are applied and delete queue is not saturated
+func (a *MyApp) Ready(ctx context.Context) error {
+	if a.shuttingDown.Load() {
+		return ErrShuttingDown
+	}
+	if err := a.store.Ping(ctx); err != nil {
+		return err
+	}
+	if err := a.store.CheckMigrations(ctx); err != nil {
+		return err
+	}
+	if len(a.msgChan)*100 >= deleteQueueSaturation*cap(a.msgChan) {
+		return fmt.Errorf("%w: %d of %d", ErrDeleteQueueSaturated, len(a.msgChan), cap(a.msgChan))
+	}
+	return nil
+}
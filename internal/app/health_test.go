@@ -0,0 +1,42 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/serg2014/shortener/storage"
+	"github.com/serg2014/shortener/storage/mock"
+)
+
+func TestReady(t *testing.T) {
+	store, err := storage.NewStorageMemory()
+	require.NoError(t, err)
+	a := NewApp(store, nil)
+	require.NoError(t, a.Ready(t.Context()))
+
+	// очередь удаления почти заполнена
+	for range (cap(a.msgChan)*deleteQueueSaturation + 99) / 100 {
+		a.msgChan <- storage.Message{}
+	}
+	assert.ErrorIs(t, a.Ready(t.Context()), ErrDeleteQueueSaturated)
+	for len(a.msgChan) > 0 {
+		<-a.msgChan
+	}
+
+	a.SetShuttingDown()
+	assert.ErrorIs(t, a.Ready(t.Context()), ErrShuttingDown)
+}
+
+func TestReady_migrations(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store := mock.NewMockStorager(ctrl)
+	store.EXPECT().Ping(gomock.Any()).Return(nil)
+	store.EXPECT().CheckMigrations(gomock.Any()).Return(storage.ErrMigrations)
+
+	a := NewApp(store, nil)
+	assert.ErrorIs(t, a.Ready(t.Context()), storage.ErrMigrations)
+}
@@ -0,0 +1,53 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/serg2014/shortener/internal/logger"
+)
+
+// hitsFlushInterval counts of redirects are saved into storage with this interval
+const hitsFlushInterval = 10 * time.Second
+
+// countHit count redirect by short url. Counts are saved by FlushHits.
+// Storage without HitsStorage does not count redirects
+func (a *MyApp) countHit(key string) {
+	if a.hitsStore == nil {
+		return
+	}
+	a.hitsMu.Lock()
+	defer a.hitsMu.Unlock()
+	a.hits[key]++
+}
+
+// FlushHits save counted redirects into storage
+func (a *MyApp) FlushHits(ctx context.Context) {
+	a.hitsMu.Lock()
+	hits := a.hits
+	a.hits = make(map[string]uint64)
+	a.hitsMu.Unlock()
+	if len(hits) == 0 {
+		return
+	}
+	if err := a.hitsStore.AddHits(ctx, hits); err != nil {
+		logger.Component(ctx, componentApp).Error("save hits", zap.Error(err))
+	}
+}
+
+// FlushHitsBackground save counted redirects until ctx is done. Last counts are saved on exit
+func (a *MyApp) FlushHitsBackground(ctx context.Context) {
+	ticker := time.NewTicker(hitsFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			a.FlushHits(context.WithoutCancel(ctx))
+			return
+		case <-ticker.C:
+			a.FlushHits(ctx)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Scopes of api keys
+const (
+	// ScopeCreate create short urls
+	ScopeCreate = "create"
+	// ScopeRead read urls of user
+	ScopeRead = "read"
+	// ScopeDelete delete urls of user
+	ScopeDelete = "delete"
+	// ScopeStats read internal stats
+	ScopeStats = "stats"
+	// ScopeUser is never granted to api keys. Use it for actions of user himself,
+	// like management of api keys
+	ScopeUser = "user"
+)
+
+// APIKeyScopes scopes which can be granted to api key
+var APIKeyScopes = []string{ScopeCreate, ScopeRead, ScopeDelete, ScopeStats}
+
+// HeaderAPIKey name of header with api key
+const HeaderAPIKey = "X-API-Key"
+
+// MetaAPIKey name of meta grpc with api key
+const MetaAPIKey = "x-api-key"
+
+// ErrBadAPIKey error for unknown, revoked or malformed api key
+var ErrBadAPIKey = errors.New("bad api key")
+
+// ErrNoAPIKeys error when api keys are not enabled
+var ErrNoAPIKeys = errors.New("api keys are not supported")
+
+// APIKeyVerifier check api key and return its owner and scopes
+type APIKeyVerifier interface {
+	VerifyAPIKey(ctx context.Context, key string) (UserID, []string, error)
+}
+
+var apiKeyVerifier APIKeyVerifier
+
+// SetAPIKeyVerifier set verifier of api keys. Without it api keys are rejected.
+// Call it before serving requests
+func SetAPIKeyVerifier(v APIKeyVerifier) {
+	apiKeyVerifier = v
+}
+
+// ValidScope scope can be granted to api key
+func ValidScope(scope string) bool {
+	return slices.Contains(APIKeyScopes, scope)
+}
+
+func verifyAPIKey(ctx context.Context, key string) (UserID, []string, error) {
+	if apiKeyVerifier == nil {
+		return "", nil, ErrNoAPIKeys
+	}
+	return apiKeyVerifier.VerifyAPIKey(ctx, key)
+}
+
+type scopesCtxKeyType string
+
+const scopesCtxKey scopesCtxKeyType = "scopes"
+
+// withScopes save scopes of api key in context
+func withScopes(ctx context.Context, scopes []string) context.Context {
+	return context.WithValue(ctx, scopesCtxKey, scopes)
+}
+
+// IsAPIKey request is authenticated by api key
+func IsAPIKey(ctx context.Context) bool {
+	_, ok := ctx.Value(scopesCtxKey).([]string)
+	return ok
+}
+
+// HasScope request may do action of scope.
+// User authenticated by token or cookie has all scopes, api key has only granted ones
+func HasScope(ctx context.Context, scope string) bool {
+	scopes, ok := ctx.Value(scopesCtxKey).([]string)
+	if !ok {
+		return true
+	}
+	return slices.Contains(scopes, scope)
+}
+
+// RequireScope middleware reject request without scope with 403
+func RequireScope(scope string) func(h http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !HasScope(r.Context(), scope) {
+				code := http.StatusForbidden
+				http.Error(w, http.StatusText(code), code)
+				return
+			}
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
+// APIKeyMiddleware authenticate request by header X-API-Key if it is set. Bad key gets 401.
+// Request without header is passed as is
+func APIKeyMiddleware(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(HeaderAPIKey)
+		if key == "" {
+			h.ServeHTTP(w, r)
+			return
+		}
+		userID, scopes, err := verifyAPIKey(r.Context(), key)
+		if err != nil {
+			http.Error(w, ErrBadAPIKey.Error(), http.StatusUnauthorized)
+			return
+		}
+		r = r.WithContext(withScopes(r.Context(), scopes))
+		serveWithUser(h, w, r, userID)
+	})
+}
+
+// apiKeyFromMeta authenticate request by meta x-api-key. ok is false without meta
+func apiKeyFromMeta(ctx context.Context) (context.Context, bool, error) {
+	key := metaValue(ctx, MetaAPIKey)
+	if key == "" {
+		return ctx, false, nil
+	}
+	userID, scopes, err := verifyAPIKey(ctx, key)
+	if err != nil {
+		return ctx, true, status.Error(codes.Unauthenticated, ErrBadAPIKey.Error())
+	}
+	return WithUser(withScopes(ctx, scopes), &userID), true, nil
+}
+
+// ScopeInterceptor reject call of method without scope from methodScopes with PermissionDenied.
+// Methods not in methodScopes are allowed
+func ScopeInterceptor(methodScopes map[string]string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if scope, ok := methodScopes[info.FullMethod]; ok && !HasScope(ctx, scope) {
+			code := codes.PermissionDenied
+			return nil, status.Error(code, code.String())
+		}
+		return handler(ctx, req)
+	}
+}
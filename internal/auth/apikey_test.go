@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// fakeVerifier knows one key "good" of user1 with scope read
+type fakeVerifier struct{}
+
+func (fakeVerifier) VerifyAPIKey(ctx context.Context, key string) (UserID, []string, error) {
+	if key != "good" {
+		return "", nil, ErrBadAPIKey
+	}
+	return "user1", []string{ScopeRead}, nil
+}
+
+func TestAuthMiddleware_apiKey(t *testing.T) {
+	SetAPIKeyVerifier(fakeVerifier{})
+	defer SetAPIKeyVerifier(nil)
+
+	tests := []struct {
+		name       string
+		key        string
+		scope      string
+		statusCode int
+	}{
+		{name: "granted scope", key: "good", scope: ScopeRead, statusCode: http.StatusOK},
+		{name: "not granted scope", key: "good", scope: ScopeCreate, statusCode: http.StatusForbidden},
+		{name: "user scope", key: "good", scope: ScopeUser, statusCode: http.StatusForbidden},
+		{name: "bad key", key: "bad", scope: ScopeRead, statusCode: http.StatusUnauthorized},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handlerToTest := AuthMiddleware(RequireScope(test.scope)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				userID, err := GetUserID(r.Context())
+				require.NoError(t, err)
+				assert.Equal(t, UserID("user1"), userID)
+				assert.True(t, IsAPIKey(r.Context()))
+			})))
+			req := httptest.NewRequest("GET", "http://localhost/", nil)
+			req.Header.Set(HeaderAPIKey, test.key)
+			w := httptest.NewRecorder()
+			handlerToTest.ServeHTTP(w, req)
+			res := w.Result()
+			defer res.Body.Close()
+			assert.Equal(t, test.statusCode, res.StatusCode)
+			assert.Empty(t, res.Cookies())
+		})
+	}
+}
+
+func TestRequireScope_user(t *testing.T) {
+	// пользователю с кукой доступны все действия
+	called := false
+	handlerToTest := AuthMiddleware(RequireScope(ScopeUser)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		assert.False(t, IsAPIKey(r.Context()))
+	})))
+	handlerToTest.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "http://localhost/", nil))
+	assert.True(t, called)
+}
+
+func TestScopeInterceptor(t *testing.T) {
+	SetAPIKeyVerifier(fakeVerifier{})
+	defer SetAPIKeyVerifier(nil)
+
+	scopes := map[string]string{
+		"/shortener.ShortenerService/GetUserURLS": ScopeRead,
+		"/shortener.ShortenerService/ShortURLS":   ScopeCreate,
+	}
+	call := func(method, key string) error {
+		ctx := metadata.NewIncomingContext(t.Context(), metadata.Pairs(MetaAPIKey, key))
+		info := &grpc.UnaryServerInfo{FullMethod: method}
+		_, err := AuthInterceptor(ctx, nil, info, func(ctx context.Context, req any) (any, error) {
+			return ScopeInterceptor(scopes)(ctx, req, info, func(ctx context.Context, req any) (any, error) {
+				return nil, nil
+			})
+		})
+		return err
+	}
+	assert.NoError(t, call("/shortener.ShortenerService/GetUserURLS", "good"))
+	assert.NoError(t, call("/shortener.ShortenerService/GetURL", "good"))
+	assert.Equal(t, codes.PermissionDenied, status.Code(call("/shortener.ShortenerService/ShortURLS", "good")))
+	assert.Equal(t, codes.Unauthenticated, status.Code(call("/shortener.ShortenerService/GetUserURLS", "bad")))
+}
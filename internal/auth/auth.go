@@ -12,10 +12,13 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
 // CookieName name of cookie for saving userid
@@ -24,6 +27,12 @@ const CookieName = "user_id"
 // MetaUserName name of meta for saving userid
 const MetaUserName = "User-ID"
 
+// MetaAuthorization name of meta grpc with bearer token
+const MetaAuthorization = "authorization"
+
+// BearerScheme auth scheme of header Authorization and meta authorization
+const BearerScheme = "Bearer"
+
 // TokenSep seperator for cookie value
 const TokenSep = "."
 
@@ -42,7 +51,105 @@ var ErrBadToken = errors.New("bad token")
 // ErrBadSignature error for signature
 var ErrBadSignature = errors.New("bad signature")
 
-var secret = []byte("somesecret")
+// ErrUnknownKey error for token signed by unknown or retired key
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// ErrNoKeys error for empty list of keys
+var ErrNoKeys = errors.New("no signing keys")
+
+// Key secret for signing tokens. ID is saved in the token
+type Key struct {
+	ID     string
+	Secret []byte
+}
+
+const (
+	// DefaultTokenTTL lifetime of user token
+	DefaultTokenTTL = 30 * 24 * time.Hour
+	// DefaultTokenRefresh token is reissued when it expires sooner than this
+	DefaultTokenRefresh = 7 * 24 * time.Hour
+	// DefaultTokenGrace expired token is reissued for the same userid during this time
+	DefaultTokenGrace = 7 * 24 * time.Hour
+)
+
+// ErrTokenExpired error for expired token
+var ErrTokenExpired = errors.New("token expired")
+
+var (
+	tokenTTL     = DefaultTokenTTL
+	tokenRefresh = DefaultTokenRefresh
+	tokenGrace   = DefaultTokenGrace
+	// now for tests
+	now = time.Now
+	// legacyTokens accept tokens userid.signature issued before signing keys
+	legacyTokens = false
+)
+
+// legacySecret secret of tokens userid.signature. It is public, anyone can sign such token
+var legacySecret = []byte("somesecret")
+
+// SetLegacyTokens accept or reject tokens userid.signature of old versions. Rejected by default.
+// Userid of accepted token is not fresh (see FreshToken) and is never reissued under signing keys.
+// Do not enable it in production. Call it before serving requests
+func SetLegacyTokens(enabled bool) {
+	legacyTokens = enabled
+}
+
+// SetTokenLifetime set lifetime of new tokens and time before expiry when token is reissued.
+// Values <= 0 mean DefaultTokenTTL and DefaultTokenRefresh. Call it before serving requests
+func SetTokenLifetime(ttl, refresh time.Duration) {
+	if ttl <= 0 {
+		ttl = DefaultTokenTTL
+	}
+	if refresh <= 0 {
+		refresh = DefaultTokenRefresh
+	}
+	tokenTTL = ttl
+	tokenRefresh = min(refresh, ttl)
+}
+
+// SetTokenGrace set time after expiry when token is still reissued for the same userid.
+// Value <= 0 means DefaultTokenGrace. Call it before serving requests
+func SetTokenGrace(grace time.Duration) {
+	if grace <= 0 {
+		grace = DefaultTokenGrace
+	}
+	tokenGrace = grace
+}
+
+// DevKey is used when keys are not configured. Do not use it in production
+var DevKey = Key{ID: "dev", Secret: []byte("somesecret")}
+
+// keyring first key signs, all keys verify
+type keyring struct {
+	signer Key
+	keys   map[string][]byte
+}
+
+var keys atomic.Pointer[keyring]
+
+func init() {
+	if err := SetKeys([]Key{DevKey}); err != nil {
+		panic(err)
+	}
+}
+
+// SetKeys set keys for tokens. The first key signs new tokens,
+// tokens signed by other keys are still valid. Tokens of removed keys become invalid
+func SetKeys(newKeys []Key) error {
+	if len(newKeys) == 0 {
+		return ErrNoKeys
+	}
+	kr := &keyring{signer: newKeys[0], keys: make(map[string][]byte, len(newKeys))}
+	for _, key := range newKeys {
+		if key.ID == "" || strings.Contains(key.ID, TokenSep) || len(key.Secret) == 0 {
+			return fmt.Errorf("bad signing key %q", key.ID)
+		}
+		kr.keys[key.ID] = key.Secret
+	}
+	keys.Store(kr)
+	return nil
+}
 
 func sign(value, key []byte) string {
 	h := hmac.New(sha256.New, key)
@@ -57,71 +164,195 @@ func generateUserID() UserID {
 	return UserID(base64.RawStdEncoding.EncodeToString([]byte(time)) + base64.RawStdEncoding.EncodeToString(b))
 }
 
-func createToken(value UserID) string {
-	signature := sign([]byte(value), secret)
-	return fmt.Sprintf("%s%s%s", value, TokenSep, signature)
+// NewUserID generate new userid
+func NewUserID() UserID {
+	return generateUserID()
+}
+
+// token claims of signed token
+type token struct {
+	userID    UserID
+	issuedAt  time.Time
+	expiresAt time.Time
+	// legacy token userid.signature without key and expiry
+	legacy bool
+}
+
+// needRefresh token expires soon and must be reissued. Legacy token is never reissued
+func (t *token) needRefresh() bool {
+	return !t.legacy && now().Add(tokenRefresh).After(t.expiresAt)
+}
+
+// inGrace expired token is reissued for the same userid
+func (t *token) inGrace() bool {
+	return now().Before(t.expiresAt.Add(tokenGrace))
+}
+
+// createToken make token kid.userid.iat.exp.signature. Return token and time of expiry
+func createToken(value UserID) (string, time.Time) {
+	signer := keys.Load().signer
+	issuedAt := now()
+	expiresAt := issuedAt.Add(tokenTTL)
+	payload := strings.Join([]string{
+		signer.ID,
+		string(value),
+		strconv.FormatInt(issuedAt.Unix(), 10),
+		strconv.FormatInt(expiresAt.Unix(), 10),
+	}, TokenSep)
+	return payload + TokenSep + sign([]byte(payload), signer.Secret), expiresAt
+}
+
+// checkToken check signature and expiry of token. Expired token is returned with ErrTokenExpired
+func checkToken(value string) (*token, error) {
+	items := strings.Split(value, TokenSep)
+	if len(items) == 2 && legacyTokens {
+		return checkLegacyToken(items)
+	}
+	if len(items) != 5 {
+		return nil, ErrBadToken
+	}
+	secret, ok := keys.Load().keys[items[0]]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	payload := strings.Join(items[:4], TokenSep)
+	if !hmac.Equal([]byte(sign([]byte(payload), secret)), []byte(items[4])) {
+		return nil, ErrBadSignature
+	}
+	iat, err := strconv.ParseInt(items[2], 10, 64)
+	if err != nil {
+		return nil, ErrBadToken
+	}
+	exp, err := strconv.ParseInt(items[3], 10, 64)
+	if err != nil {
+		return nil, ErrBadToken
+	}
+	t := &token{userID: UserID(items[1]), issuedAt: time.Unix(iat, 0), expiresAt: time.Unix(exp, 0)}
+	if !now().Before(t.expiresAt) {
+		return t, ErrTokenExpired
+	}
+	return t, nil
 }
 
-func checkToken(token string) (UserID, error) {
-	items := strings.Split(token, TokenSep)
-	if len(items) != 2 {
-		return "", ErrBadToken
+// checkLegacyToken check token userid.signature of old versions
+func checkLegacyToken(items []string) (*token, error) {
+	if items[0] == "" {
+		return nil, ErrBadToken
 	}
-	if sign([]byte(items[0]), secret) != items[1] {
-		return "", ErrBadSignature
+	if !hmac.Equal([]byte(sign([]byte(items[0]), legacySecret)), []byte(items[1])) {
+		return nil, ErrBadSignature
 	}
-	return UserID(items[0]), nil
+	return &token{userID: UserID(items[0]), legacy: true}, nil
 }
 
-func setCookieUserID(w http.ResponseWriter, value UserID) {
+func setCookieUserID(w http.ResponseWriter, value UserID) (string, time.Time) {
+	val, expiresAt := createToken(value)
 	cookie := &http.Cookie{
 		Name:     CookieName,
-		Value:    createToken(value),
+		Value:    val,
 		Path:     "/",
+		Expires:  expiresAt,
 		HttpOnly: true,                    // Доступ только через HTTP, защита от XSS
 		SameSite: http.SameSiteStrictMode, // Защита от CSRF
 	}
 	http.SetCookie(w, cookie)
+	return val, expiresAt
 }
 
-// GetUserIDFromCookie get userid from cookie user_id
-func GetUserIDFromCookie(r *http.Request) (UserID, error) {
+// SetUserCookie set cookie with new token of userid, e.g. after login. Return token and time of expiry
+func SetUserCookie(w http.ResponseWriter, userID UserID) (string, time.Time) {
+	return setCookieUserID(w, userID)
+}
+
+func tokenFromCookie(r *http.Request) (*token, error) {
 	cookie, err := r.Cookie(CookieName)
 	if err != nil {
-		return "", ErrCookieUserID
+		return nil, ErrCookieUserID
 	}
-	userID, err := checkToken(cookie.Value)
-	if err != nil {
-		return "", err
+	return checkToken(cookie.Value)
+}
+
+// tokenFromBearer parse value "Bearer <token>"
+func tokenFromBearer(value string) (*token, error) {
+	scheme, val, ok := strings.Cut(value, " ")
+	// схема регистронезависима
+	if !ok || !strings.EqualFold(scheme, BearerScheme) {
+		return nil, ErrBadToken
 	}
-	return userID, nil
+	return checkToken(strings.TrimSpace(val))
 }
 
-// GetUserIDFromMeta get userid from meta grpc
-func GetUserIDFromMeta(ctx context.Context) (UserID, error) {
-	value := ""
+// metaValue first value of incoming meta grpc
+func metaValue(ctx context.Context, name string) string {
 	md, ok := metadata.FromIncomingContext(ctx)
 	if ok {
-		values := md.Get(MetaUserName)
+		values := md.Get(name)
 		if len(values) > 0 {
 			// ключ содержит слайс строк, получаем первую строку
-			value = values[0]
+			return values[0]
 		}
 	}
+	return ""
+}
+
+func tokenFromMeta(ctx context.Context) (*token, error) {
+	value := metaValue(ctx, MetaUserName)
 	if value == "" {
-		return "", ErrCookieUserID
+		return nil, ErrCookieUserID
+	}
+	return checkToken(value)
+}
+
+// IssueToken create new token for userid. Return token and time of expiry
+func IssueToken(userID UserID) (string, time.Time) {
+	return createToken(userID)
+}
+
+// GetUserIDFromCookie get userid from cookie user_id
+func GetUserIDFromCookie(r *http.Request) (UserID, error) {
+	t, err := tokenFromCookie(r)
+	if err != nil {
+		return "", err
 	}
-	userID, err := checkToken(value)
+	return t.userID, nil
+}
+
+// GetUserIDFromMeta get userid from meta grpc
+func GetUserIDFromMeta(ctx context.Context) (UserID, error) {
+	t, err := tokenFromMeta(ctx)
 	if err != nil {
 		return "", err
 	}
-	return userID, nil
+	return t.userID, nil
 }
 
 type userCtxKeyType string
 
 const userCtxKey userCtxKeyType = "userID"
 
+type staleCtxKey struct{}
+
+// withStaleToken mark userid of ctx as taken from not fresh token, see FreshToken
+func withStaleToken(ctx context.Context) context.Context {
+	return context.WithValue(ctx, staleCtxKey{}, true)
+}
+
+// withTokenState mark userid of legacy token as not fresh: its secret is public
+func withTokenState(ctx context.Context, t *token) context.Context {
+	if t.legacy {
+		return withStaleToken(ctx)
+	}
+	return ctx
+}
+
+// FreshToken userid of ctx is taken from not expired token, api key or is new.
+// Userid of expired token in grace and of legacy token is not fresh: links, accounts
+// and new tokens are not bound to it
+func FreshToken(ctx context.Context) bool {
+	stale, _ := ctx.Value(staleCtxKey{}).(bool)
+	return !stale
+}
+
 // WithUser helper set userid in context
 func WithUser(ctx context.Context, userID *UserID) context.Context {
 	return context.WithValue(ctx, userCtxKey, userID)
@@ -137,41 +368,138 @@ func GetUserID(ctx context.Context) (UserID, error) {
 	return *userID, nil
 }
 
-// AuthMiddleware get userid from cookie and save it in context.
-// Or create userid, save it into context and set cookie
+// AuthMiddleware get userid from header X-API-Key, Authorization or cookie and save it in context.
+// Without them create userid, save it into context and set cookie.
+// Token from cookie close to expiry is reissued. Token expired less than grace ago is reissued
+// with the same userid, which is not fresh for this request (see FreshToken).
+// Token expired longer ago is ignored as bad one. Expired or bad bearer token gets 401
 func AuthMiddleware(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		userID, err := GetUserIDFromCookie(r)
-		if err != nil {
+		if r.Header.Get(HeaderAPIKey) != "" {
+			APIKeyMiddleware(h).ServeHTTP(w, r)
+			return
+		}
+		var userID UserID
+		if header := r.Header.Get("Authorization"); header != "" {
+			t, err := tokenFromBearer(header)
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", BearerScheme)
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+			serveWithUser(h, w, r.WithContext(withTokenState(r.Context(), t)), t.userID)
+			return
+		}
+
+		t, err := tokenFromCookie(r)
+		switch {
+		case errors.Is(err, ErrTokenExpired) && t.inGrace():
+			userID = t.userID
+			setCookieUserID(w, userID)
+			r = r.WithContext(withStaleToken(r.Context()))
+		case err != nil:
+			// токен, протухший давно, не дает старой личности
 			userID = generateUserID()
 			setCookieUserID(w, userID)
+		default:
+			userID = t.userID
+			r = r.WithContext(withTokenState(r.Context(), t))
+			if t.needRefresh() {
+				setCookieUserID(w, userID)
+			}
 		}
-		// сохраним в контекст
-		ctx := WithUser(r.Context(), &userID)
-		// TODO может надо Clone
-		r2 := r.WithContext(ctx)
-		*r = *r2
+		serveWithUser(h, w, r, userID)
+	})
+}
 
-		// передаём управление хендлеру
+// RequireFreshToken reject request of user with not fresh token with 401, see FreshToken.
+// It protects routes which issue new credentials for userid
+func RequireFreshToken(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !FreshToken(r.Context()) {
+			http.Error(w, ErrTokenExpired.Error(), http.StatusUnauthorized)
+			return
+		}
 		h.ServeHTTP(w, r)
 	})
 }
 
-// AuthInterceptor get userid from meta grpc and save it in context.
-// Or create userid, save it into context and meta
+// serveWithUser save userid in context and call handler
+func serveWithUser(h http.Handler, w http.ResponseWriter, r *http.Request, userID UserID) {
+	// сохраним в контекст
+	ctx := WithUser(r.Context(), &userID)
+	// TODO может надо Clone
+	r2 := r.WithContext(ctx)
+	*r = *r2
+	// передаём управление хендлеру
+	h.ServeHTTP(w, r)
+}
+
+// AuthInterceptor get userid from meta x-api-key, authorization or User-ID and save it in context.
+// Service from CertInterceptor without authorization gets its own userid.
+// Without them create userid, save it into context and send token in trailer.
+// Token from User-ID close to expiry or expired less than grace ago is reissued in trailer,
+// userid of expired token is not fresh (see FreshToken). Token expired longer ago is ignored.
+// Expired or bad bearer token gets Unauthenticated
 func AuthInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
 	// выполняем действия перед вызовом метода
-	if info.FullMethod != "/shortener.ShortenerService/InternalStats" {
-		userID, err := GetUserIDFromMeta(ctx)
+	if ctxKey, ok, err := apiKeyFromMeta(ctx); ok {
 		if err != nil {
-			userID = generateUserID()
-			ctx = metadata.AppendToOutgoingContext(ctx, MetaUserName, createToken(userID))
+			return nil, err
 		}
-
-		// сохраним в контекст
-		ctx = WithUser(ctx, &userID)
+		return handler(ctxKey, req)
+	}
+	if info.FullMethod == "/shortener.ShortenerService/InternalStats" {
+		return handler(ctx, req)
+	}
+	if value := metaValue(ctx, MetaAuthorization); value != "" {
+		t, err := tokenFromBearer(value)
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		return handler(WithUser(withTokenState(ctx, t), &t.userID), req)
+	}
+	// сервис без токена работает от своего пользователя
+	if svc, ok := GetService(ctx); ok {
+		userID := svc.UserID()
+		return handler(WithUser(ctx, &userID), req)
 	}
 
+	var userID UserID
+	t, err := tokenFromMeta(ctx)
+	switch {
+	case errors.Is(err, ErrTokenExpired) && t.inGrace():
+		userID = t.userID
+		ctx = withStaleToken(withTokenUserID(ctx, userID))
+	case err != nil:
+		userID = generateUserID()
+		ctx = withTokenUserID(ctx, userID)
+	default:
+		userID = t.userID
+		ctx = withTokenState(ctx, t)
+		if t.needRefresh() {
+			ctx = withTokenUserID(ctx, userID)
+		}
+	}
+	// сохраним в контекст
+	ctx = WithUser(ctx, &userID)
 	// Возвращаем ответ и ошибку от фактического обработчика
 	return handler(ctx, req)
 }
+
+// FreshTokenInterceptor reject call of methods from freshMethods by user with not fresh token
+// with Unauthenticated, see FreshToken
+func FreshTokenInterceptor(freshMethods map[string]bool) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !FreshToken(ctx) && freshMethods[info.FullMethod] {
+			return nil, status.Error(codes.Unauthenticated, ErrTokenExpired.Error())
+		}
+		return handler(ctx, req)
+	}
+}
+
+// withTokenUserID put new token into outgoing meta. It is sent to client in trailer
+func withTokenUserID(ctx context.Context, userID UserID) context.Context {
+	val, _ := createToken(userID)
+	return metadata.AppendToOutgoingContext(ctx, MetaUserName, val)
+}
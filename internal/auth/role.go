@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Roles of users
+const (
+	// RoleUser registered user
+	RoleUser = "user"
+	// RoleAdmin moderator with access to admin api
+	RoleAdmin = "admin"
+)
+
+// RoleResolver return role of user. Anonymous user has empty role
+type RoleResolver interface {
+	UserRole(ctx context.Context, userID UserID) (string, error)
+}
+
+var roleResolver RoleResolver
+
+// SetRoleResolver set resolver of roles. Without it nobody has a role.
+// Call it before serving requests
+func SetRoleResolver(r RoleResolver) {
+	roleResolver = r
+}
+
+type roleCtxKeyType string
+
+const roleCtxKey roleCtxKeyType = "role"
+
+// GetRole return role of user checked by RequireRole or RoleInterceptor
+func GetRole(ctx context.Context) string {
+	role, _ := ctx.Value(roleCtxKey).(string)
+	return role
+}
+
+// checkRole user of request has role. Request by api key never has a role,
+// request of service has role of service
+func checkRole(ctx context.Context, role string) (context.Context, bool, error) {
+	if svc, ok := GetService(ctx); ok {
+		return context.WithValue(ctx, roleCtxKey, svc.Role), svc.HasRole(role), nil
+	}
+	if roleResolver == nil || IsAPIKey(ctx) {
+		return ctx, false, nil
+	}
+	userID, err := GetUserID(ctx)
+	if err != nil {
+		return ctx, false, nil
+	}
+	userRole, err := roleResolver.UserRole(ctx, userID)
+	if err != nil {
+		return ctx, false, err
+	}
+	return context.WithValue(ctx, roleCtxKey, userRole), userRole == role, nil
+}
+
+// RequireRole middleware reject request of user without role with 403. Use it after AuthMiddleware
+func RequireRole(role string) func(h http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, ok, err := checkRole(r.Context(), role)
+			if err != nil {
+				code := http.StatusInternalServerError
+				http.Error(w, http.StatusText(code), code)
+				return
+			}
+			if !ok {
+				code := http.StatusForbidden
+				http.Error(w, http.StatusText(code), code)
+				return
+			}
+			h.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RoleInterceptor reject call of method without role from methodRoles with PermissionDenied.
+// Methods not in methodRoles are allowed
+func RoleInterceptor(methodRoles map[string]string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		role, found := methodRoles[info.FullMethod]
+		if !found {
+			return handler(ctx, req)
+		}
+		ctx, ok, err := checkRole(ctx, role)
+		if err != nil {
+			code := codes.Internal
+			return nil, status.Error(code, code.String())
+		}
+		if !ok {
+			code := codes.PermissionDenied
+			return nil, status.Error(code, code.String())
+		}
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeResolver admin is the only user with role admin
+type fakeResolver struct{}
+
+func (fakeResolver) UserRole(ctx context.Context, userID UserID) (string, error) {
+	if userID == "admin" {
+		return RoleAdmin, nil
+	}
+	return RoleUser, nil
+}
+
+func TestRequireRole(t *testing.T) {
+	SetRoleResolver(fakeResolver{})
+	defer SetRoleResolver(nil)
+	SetAPIKeyVerifier(fakeVerifier{})
+	defer SetAPIKeyVerifier(nil)
+
+	tests := []struct {
+		name       string
+		userID     UserID
+		apiKey     bool
+		statusCode int
+	}{
+		{name: "admin", userID: "admin", statusCode: http.StatusOK},
+		{name: "user", userID: "user1", statusCode: http.StatusForbidden},
+		{name: "api key", userID: "admin", apiKey: true, statusCode: http.StatusForbidden},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handlerToTest := RequireRole(RoleAdmin)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, RoleAdmin, GetRole(r.Context()))
+			}))
+			req := httptest.NewRequest("GET", "http://localhost/", nil)
+			ctx := WithUser(req.Context(), &test.userID)
+			if test.apiKey {
+				ctx = withScopes(ctx, []string{ScopeRead})
+			}
+			w := httptest.NewRecorder()
+			handlerToTest.ServeHTTP(w, req.WithContext(ctx))
+			res := w.Result()
+			defer res.Body.Close()
+			assert.Equal(t, test.statusCode, res.StatusCode)
+		})
+	}
+}
+
+func TestRoleInterceptor(t *testing.T) {
+	SetRoleResolver(fakeResolver{})
+	defer SetRoleResolver(nil)
+
+	interceptor := RoleInterceptor(map[string]string{"/shortener.ShortenerService/TopURLS": RoleAdmin})
+	handler := func(ctx context.Context, req any) (any, error) { return nil, nil }
+	call := func(method string, userID UserID) error {
+		ctx := WithUser(context.Background(), &userID)
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+		return err
+	}
+
+	assert.NoError(t, call("/shortener.ShortenerService/TopURLS", "admin"))
+	assert.Equal(t, codes.PermissionDenied, status.Code(call("/shortener.ShortenerService/TopURLS", "user1")))
+	assert.NoError(t, call("/shortener.ShortenerService/GetURL", "user1"))
+}
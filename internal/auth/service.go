@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// RoleStats internal service with access to internal stats
+const RoleStats = "stats"
+
+// serviceRolesAllowed roles which can be granted to services
+var serviceRolesAllowed = []string{RoleStats, RoleAdmin}
+
+// ErrBadServiceRole error for unknown role of service
+var ErrBadServiceRole = errors.New("bad service role")
+
+// Service internal service authenticated by client certificate
+type Service struct {
+	Name string
+	Role string
+}
+
+// UserID user of service. It owns urls created by service
+func (s Service) UserID() UserID {
+	return UserID("service:" + s.Name)
+}
+
+// HasRole service has role. Admin has all roles
+func (s Service) HasRole(role string) bool {
+	return s.Role == role || s.Role == RoleAdmin
+}
+
+var serviceRoles map[string]string
+
+// SetServiceRoles set roles of services by identity of client certificate.
+// Without it client certificates do not grant roles. Call it before serving requests
+func SetServiceRoles(roles map[string]string) error {
+	for name, role := range roles {
+		if role != RoleStats && role != RoleAdmin {
+			return fmt.Errorf("%w: %q of %q, want one of %v", ErrBadServiceRole, role, name, serviceRolesAllowed)
+		}
+	}
+	serviceRoles = roles
+	return nil
+}
+
+// certIdentities identities of certificate: uri and dns from SAN, then common name
+func certIdentities(cert *x509.Certificate) []string {
+	result := make([]string, 0, len(cert.URIs)+len(cert.DNSNames)+1)
+	for _, uri := range cert.URIs {
+		result = append(result, uri.String())
+	}
+	result = append(result, cert.DNSNames...)
+	if cert.Subject.CommonName != "" {
+		result = append(result, cert.Subject.CommonName)
+	}
+	return result
+}
+
+// serviceFromCert return service of the first identity of cert with role
+func serviceFromCert(cert *x509.Certificate) (Service, bool) {
+	for _, name := range certIdentities(cert) {
+		if role, ok := serviceRoles[name]; ok {
+			return Service{Name: name, Role: role}, true
+		}
+	}
+	return Service{}, false
+}
+
+type serviceCtxKeyType string
+
+const serviceCtxKey serviceCtxKeyType = "service"
+
+// GetService return service authenticated by CertInterceptor
+func GetService(ctx context.Context) (Service, bool) {
+	svc, ok := ctx.Value(serviceCtxKey).(Service)
+	return svc, ok
+}
+
+// HasServiceRole request is made by service with role
+func HasServiceRole(ctx context.Context, role string) bool {
+	svc, ok := GetService(ctx)
+	return ok && svc.HasRole(role)
+}
+
+// CertInterceptor save in context service of verified client certificate.
+// Certificate without known identity is ignored. Use it before AuthInterceptor
+func CertInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return handler(ctx, req)
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	// цепочка есть только у сертификата, проверенного по ca
+	if !ok || len(tlsInfo.State.VerifiedChains) == 0 || len(tlsInfo.State.VerifiedChains[0]) == 0 {
+		return handler(ctx, req)
+	}
+	if svc, ok := serviceFromCert(tlsInfo.State.VerifiedChains[0][0]); ok {
+		ctx = context.WithValue(ctx, serviceCtxKey, svc)
+	}
+	return handler(ctx, req)
+}
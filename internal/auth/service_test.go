@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// peerWithCert context of grpc request with client certificate. verified - cert is checked by ca
+func peerWithCert(cert *x509.Certificate, verified bool) context.Context {
+	state := tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+	if verified {
+		state.VerifiedChains = [][]*x509.Certificate{{cert}}
+	}
+	return peer.NewContext(context.Background(), &peer.Peer{AuthInfo: credentials.TLSInfo{State: state}})
+}
+
+func TestSetServiceRoles(t *testing.T) {
+	assert.ErrorIs(t, SetServiceRoles(map[string]string{"svc": "root"}), ErrBadServiceRole)
+	assert.NoError(t, SetServiceRoles(map[string]string{"svc": RoleStats, "ops": RoleAdmin}))
+	SetServiceRoles(nil)
+}
+
+func TestCertInterceptor(t *testing.T) {
+	require.NoError(t, SetServiceRoles(map[string]string{
+		"spiffe://prod/ops": RoleAdmin,
+		"billing.internal":  RoleStats,
+		"reporter":          RoleStats,
+	}))
+	defer SetServiceRoles(nil)
+
+	uri, err := url.Parse("spiffe://prod/ops")
+	require.NoError(t, err)
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		service Service
+		found   bool
+	}{
+		{name: "without peer", ctx: context.Background()},
+		{
+			name:    "uri san",
+			ctx:     peerWithCert(&x509.Certificate{URIs: []*url.URL{uri}, DNSNames: []string{"billing.internal"}}, true),
+			service: Service{Name: "spiffe://prod/ops", Role: RoleAdmin},
+			found:   true,
+		},
+		{
+			name:    "dns san",
+			ctx:     peerWithCert(&x509.Certificate{DNSNames: []string{"unknown", "billing.internal"}}, true),
+			service: Service{Name: "billing.internal", Role: RoleStats},
+			found:   true,
+		},
+		{
+			name:    "common name",
+			ctx:     peerWithCert(&x509.Certificate{Subject: pkix.Name{CommonName: "reporter"}}, true),
+			service: Service{Name: "reporter", Role: RoleStats},
+			found:   true,
+		},
+		{name: "unknown identity", ctx: peerWithCert(&x509.Certificate{DNSNames: []string{"unknown"}}, true)},
+		{name: "not verified", ctx: peerWithCert(&x509.Certificate{DNSNames: []string{"billing.internal"}}, false)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := CertInterceptor(test.ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req any) (any, error) {
+				svc, ok := GetService(ctx)
+				assert.Equal(t, test.found, ok)
+				assert.Equal(t, test.service, svc)
+				return nil, nil
+			})
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestRoleInterceptor_service(t *testing.T) {
+	require.NoError(t, SetServiceRoles(map[string]string{"ops": RoleAdmin, "billing": RoleStats}))
+	defer SetServiceRoles(nil)
+
+	interceptor := RoleInterceptor(map[string]string{"/admin": RoleAdmin})
+	chain := func(ctx context.Context) error {
+		_, err := CertInterceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/admin"}, func(ctx context.Context, req any) (any, error) {
+			return AuthInterceptor(ctx, req, &grpc.UnaryServerInfo{FullMethod: "/admin"}, func(ctx context.Context, req any) (any, error) {
+				userID, err := GetUserID(ctx)
+				require.NoError(t, err)
+				assert.Contains(t, string(userID), "service:")
+				return interceptor(ctx, req, &grpc.UnaryServerInfo{FullMethod: "/admin"}, func(ctx context.Context, req any) (any, error) {
+					return nil, nil
+				})
+			})
+		})
+		return err
+	}
+
+	assert.NoError(t, chain(peerWithCert(&x509.Certificate{DNSNames: []string{"ops"}}, true)))
+	err := chain(peerWithCert(&x509.Certificate{DNSNames: []string{"billing"}}, true))
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	assert.True(t, Service{Role: RoleAdmin}.HasRole(RoleStats))
+}
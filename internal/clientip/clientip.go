@@ -0,0 +1,104 @@
+// Package clientip resolve ip of client of http and grpc requests.
+// Headers X-Forwarded-For and X-Real-IP are honored only when the direct peer
+// is a trusted proxy, otherwise anybody could spoof them
+package clientip
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// Headers with ip of client set by proxies
+const (
+	HeaderForwardedFor = "X-Forwarded-For"
+	HeaderRealIP       = "X-Real-IP"
+)
+
+var trustedProxies atomic.Pointer[[]*net.IPNet]
+
+// SetTrustedProxies set subnets of proxies whose headers are trusted. Without them headers are ignored
+func SetTrustedProxies(nets []*net.IPNet) {
+	trustedProxies.Store(&nets)
+}
+
+// isTrustedProxy ip is in one of trusted subnets
+func isTrustedProxy(ip net.IP) bool {
+	nets := trustedProxies.Load()
+	if nets == nil || ip == nil {
+		return false
+	}
+	for _, n := range *nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// hostIP ip from address host:port or from bare ip
+func hostIP(addr string) net.IP {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	return net.ParseIP(strings.TrimSpace(host))
+}
+
+// resolve return ip of client by ip of direct peer and values of headers
+func resolve(remote net.IP, forwardedFor []string, realIP string) string {
+	if remote == nil {
+		return ""
+	}
+	if !isTrustedProxy(remote) {
+		return remote.String()
+	}
+	// идем справа налево: правые адреса добавлены нашими прокси
+	var hops []string
+	for _, value := range forwardedFor {
+		hops = append(hops, strings.Split(value, ",")...)
+	}
+	var leftmost net.IP
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := hostIP(hops[i])
+		if ip == nil {
+			// мусор в заголовке, дальше доверять нельзя
+			break
+		}
+		leftmost = ip
+		if !isTrustedProxy(ip) {
+			return ip.String()
+		}
+	}
+	if leftmost != nil {
+		return leftmost.String()
+	}
+	if ip := hostIP(realIP); ip != nil {
+		return ip.String()
+	}
+	return remote.String()
+}
+
+// FromRequest return ip of client of http request
+func FromRequest(r *http.Request) string {
+	return resolve(hostIP(r.RemoteAddr), r.Header.Values(HeaderForwardedFor), r.Header.Get(HeaderRealIP))
+}
+
+// FromContext return ip of client of grpc request
+func FromContext(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	var realIP string
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get(HeaderRealIP); len(values) > 0 {
+		realIP = values[0]
+	}
+	return resolve(hostIP(p.Addr.String()), md.Get(HeaderForwardedFor), realIP)
+}
@@ -0,0 +1,61 @@
+package clientip
+
+import (
+	"context"
+	"net"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+func TestFromRequest(t *testing.T) {
+	SetTrustedProxies([]*net.IPNet{{IP: net.IP{10, 0, 0, 0}, Mask: net.CIDRMask(8, 32)}})
+	defer SetTrustedProxies(nil)
+
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor []string
+		realIP       string
+		expect       string
+	}{
+		{name: "direct", remoteAddr: "1.2.3.4:5555", expect: "1.2.3.4"},
+		{name: "spoofed headers", remoteAddr: "1.2.3.4:5555", forwardedFor: []string{"5.6.7.8"}, realIP: "5.6.7.8", expect: "1.2.3.4"},
+		{name: "proxy", remoteAddr: "10.0.0.1:5555", forwardedFor: []string{"5.6.7.8"}, expect: "5.6.7.8"},
+		{name: "chain of proxies", remoteAddr: "10.0.0.1:5555", forwardedFor: []string{"9.9.9.9, 5.6.7.8", "10.0.0.2"}, expect: "5.6.7.8"},
+		{name: "only proxies", remoteAddr: "10.0.0.1:5555", forwardedFor: []string{"10.0.0.3, 10.0.0.2"}, expect: "10.0.0.3"},
+		{name: "garbage", remoteAddr: "10.0.0.1:5555", forwardedFor: []string{"5.6.7.8, garbage"}, realIP: "5.6.7.8", expect: "5.6.7.8"},
+		{name: "real ip", remoteAddr: "10.0.0.1:5555", realIP: "5.6.7.8", expect: "5.6.7.8"},
+		{name: "ipv6", remoteAddr: "[::1]:5555", expect: "::1"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "http://localhost/", nil)
+			req.RemoteAddr = test.remoteAddr
+			for _, value := range test.forwardedFor {
+				req.Header.Add(HeaderForwardedFor, value)
+			}
+			if test.realIP != "" {
+				req.Header.Set(HeaderRealIP, test.realIP)
+			}
+			assert.Equal(t, test.expect, FromRequest(req))
+		})
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	SetTrustedProxies([]*net.IPNet{{IP: net.IP{10, 0, 0, 0}, Mask: net.CIDRMask(8, 32)}})
+	defer SetTrustedProxies(nil)
+
+	md := metadata.Pairs("x-real-ip", "5.6.7.8")
+	ctx := metadata.NewIncomingContext(context.Background(), md)
+	assert.Equal(t, "", FromContext(ctx))
+
+	direct := peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.IP{1, 2, 3, 4}, Port: 5555}})
+	assert.Equal(t, "1.2.3.4", FromContext(direct))
+	proxy := peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.IP{10, 0, 0, 1}, Port: 5555}})
+	assert.Equal(t, "5.6.7.8", FromContext(proxy))
+}
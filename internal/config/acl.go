@@ -0,0 +1,104 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Endpoints with optional acl
+const (
+	// ACLStats /api/internal/stats and grpc InternalStats
+	ACLStats = "stats"
+	// ACLDebug /debug with pprof
+	ACLDebug = "debug"
+	// ACLAdmin /api/admin and grpc admin methods
+	ACLAdmin = "admin"
+)
+
+// aclEndpoints endpoints which can have acl
+var aclEndpoints = []string{ACLStats, ACLDebug, ACLAdmin}
+
+// ErrParseACL error for bad acl
+var ErrParseACL = errors.New("bad acl")
+
+// ACL allowed networks by endpoint
+type ACL struct {
+	Rules map[string]TrustedSubnet `env:"-" json:"-"`
+}
+
+// String flag.Value interface for type ACL
+func (acl *ACL) String() string {
+	items := make([]string, 0, len(acl.Rules))
+	for _, name := range slices.Sorted(maps.Keys(acl.Rules)) {
+		nets := acl.Rules[name]
+		items = append(items, name+"="+nets.String())
+	}
+	return strings.Join(items, ";")
+}
+
+// add add nets of endpoint
+func (acl *ACL) add(name, nets string) error {
+	if !slices.Contains(aclEndpoints, name) {
+		return fmt.Errorf("%w: unknown endpoint %q", ErrParseACL, name)
+	}
+	if acl.Rules == nil {
+		acl.Rules = make(map[string]TrustedSubnet)
+	}
+	tsn := acl.Rules[name]
+	if err := tsn.Set(nets); err != nil {
+		return fmt.Errorf("%w: %w", ErrParseACL, err)
+	}
+	acl.Rules[name] = tsn
+	return nil
+}
+
+// Set flag.Value interface for type ACL. Value like (stats=10.0.0.0/8,fd00::/8;debug=127.0.0.1/32)
+func (acl *ACL) Set(val string) error {
+	for _, rule := range strings.Split(val, ";") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+		name, nets, ok := strings.Cut(rule, "=")
+		if !ok {
+			return fmt.Errorf("%w: want endpoint=cidr,cidr", ErrParseACL)
+		}
+		if err := acl.add(strings.TrimSpace(name), nets); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UnmarshalJSON for ACL. Value is object endpoint to string or array of cidr
+func (acl *ACL) UnmarshalJSON(data []byte) error {
+	var rules map[string]TrustedSubnet
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return err
+	}
+	*acl = ACL{}
+	for name, nets := range rules {
+		if err := acl.add(name, nets.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Get return allowed nets of endpoint. ok is false if endpoint has no acl
+func (acl *ACL) Get(name string) (TrustedSubnet, bool) {
+	nets, ok := acl.Rules[name]
+	return nets, ok
+}
+
+// GetOr return allowed nets of endpoint or fallback if endpoint has no acl
+func (acl *ACL) GetOr(name string, fallback TrustedSubnet) TrustedSubnet {
+	if nets, ok := acl.Get(name); ok {
+		return nets
+	}
+	return fallback
+}
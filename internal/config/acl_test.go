@@ -0,0 +1,35 @@
+package config
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestACL(t *testing.T) {
+	var acl ACL
+	require.NoError(t, acl.Set("stats=10.0.0.0/8,fd00::/8; debug=127.0.0.1/32"))
+	require.NoError(t, acl.Set("stats=192.168.1.0/24"))
+	assert.Equal(t, "debug=127.0.0.1/32;stats=10.0.0.0/8,fd00::/8,192.168.1.0/24", acl.String())
+
+	stats, ok := acl.Get(ACLStats)
+	require.True(t, ok)
+	assert.True(t, stats.IsTrusted("fd00::1"))
+	_, ok = acl.Get(ACLAdmin)
+	assert.False(t, ok)
+
+	var fallback TrustedSubnet
+	require.NoError(t, fallback.Set("172.16.0.0/12"))
+	admin := acl.GetOr(ACLAdmin, fallback)
+	assert.True(t, admin.IsTrusted("172.16.0.1"))
+
+	for _, bad := range []string{"stats", "unknown=10.0.0.0/8", "stats=bad"} {
+		assert.ErrorIs(t, (&ACL{}).Set(bad), ErrParseACL, bad)
+	}
+
+	require.NoError(t, json.Unmarshal([]byte(`{"admin":["10.0.0.0/8","::1/128"],"debug":"127.0.0.1/32"}`), &acl))
+	assert.Equal(t, "admin=10.0.0.0/8,::1/128;debug=127.0.0.1/32", acl.String())
+	assert.ErrorIs(t, json.Unmarshal([]byte(`{"unknown":"10.0.0.0/8"}`), &acl), ErrParseACL)
+}
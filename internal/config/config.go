@@ -4,6 +4,7 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"net"
@@ -62,6 +63,24 @@ func (sa *ServerAddress) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// ErrNoAdminPassword basic auth of admin listener without password
+var ErrNoAdminPassword = errors.New("admin password is required with admin user")
+
+// ErrSinglePortGRPC grpc on the port of http server has no own address and certificate
+var ErrSinglePortGRPC = errors.New("single port mode does not allow grpc address and grpc tls cert")
+
+// ErrSinglePortClientCert required client certificate on single port would be required from browsers and rest clients too
+var ErrSinglePortClientCert = errors.New("single port mode does not allow required grpc client cert")
+
+// ErrNoTLSCert https without certificate files and without dev certificate
+var ErrNoTLSCert = errors.New("https requires tls cert and key files or tls dev cert")
+
+// ErrDevCertInProduction self-signed certificate is not allowed in production
+var ErrDevCertInProduction = errors.New("tls dev cert is not allowed in production")
+
+// ErrLegacyTokensInProduction tokens signed by public secret are not allowed in production
+var ErrLegacyTokensInProduction = errors.New("legacy tokens are not allowed in production")
+
 type config struct {
 	// ServerAddress is hostname where app will work
 	ServerAddress ServerAddress `env:"SERVER_ADDRESS" json:"server_address"`
@@ -69,16 +88,140 @@ type config struct {
 	BaseURL string `env:"BASE_URL" json:"base_url"`
 	// LogLevel - logging level
 	LogLevel string `env:"LOG_LEVEL" json:"log_level"`
+	// LogLevels comma separated own levels of components like storage=debug,grpc=warn
+	LogLevels string `env:"LOG_LEVELS" json:"log_levels"`
+	// LogEncoding encoding of application log: json or console. Empty - json
+	LogEncoding string `env:"LOG_ENCODING" json:"log_encoding"`
+	// LogOutput comma separated outputs of application log: stderr, stdout or path to file. Empty - stderr
+	LogOutput string `env:"LOG_OUTPUT" json:"log_output"`
+	// LogMaxSize size of log file in megabytes when it is rotated. 0 - no rotation by size
+	LogMaxSize int `env:"LOG_MAX_SIZE" json:"log_max_size"`
+	// LogRotateInterval log files are rotated at every multiple of interval (24h - at midnight UTC).
+	// 0 - no rotation by time
+	LogRotateInterval Duration `env:"LOG_ROTATE_INTERVAL" json:"log_rotate_interval"`
+	// LogMaxBackups count of kept rotated files. 0 - all files are kept
+	LogMaxBackups int `env:"LOG_MAX_BACKUPS" json:"log_max_backups"`
+	// LogMaxAge rotated files older than this are removed, rounded up to days. 0 - files are not removed by age
+	LogMaxAge Duration `env:"LOG_MAX_AGE" json:"log_max_age"`
+	// LogCompress compress rotated files by gzip
+	LogCompress bool `env:"LOG_COMPRESS" json:"log_compress"`
+	// AccessLog comma separated outputs of access log like LogOutput. Empty - requests are logged into application log
+	AccessLog string `env:"ACCESS_LOG" json:"access_log"`
+	// AccessLogFormat format of access log: json, console or combined. Empty - LogEncoding
+	AccessLogFormat string `env:"ACCESS_LOG_FORMAT" json:"access_log_format"`
+	// AccessLogSample log every n-th successful request, failed requests are always logged. 0 - all requests
+	AccessLogSample int `env:"ACCESS_LOG_SAMPLE" json:"access_log_sample"`
+	// LogPrivacy privacy mode of logs: ip is truncated to network, user ids are hashed,
+	// LogRedactParams are removed from urls
+	LogPrivacy bool `env:"LOG_PRIVACY" json:"log_privacy"`
+	// LogRedactParams comma separated query params removed from urls in privacy mode. Empty - token,key,sig
+	LogRedactParams string `env:"LOG_REDACT_PARAMS" json:"log_redact_params"`
 	// FileStoragePath - path to the file where storage will save
 	FileStoragePath string `env:"FILE_STORAGE_PATH" json:"file_storage_path"`
 	// DatabaseDSN - dsn for connect ot database
 	DatabaseDSN string `env:"DATABASE_DSN" json:"database_dsn"`
 	// HTTPS use https
 	HTTPS bool `env:"ENABLE_HTTPS" json:"enable_https"`
+	// TLSCertFile path to certificate of https server. It is reloaded on SIGHUP or change of file
+	TLSCertFile string `env:"TLS_CERT_FILE" json:"tls_cert_file"`
+	// TLSKeyFile path to private key of https server
+	TLSKeyFile string `env:"TLS_KEY_FILE" json:"tls_key_file"`
+	// TLSDevCert use self-signed certificate for https without cert files. Only for development
+	TLSDevCert bool `env:"TLS_DEV_CERT" json:"tls_dev_cert"`
+	// TLSDevDir dir where self-signed certificate is kept. Empty - use default dir in cache of user
+	TLSDevDir string `env:"TLS_DEV_DIR" json:"tls_dev_dir"`
+	// HTTPRedirectAddress host:port of plain http listener which redirects to https.
+	// Empty - redirect listener is disabled
+	HTTPRedirectAddress string `env:"HTTP_REDIRECT_ADDRESS" json:"http_redirect_address"`
+	// HSTSMaxAge max-age of Strict-Transport-Security over https. 0 - use default value, negative - header is not sent
+	HSTSMaxAge Duration `env:"HSTS_MAX_AGE" json:"hsts_max_age"`
+	// HSTSIncludeSubdomains add includeSubDomains to Strict-Transport-Security
+	HSTSIncludeSubdomains bool `env:"HSTS_INCLUDE_SUBDOMAINS" json:"hsts_include_subdomains"`
+	// SecurityHeaders policies of security headers by route group
+	SecurityHeaders SecurityHeaders `env:"SECURITY_HEADERS" json:"security_headers"`
 	// ConfigPath path to the config file json
 	ConfigPath string `env:"CONFIG,unset" json:"-"`
 	// TrustedSubnet subnet for internal usage
 	TrustedSubnet TrustedSubnet `env:"TRUSTED_SUBNET" json:"trusted_subnet"`
+	// TrustedProxies subnets of proxies. Ip of client is taken from their headers
+	// X-Forwarded-For and X-Real-IP
+	TrustedProxies TrustedSubnet `env:"TRUSTED_PROXIES" json:"trusted_proxies"`
+	// ACL allowed networks of endpoints stats, debug and admin. Stats without acl uses TrustedSubnet
+	ACL ACL `env:"ACL" json:"acl"`
+	// DeleteWorkers count of goroutines which delete urls in the background.
+	// 0 - use default value
+	DeleteWorkers int `env:"DELETE_WORKERS" json:"delete_workers"`
+	// DeleteQueueSize capacity of the queue for delete requests.
+	// 0 - use default value
+	DeleteQueueSize int `env:"DELETE_QUEUE_SIZE" json:"delete_queue_size"`
+	// DeleteBatchSize max count of urls deleted by one batch.
+	// 0 - use default value
+	DeleteBatchSize int `env:"DELETE_BATCH_SIZE" json:"delete_batch_size"`
+	// DeleteFlushInterval max time while delete request waits in the batch.
+	// 0 - use default value
+	DeleteFlushInterval Duration `env:"DELETE_FLUSH_INTERVAL" json:"delete_flush_interval"`
+	// CacheSize max count of urls in the redirect cache. 0 - cache is disabled
+	CacheSize int `env:"CACHE_SIZE" json:"cache_size"`
+	// CacheTTL time while url lives in the cache. 0 - use default value
+	CacheTTL Duration `env:"CACHE_TTL" json:"cache_ttl"`
+	// CacheNegativeTTL time while unknown url lives in the cache. 0 - use default value
+	CacheNegativeTTL Duration `env:"CACHE_NEGATIVE_TTL" json:"cache_negative_ttl"`
+	// BloomFilter reject unknown urls by bloom filter without request to storage
+	BloomFilter bool `env:"BLOOM_FILTER" json:"bloom_filter"`
+	// BloomCapacity expected count of urls in the bloom filter, it grows with storage. 0 - use default value
+	BloomCapacity uint `env:"BLOOM_CAPACITY" json:"bloom_capacity"`
+	// BloomFPRate expected false positive rate of the bloom filter. 0 - use default value
+	BloomFPRate float64 `env:"BLOOM_FP_RATE" json:"bloom_fp_rate"`
+	// AppEnv environment of the app. In "production" SigningKeys are required
+	AppEnv string `env:"APP_ENV" json:"app_env"`
+	// SigningKeys keys for signing user tokens. The first key signs new tokens
+	SigningKeys SigningKeys `env:"SIGNING_KEYS" json:"signing_keys"`
+	// TokenTTL lifetime of user token. 0 - use default value
+	TokenTTL Duration `env:"TOKEN_TTL" json:"token_ttl"`
+	// TokenRefresh token is reissued when it expires sooner than this. 0 - use default value
+	TokenRefresh Duration `env:"TOKEN_REFRESH" json:"token_refresh"`
+	// TokenGrace expired token is reissued for the same userid during this time. 0 - use default value
+	TokenGrace Duration `env:"TOKEN_GRACE" json:"token_grace"`
+	// LegacyTokens accept tokens userid.signature of old versions. Their secret is public,
+	// so it is not allowed in production
+	LegacyTokens bool `env:"LEGACY_TOKENS" json:"legacy_tokens"`
+	// Admins comma separated logins of accounts with role admin. Logins from the list can not be registered,
+	// admin account is registered before its login is added here
+	Admins string `env:"ADMINS" json:"admins"`
+	// AdminAddress host:port of admin listener with pprof, stats, log level and health.
+	// Empty - admin listener is disabled
+	AdminAddress string `env:"ADMIN_ADDRESS" json:"admin_address"`
+	// AdminUser user of basic auth of admin listener. Empty - basic auth is disabled
+	AdminUser string `env:"ADMIN_USER" json:"admin_user"`
+	// AdminPassword password of basic auth of admin listener
+	AdminPassword string `env:"ADMIN_PASSWORD" json:"admin_password"`
+	// ShutdownDelay time between not ready and stop of servers, load balancers drain traffic in it.
+	// 0 - servers are stopped at once
+	ShutdownDelay Duration `env:"SHUTDOWN_DELAY" json:"shutdown_delay"`
+	// GRPCAddress host:port of grpc server. Empty - port of ServerAddress + 1
+	GRPCAddress string `env:"GRPC_ADDRESS" json:"grpc_address"`
+	// SinglePort serve grpc and http on ServerAddress. grpc uses tls of https server, without https - h2c
+	SinglePort bool `env:"SINGLE_PORT" json:"single_port"`
+	// GRPCTLSCert path to certificate of grpc server. With GRPCTLSKey enables tls of grpc
+	GRPCTLSCert string `env:"GRPC_TLS_CERT" json:"grpc_tls_cert"`
+	// GRPCTLSKey path to private key of grpc server
+	GRPCTLSKey string `env:"GRPC_TLS_KEY" json:"grpc_tls_key"`
+	// GRPCClientCA path to ca of client certificates. Empty - client certificates are not verified
+	GRPCClientCA string `env:"GRPC_CLIENT_CA" json:"grpc_client_ca"`
+	// GRPCRequireClientCert reject grpc clients without certificate signed by GRPCClientCA
+	GRPCRequireClientCert bool `env:"GRPC_REQUIRE_CLIENT_CERT" json:"grpc_require_client_cert"`
+	// ServiceRoles roles of services by identity of client certificate
+	ServiceRoles ServiceRoles `env:"SERVICE_ROLES" json:"service_roles"`
+	// TracingExporter exporter of traces: otlp, stdout or file. Empty - tracing is disabled
+	TracingExporter string `env:"TRACING_EXPORTER" json:"tracing_exporter"`
+	// TracingEndpoint host:port of otlp collector (grpc). Empty - default of otlp exporter
+	TracingEndpoint string `env:"TRACING_ENDPOINT" json:"tracing_endpoint"`
+	// TracingInsecure connect to otlp collector without tls
+	TracingInsecure bool `env:"TRACING_INSECURE" json:"tracing_insecure"`
+	// TracingFile path to file of exporter file
+	TracingFile string `env:"TRACING_FILE" json:"tracing_file"`
+	// TracingSampleRatio part of traces started by the app which are sampled. 0 - all traces
+	TracingSampleRatio float64 `env:"TRACING_SAMPLE_RATIO" json:"tracing_sample_ratio"`
 }
 
 // newConfig create a new *config
@@ -115,17 +258,84 @@ func (c *config) URL() string {
 	return fmt.Sprintf("%s://%s:%d/", proto, c.ServerAddress.Host, c.ServerAddress.Port)
 }
 
+// AdminLogins return logins of admins from Admins
+func (c *config) AdminLogins() []string {
+	result := make([]string, 0)
+	for _, login := range strings.Split(c.Admins, ",") {
+		if login = strings.TrimSpace(login); login != "" {
+			result = append(result, login)
+		}
+	}
+	return result
+}
+
 // InitConfig - initialize config
 func (c *config) InitConfig() error {
 	flag.Var(&c.ServerAddress, "a", "Net address host:port")
 	flag.StringVar(&c.BaseURL, "b", c.BaseURL, "Like http://ya.ru")
 	flag.StringVar(&c.LogLevel, "l", c.LogLevel, "log level")
+	flag.StringVar(&c.LogLevels, "log-levels", c.LogLevels, "levels of components like (storage=debug,grpc=warn)")
+	flag.StringVar(&c.LogEncoding, "log-encoding", c.LogEncoding, "encoding of log (json, console)")
+	flag.StringVar(&c.LogOutput, "log-output", c.LogOutput, "outputs of log like (stderr,/var/log/shortener.log)")
+	flag.IntVar(&c.LogMaxSize, "log-max-size", c.LogMaxSize, "rotate log file at size in megabytes, 0 - disabled")
+	flag.Var(&c.LogRotateInterval, "log-rotate-interval", "rotate log file every interval (24h), 0 - disabled")
+	flag.IntVar(&c.LogMaxBackups, "log-max-backups", c.LogMaxBackups, "count of kept rotated log files, 0 - all")
+	flag.Var(&c.LogMaxAge, "log-max-age", "remove rotated log files older than (168h), 0 - never")
+	flag.BoolVar(&c.LogCompress, "log-compress", c.LogCompress, "compress rotated log files")
+	flag.StringVar(&c.AccessLog, "access-log", c.AccessLog, "outputs of access log like (/var/log/access.log), empty - application log")
+	flag.StringVar(&c.AccessLogFormat, "access-log-format", c.AccessLogFormat, "format of access log (json, console, combined)")
+	flag.BoolVar(&c.LogPrivacy, "log-privacy", c.LogPrivacy, "anonymize ip, user ids and urls in logs")
+	flag.StringVar(&c.LogRedactParams, "log-redact-params", c.LogRedactParams, "query params removed from urls in privacy mode like (token,key,sig)")
+	flag.IntVar(&c.AccessLogSample, "access-log-sample", c.AccessLogSample, "log every n-th successful request (100), 0 - all")
 	flag.StringVar(&c.FileStoragePath, "f", c.FileStoragePath, "path to storage file")
 	flag.StringVar(&c.DatabaseDSN, "d", c.DatabaseDSN, "database dsn")
 	flag.BoolVar(&c.HTTPS, "s", c.HTTPS, "enable https")
+	flag.StringVar(&c.TLSCertFile, "tls-cert", c.TLSCertFile, "path to tls certificate of https server")
+	flag.StringVar(&c.TLSKeyFile, "tls-key", c.TLSKeyFile, "path to tls private key of https server")
+	flag.BoolVar(&c.TLSDevCert, "tls-dev", c.TLSDevCert, "use self-signed certificate for https (development only)")
+	flag.StringVar(&c.TLSDevDir, "tls-dev-dir", c.TLSDevDir, "dir of self-signed certificate")
+	flag.StringVar(&c.HTTPRedirectAddress, "http-redirect-address", c.HTTPRedirectAddress, "address of http listener redirecting to https like (:80)")
+	flag.Var(&c.HSTSMaxAge, "hsts-max-age", "max-age of HSTS (8760h), negative - disabled")
+	flag.BoolVar(&c.HSTSIncludeSubdomains, "hsts-include-subdomains", c.HSTSIncludeSubdomains, "add includeSubDomains to HSTS")
+	flag.Var(&c.SecurityHeaders, "security-headers", `security headers by route group like ({"api":{"csp":"default-src 'none'"}})`)
 	flag.StringVar(&c.ConfigPath, "c", "", "config path")
 	flag.StringVar(&c.ConfigPath, "config", "", "config path")
-	flag.Var(&c.TrustedSubnet, "t", "trusted subnet like (192.168.1.0/24)")
+	flag.Var(&subnetsFlag{tsn: &c.TrustedSubnet}, "t", "trusted subnets like (192.168.1.0/24,fd00::/8), can be repeated")
+	flag.Var(&c.ACL, "acl", "allowed networks of endpoint like (stats=10.0.0.0/8,fd00::/8), can be repeated")
+	flag.Var(&subnetsFlag{tsn: &c.TrustedProxies}, "trusted-proxies", "subnets of trusted proxies like (10.0.0.0/8,127.0.0.1/32)")
+	flag.IntVar(&c.DeleteWorkers, "delete-workers", c.DeleteWorkers, "count of delete workers")
+	flag.IntVar(&c.DeleteQueueSize, "delete-queue-size", c.DeleteQueueSize, "capacity of delete queue")
+	flag.IntVar(&c.DeleteBatchSize, "delete-batch-size", c.DeleteBatchSize, "max urls in one delete batch")
+	flag.Var(&c.DeleteFlushInterval, "delete-flush-interval", "flush interval of delete batch (1s, 500ms)")
+	flag.IntVar(&c.CacheSize, "cache-size", c.CacheSize, "size of redirect cache, 0 - disabled")
+	flag.Var(&c.CacheTTL, "cache-ttl", "ttl of url in redirect cache (1m)")
+	flag.Var(&c.CacheNegativeTTL, "cache-negative-ttl", "ttl of unknown url in redirect cache (10s)")
+	flag.BoolVar(&c.BloomFilter, "bloom-filter", c.BloomFilter, "enable bloom filter of short urls")
+	flag.UintVar(&c.BloomCapacity, "bloom-capacity", c.BloomCapacity, "expected count of urls in bloom filter")
+	flag.Float64Var(&c.BloomFPRate, "bloom-fp-rate", c.BloomFPRate, "false positive rate of bloom filter (0.01)")
+	flag.StringVar(&c.AppEnv, "app-env", c.AppEnv, "environment of app (production)")
+	flag.Var(&c.SigningKeys, "signing-keys", "keys for user tokens like (kid1:secret1,kid2:secret2)")
+	flag.Var(&c.TokenTTL, "token-ttl", "lifetime of user token (720h)")
+	flag.Var(&c.TokenRefresh, "token-refresh", "reissue user token when it expires sooner than this (168h)")
+	flag.Var(&c.TokenGrace, "token-grace", "reissue expired user token for the same userid during this time (168h)")
+	flag.BoolVar(&c.LegacyTokens, "legacy-tokens", c.LegacyTokens, "accept user tokens of old format userid.signature (not in production)")
+	flag.StringVar(&c.Admins, "admins", c.Admins, "logins of admins like (alice,bob)")
+	flag.StringVar(&c.AdminAddress, "admin-address", c.AdminAddress, "address of admin listener like (localhost:8090)")
+	flag.StringVar(&c.AdminUser, "admin-user", c.AdminUser, "user of basic auth of admin listener")
+	flag.StringVar(&c.AdminPassword, "admin-password", c.AdminPassword, "password of basic auth of admin listener")
+	flag.Var(&c.ShutdownDelay, "shutdown-delay", "delay between not ready and stop of servers (5s)")
+	flag.StringVar(&c.GRPCAddress, "grpc-address", c.GRPCAddress, "address of grpc server host:port, default port of -a + 1")
+	flag.BoolVar(&c.SinglePort, "single-port", c.SinglePort, "serve grpc and http on one port")
+	flag.StringVar(&c.GRPCTLSCert, "grpc-tls-cert", c.GRPCTLSCert, "path to certificate of grpc server")
+	flag.StringVar(&c.GRPCTLSKey, "grpc-tls-key", c.GRPCTLSKey, "path to private key of grpc server")
+	flag.StringVar(&c.GRPCClientCA, "grpc-client-ca", c.GRPCClientCA, "path to ca of grpc client certificates")
+	flag.BoolVar(&c.GRPCRequireClientCert, "grpc-require-client-cert", c.GRPCRequireClientCert, "require grpc client certificate")
+	flag.Var(&c.ServiceRoles, "service-roles", "roles of services by client certificate like (billing.internal=stats,ops.internal=admin)")
+	flag.StringVar(&c.TracingExporter, "tracing-exporter", c.TracingExporter, "exporter of traces (otlp, stdout, file)")
+	flag.StringVar(&c.TracingEndpoint, "tracing-endpoint", c.TracingEndpoint, "address of otlp collector like (localhost:4317)")
+	flag.BoolVar(&c.TracingInsecure, "tracing-insecure", c.TracingInsecure, "connect to otlp collector without tls")
+	flag.StringVar(&c.TracingFile, "tracing-file", c.TracingFile, "path to file of exporter file")
+	flag.Float64Var(&c.TracingSampleRatio, "tracing-sample-ratio", c.TracingSampleRatio, "part of sampled traces (0.1)")
 	flag.Parse()
 
 	err := env.ParseWithOptions(
@@ -140,6 +350,38 @@ func (c *config) InitConfig() error {
 					}
 					return tsn, nil
 				},
+				reflect.TypeOf(ACL{}): func(val string) (any, error) {
+					acl := ACL{}
+					err := acl.Set(val)
+					if err != nil {
+						return nil, err
+					}
+					return acl, nil
+				},
+				reflect.TypeOf(ServiceRoles{}): func(val string) (any, error) {
+					sr := ServiceRoles{}
+					err := sr.Set(val)
+					if err != nil {
+						return nil, err
+					}
+					return sr, nil
+				},
+				reflect.TypeOf(SecurityHeaders{}): func(val string) (any, error) {
+					sh := SecurityHeaders{}
+					err := sh.Set(val)
+					if err != nil {
+						return nil, err
+					}
+					return sh, nil
+				},
+				reflect.TypeOf(SigningKeys{}): func(val string) (any, error) {
+					sk := SigningKeys{}
+					err := sk.Set(val)
+					if err != nil {
+						return nil, err
+					}
+					return sk, nil
+				},
 				reflect.TypeOf(ServerAddress{}): func(val string) (any, error) {
 					sa := ServerAddress{}
 					err := sa.Set(val)
@@ -168,9 +410,65 @@ func (c *config) InitConfig() error {
 		}
 		*c = *newconfig
 	}
+
+	if c.IsProduction() && len(c.SigningKeys.Keys) == 0 {
+		return ErrNoSigningKeys
+	}
+	if c.HTTPRedirectAddress != "" && !c.HTTPS {
+		return ErrRedirectWithoutHTTPS
+	}
+	if c.IsProduction() && c.TLSDevCert {
+		return ErrDevCertInProduction
+	}
+	if c.IsProduction() && c.LegacyTokens {
+		return ErrLegacyTokensInProduction
+	}
+	if c.AdminUser != "" && c.AdminPassword == "" {
+		return ErrNoAdminPassword
+	}
+	if c.SinglePort && (c.GRPCAddress != "" || c.GRPCTLS()) {
+		return ErrSinglePortGRPC
+	}
+	if c.SinglePort && c.GRPCRequireClientCert {
+		return ErrSinglePortClientCert
+	}
+	if err := c.checkTracing(); err != nil {
+		return err
+	}
+	if err := c.checkLog(); err != nil {
+		return err
+	}
+	// на одном порту grpc работает с tls https сервера
+	grpcTLS := c.GRPCTLS() || (c.SinglePort && c.HTTPS)
+	if (c.GRPCClientCA != "" && !grpcTLS) || (c.GRPCRequireClientCert && c.GRPCClientCA == "") {
+		return ErrNoGRPCCert
+	}
 	return nil
 }
 
+// TLSFiles certificate and key of https server are set
+func (c *config) TLSFiles() bool {
+	return c.TLSCertFile != "" && c.TLSKeyFile != ""
+}
+
+// GRPCListenAddress address of grpc server: GRPCAddress or port of ServerAddress + 1
+func (c *config) GRPCListenAddress() string {
+	if c.GRPCAddress != "" {
+		return c.GRPCAddress
+	}
+	return net.JoinHostPort(c.ServerAddress.Host, strconv.FormatUint(c.ServerAddress.Port+1, 10))
+}
+
+// GRPCTLS grpc server uses tls
+func (c *config) GRPCTLS() bool {
+	return c.GRPCTLSCert != "" && c.GRPCTLSKey != ""
+}
+
+// IsProduction app works in production environment
+func (c *config) IsProduction() bool {
+	return c.AppEnv == "production"
+}
+
 func configFromFileWithFlags(c *config) (*config, error) {
 	newconfig, err := getConfigFromFile(c.ConfigPath)
 	if err != nil {
@@ -70,10 +70,10 @@ func TestInitConfig(t *testing.T) {
 				DatabaseDSN:     "dsn",
 				HTTPS:           true,
 				TrustedSubnet: TrustedSubnet{
-					Data: &net.IPNet{
+					Data: []*net.IPNet{{
 						IP:   net.IP([]byte{0xc0, 0xa8, 0x01, 0x0}),
 						Mask: net.IPMask([]byte{0xff, 0xff, 0xff, 0x0}),
-					},
+					}},
 				},
 			},
 			envVars: map[string]string{
@@ -100,10 +100,10 @@ func TestInitConfig(t *testing.T) {
 				DatabaseDSN:     "dsn",
 				HTTPS:           true,
 				TrustedSubnet: TrustedSubnet{
-					Data: &net.IPNet{
+					Data: []*net.IPNet{{
 						IP:   net.IP([]byte{0x7f, 0x0, 0x0, 0x0}),
 						Mask: net.IPMask([]byte{0xff, 0xff, 0xff, 0x0}),
-					},
+					}},
 				},
 			},
 			envVars: make(map[string]string),
@@ -130,10 +130,10 @@ func TestInitConfig(t *testing.T) {
 				DatabaseDSN:     "dsn-env",
 				HTTPS:           false,
 				TrustedSubnet: TrustedSubnet{
-					Data: &net.IPNet{
+					Data: []*net.IPNet{{
 						IP:   net.IP([]byte{0xc0, 0xa8, 0x01, 0x0}),
 						Mask: net.IPMask([]byte{0xff, 0xff, 0xff, 0x0}),
-					},
+					}},
 				},
 			},
 			envVars: map[string]string{
@@ -169,10 +169,10 @@ func TestInitConfig(t *testing.T) {
 				HTTPS:           true,
 				ConfigPath:      path.Join(tmpDir, "config1.json"),
 				TrustedSubnet: TrustedSubnet{
-					Data: &net.IPNet{
+					Data: []*net.IPNet{{
 						IP:   net.IP([]byte{0xc0, 0x0, 0x0, 0x0}),
 						Mask: net.IPMask([]byte{0xff, 0xff, 0xff, 0x0}),
-					},
+					}},
 				},
 			},
 			envVars:    make(map[string]string),
@@ -213,10 +213,10 @@ func TestInitConfig(t *testing.T) {
 				HTTPS:           false,
 				ConfigPath:      path.Join(tmpDir, "config3.json"),
 				TrustedSubnet: TrustedSubnet{
-					Data: &net.IPNet{
+					Data: []*net.IPNet{{
 						IP:   net.IP([]byte{0x7f, 0x0, 0x0, 0x0}),
 						Mask: net.IPMask([]byte{0xff, 0xff, 0xff, 0x0}),
-					},
+					}},
 				},
 			},
 			envVars: map[string]string{
@@ -247,10 +247,10 @@ func TestInitConfig(t *testing.T) {
 				HTTPS:           false,
 				ConfigPath:      path.Join(tmpDir, "config4.json"),
 				TrustedSubnet: TrustedSubnet{
-					Data: &net.IPNet{
+					Data: []*net.IPNet{{
 						IP:   net.IP([]byte{0x7f, 0x0, 0x0, 0x0}),
 						Mask: net.IPMask([]byte{0xff, 0xff, 0xff, 0x0}),
-					},
+					}},
 				},
 			},
 			envVars: map[string]string{
@@ -353,6 +353,17 @@ func TestURL(t *testing.T) {
 	}
 }
 
+func TestAdminLogins(t *testing.T) {
+	resetFlags()
+	t.Setenv("ADMINS", " alice,,bob ")
+	os.Args = []string{"cmd"}
+
+	conf := newConfig()
+	require.NoError(t, conf.InitConfig())
+	assert.Equal(t, []string{"alice", "bob"}, conf.AdminLogins())
+	assert.Empty(t, newConfig().AdminLogins())
+}
+
 func Test_getConfigFromFile(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -389,3 +400,123 @@ func Test_getConfigFromFile(t *testing.T) {
 		})
 	}
 }
+
+func TestInitConfig_adminPassword(t *testing.T) {
+	resetFlags()
+	os.Args = []string{"cmd", "-admin-user", "admin"}
+	conf := newConfig()
+	require.ErrorIs(t, conf.InitConfig(), ErrNoAdminPassword)
+}
+
+func TestInitConfig_grpcClientCA(t *testing.T) {
+	resetFlags()
+	os.Args = []string{"cmd", "-grpc-client-ca", "ca.crt"}
+	conf := newConfig()
+	require.ErrorIs(t, conf.InitConfig(), ErrNoGRPCCert)
+}
+
+func TestInitConfig_devCertInProduction(t *testing.T) {
+	resetFlags()
+	os.Args = []string{"cmd", "-tls-dev", "-signing-keys", "k1:secret"}
+	t.Setenv("APP_ENV", "production")
+	conf := newConfig()
+	require.ErrorIs(t, conf.InitConfig(), ErrDevCertInProduction)
+}
+
+func TestInitConfig_singlePort(t *testing.T) {
+	resetFlags()
+	os.Args = []string{"cmd", "-single-port", "-grpc-address", "localhost:9090"}
+	conf := newConfig()
+	require.ErrorIs(t, conf.InitConfig(), ErrSinglePortGRPC)
+}
+
+func TestInitConfig_singlePortClientCert(t *testing.T) {
+	resetFlags()
+	os.Args = []string{"cmd", "-single-port", "-s", "-tls-dev", "-grpc-client-ca", "ca.pem", "-grpc-require-client-cert"}
+	conf := newConfig()
+	require.ErrorIs(t, conf.InitConfig(), ErrSinglePortClientCert)
+}
+
+func TestInitConfig_tracing(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		err  error
+	}{
+		{name: "otlp", args: []string{"-tracing-exporter", "otlp", "-tracing-sample-ratio", "0.1"}},
+		{name: "unknown exporter", args: []string{"-tracing-exporter", "zipkin"}, err: ErrBadTracing},
+		{name: "file without path", args: []string{"-tracing-exporter", "file"}, err: ErrBadTracing},
+		{name: "bad sample ratio", args: []string{"-tracing-sample-ratio", "2"}, err: ErrBadTracing},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resetFlags()
+			os.Args = append([]string{"cmd"}, test.args...)
+			conf := newConfig()
+			err := conf.InitConfig()
+			if test.err != nil {
+				require.ErrorIs(t, err, test.err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestInitConfig_log(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		outputs []string
+		access  []string
+		levels  map[string]string
+		err     error
+	}{
+		{name: "default", outputs: []string{}, access: []string{}, levels: map[string]string{}},
+		{
+			name:    "files",
+			args:    []string{"-log-output", "stderr, app.log", "-access-log", "access.log", "-access-log-format", "combined", "-log-max-size", "100"},
+			outputs: []string{"stderr", "app.log"},
+			access:  []string{"access.log"},
+			levels:  map[string]string{},
+		},
+		{
+			name:    "levels",
+			args:    []string{"-log-levels", "storage=debug, grpc=warn", "-access-log-sample", "100", "-log-privacy"},
+			outputs: []string{},
+			access:  []string{},
+			levels:  map[string]string{"storage": "debug", "grpc": "warn"},
+		},
+		{name: "unknown encoding", args: []string{"-log-encoding", "xml"}, err: ErrBadLog},
+		{name: "combined without access log", args: []string{"-access-log-format", "combined"}, err: ErrBadLog},
+		{name: "unknown access format", args: []string{"-access-log", "stdout", "-access-log-format", "common"}, err: ErrBadLog},
+		{name: "negative size", args: []string{"-log-max-size", "-1"}, err: ErrBadLog},
+		{name: "negative sample", args: []string{"-access-log-sample", "-1"}, err: ErrBadLog},
+		{name: "bad component level", args: []string{"-log-levels", "storage"}, err: ErrBadLog},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resetFlags()
+			os.Args = append([]string{"cmd"}, test.args...)
+			conf := newConfig()
+			err := conf.InitConfig()
+			if test.err != nil {
+				require.ErrorIs(t, err, test.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.outputs, conf.LogOutputs())
+			assert.Equal(t, test.access, conf.AccessLogOutputs())
+			levels, err := conf.ComponentLogLevels()
+			require.NoError(t, err)
+			assert.Equal(t, test.levels, levels)
+		})
+	}
+}
+
+func TestGRPCListenAddress(t *testing.T) {
+	conf := newConfig()
+	assert.Equal(t, "localhost:8081", conf.GRPCListenAddress())
+	conf.GRPCAddress = ":9090"
+	assert.Equal(t, ":9090", conf.GRPCListenAddress())
+}
@@ -0,0 +1,33 @@
+package config
+
+import (
+	"time"
+)
+
+// Duration own type time.Duration. Can be set as "1s", "500ms" in env, flags and json
+type Duration time.Duration
+
+// String flag.Value interface for type Duration
+func (d *Duration) String() string {
+	return time.Duration(*d).String()
+}
+
+// Set flag.Value interface for type Duration
+func (d *Duration) Set(val string) error {
+	v, err := time.ParseDuration(val)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// UnmarshalText for env and json
+func (d *Duration) UnmarshalText(text []byte) error {
+	return d.Set(string(text))
+}
+
+// Duration return value as time.Duration
+func (d Duration) Duration() time.Duration {
+	return time.Duration(d)
+}
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Encodings of logs
+const (
+	LogJSON    = "json"
+	LogConsole = "console"
+	// LogCombined Combined Log Format of apache/nginx, only for access log
+	LogCombined = "combined"
+)
+
+// ErrBadLog bad settings of logs
+var ErrBadLog = errors.New("bad log config")
+
+// checkLog check encoding, outputs and rotation of logs
+func (c *config) checkLog() error {
+	switch c.LogEncoding {
+	case "", LogJSON, LogConsole:
+	default:
+		return fmt.Errorf("%w: unknown encoding %q", ErrBadLog, c.LogEncoding)
+	}
+	switch c.AccessLogFormat {
+	case "", LogJSON, LogConsole:
+	case LogCombined:
+		if c.AccessLog == "" {
+			return fmt.Errorf("%w: format combined requires access log", ErrBadLog)
+		}
+	default:
+		return fmt.Errorf("%w: unknown access log format %q", ErrBadLog, c.AccessLogFormat)
+	}
+	if c.LogMaxSize < 0 || c.LogMaxBackups < 0 || c.LogMaxAge < 0 || c.LogRotateInterval < 0 {
+		return fmt.Errorf("%w: rotation settings must not be negative", ErrBadLog)
+	}
+	if c.AccessLogSample < 0 {
+		return fmt.Errorf("%w: access log sample must not be negative", ErrBadLog)
+	}
+	if _, err := c.ComponentLogLevels(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ComponentLogLevels return levels of components from LogLevels
+func (c *config) ComponentLogLevels() (map[string]string, error) {
+	levels := make(map[string]string)
+	for _, item := range splitList(c.LogLevels) {
+		name, level, ok := strings.Cut(item, "=")
+		name, level = strings.TrimSpace(name), strings.TrimSpace(level)
+		if !ok || name == "" || level == "" {
+			return nil, fmt.Errorf("%w: bad level of component %q", ErrBadLog, item)
+		}
+		levels[name] = level
+	}
+	return levels, nil
+}
+
+// LogOutputs return outputs of application log from LogOutput
+func (c *config) LogOutputs() []string {
+	return splitList(c.LogOutput)
+}
+
+// AccessLogOutputs return outputs of access log from AccessLog. Empty - application log is used
+func (c *config) AccessLogOutputs() []string {
+	return splitList(c.AccessLog)
+}
+
+// LogRedactParamList return query params removed from urls in privacy mode from LogRedactParams
+func (c *config) LogRedactParamList() []string {
+	return splitList(c.LogRedactParams)
+}
+
+// splitList split comma separated list and skip empty items
+func splitList(list string) []string {
+	result := make([]string, 0)
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
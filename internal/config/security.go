@@ -0,0 +1,81 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// Route groups with own policy of security headers
+const (
+	// SecurityRedirect redirect by short url
+	SecurityRedirect = "redirect"
+	// SecurityAPI other public routes
+	SecurityAPI = "api"
+	// SecurityAdmin /api/admin
+	SecurityAdmin = "admin"
+)
+
+// securityGroups route groups which can have policy
+var securityGroups = []string{SecurityRedirect, SecurityAPI, SecurityAdmin}
+
+// ErrParseSecurityHeaders error for bad policies of security headers
+var ErrParseSecurityHeaders = errors.New("bad security headers")
+
+// ErrRedirectWithoutHTTPS redirect listener works only with https
+var ErrRedirectWithoutHTTPS = errors.New("http redirect address requires https")
+
+// SecurityPolicy security headers of route group. Empty value - use default, "-" - header is not sent
+type SecurityPolicy struct {
+	// ReferrerPolicy value of Referrer-Policy for redirects
+	ReferrerPolicy string `json:"referrer_policy,omitempty"`
+	// CSP value of Content-Security-Policy for html pages
+	CSP string `json:"csp,omitempty"`
+}
+
+// SecurityHeaders policies by route group. Value is json like {"api":{"csp":"default-src 'none'"}}
+type SecurityHeaders struct {
+	Groups map[string]SecurityPolicy `env:"-" json:"-"`
+}
+
+// String flag.Value interface for type SecurityHeaders
+func (sh *SecurityHeaders) String() string {
+	if len(sh.Groups) == 0 {
+		return ""
+	}
+	data, err := json.Marshal(sh.Groups)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// Set flag.Value interface for type SecurityHeaders. Policies of groups are replaced
+func (sh *SecurityHeaders) Set(val string) error {
+	var groups map[string]SecurityPolicy
+	if err := json.Unmarshal([]byte(val), &groups); err != nil {
+		return fmt.Errorf("%w: %w", ErrParseSecurityHeaders, err)
+	}
+	for name, policy := range groups {
+		if !slices.Contains(securityGroups, name) {
+			return fmt.Errorf("%w: unknown route group %q", ErrParseSecurityHeaders, name)
+		}
+		if sh.Groups == nil {
+			sh.Groups = make(map[string]SecurityPolicy)
+		}
+		sh.Groups[name] = policy
+	}
+	return nil
+}
+
+// UnmarshalJSON for SecurityHeaders. Value is object like in Set
+func (sh *SecurityHeaders) UnmarshalJSON(data []byte) error {
+	*sh = SecurityHeaders{}
+	return sh.Set(string(data))
+}
+
+// Get return policy of route group. Empty policy if group has no policy
+func (sh *SecurityHeaders) Get(group string) SecurityPolicy {
+	return sh.Groups[group]
+}
@@ -0,0 +1,26 @@
+package config
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	var sh SecurityHeaders
+	require.NoError(t, sh.Set(`{"redirect":{"referrer_policy":"origin"}}`))
+	require.NoError(t, sh.Set(`{"api":{"csp":"default-src 'self'"}}`))
+	assert.Equal(t, SecurityPolicy{ReferrerPolicy: "origin"}, sh.Get(SecurityRedirect))
+	assert.Equal(t, SecurityPolicy{CSP: "default-src 'self'"}, sh.Get(SecurityAPI))
+	assert.Equal(t, SecurityPolicy{}, sh.Get(SecurityAdmin))
+
+	for _, bad := range []string{`{"unknown":{}}`, `not json`} {
+		assert.ErrorIs(t, (&SecurityHeaders{}).Set(bad), ErrParseSecurityHeaders, bad)
+	}
+
+	sh = SecurityHeaders{}
+	require.NoError(t, json.Unmarshal([]byte(`{"admin":{"csp":"-"}}`), &sh))
+	assert.Equal(t, `{"admin":{"csp":"-"}}`, sh.String())
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// ErrParseServiceRoles error for bad roles of services
+var ErrParseServiceRoles = errors.New("bad service roles")
+
+// ErrNoGRPCCert client certificates are verified only over tls with client ca
+var ErrNoGRPCCert = errors.New("grpc client certificates require client ca and grpc tls cert and key or https in single port mode")
+
+// ServiceRoles roles of internal services by identity from client certificate.
+// Identity is uri or dns from SAN or common name of subject
+type ServiceRoles struct {
+	Roles map[string]string `env:"-" json:"-"`
+}
+
+// String flag.Value interface for type ServiceRoles
+func (sr *ServiceRoles) String() string {
+	items := make([]string, 0, len(sr.Roles))
+	for _, name := range slices.Sorted(maps.Keys(sr.Roles)) {
+		items = append(items, name+"="+sr.Roles[name])
+	}
+	return strings.Join(items, ",")
+}
+
+// add add role of service
+func (sr *ServiceRoles) add(name, role string) error {
+	if name == "" || role == "" {
+		return fmt.Errorf("%w: want identity=role", ErrParseServiceRoles)
+	}
+	if sr.Roles == nil {
+		sr.Roles = make(map[string]string)
+	}
+	sr.Roles[name] = role
+	return nil
+}
+
+// Set flag.Value interface for type ServiceRoles. Value like (billing.internal=stats,spiffe://prod/ops=admin)
+func (sr *ServiceRoles) Set(val string) error {
+	for _, item := range strings.Split(val, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		// в uri может быть '=', роль берем после последнего
+		i := strings.LastIndex(item, "=")
+		if i < 0 {
+			return fmt.Errorf("%w: want identity=role, got %q", ErrParseServiceRoles, item)
+		}
+		if err := sr.add(strings.TrimSpace(item[:i]), strings.TrimSpace(item[i+1:])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UnmarshalJSON for ServiceRoles. Value is object identity to role
+func (sr *ServiceRoles) UnmarshalJSON(data []byte) error {
+	var roles map[string]string
+	if err := json.Unmarshal(data, &roles); err != nil {
+		return err
+	}
+	*sr = ServiceRoles{}
+	for name, role := range roles {
+		if err := sr.add(name, role); err != nil {
+			return err
+		}
+	}
+	return nil
+}
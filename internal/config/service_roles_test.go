@@ -0,0 +1,24 @@
+package config
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceRoles(t *testing.T) {
+	var sr ServiceRoles
+	require.NoError(t, sr.Set("billing.internal=stats, spiffe://prod/ops?a=b=admin"))
+	assert.Equal(t, map[string]string{"billing.internal": "stats", "spiffe://prod/ops?a=b": "admin"}, sr.Roles)
+	assert.Equal(t, "billing.internal=stats,spiffe://prod/ops?a=b=admin", sr.String())
+
+	for _, bad := range []string{"billing", "=stats", "billing="} {
+		assert.ErrorIs(t, (&ServiceRoles{}).Set(bad), ErrParseServiceRoles, bad)
+	}
+
+	sr = ServiceRoles{}
+	require.NoError(t, json.Unmarshal([]byte(`{"ops.internal":"admin"}`), &sr))
+	assert.Equal(t, map[string]string{"ops.internal": "admin"}, sr.Roles)
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrParseSigningKeys error for parse error
+var ErrParseSigningKeys = errors.New("bad signing keys")
+
+// ErrNoSigningKeys signing keys are required in production
+var ErrNoSigningKeys = errors.New("signing keys are required in production")
+
+// SigningKey secret for signing user tokens. ID is saved in the token
+type SigningKey struct {
+	ID     string
+	Secret string
+}
+
+// SigningKeys list of keys like "kid1:secret1,kid2:secret2".
+// The first key signs new tokens, all keys verify tokens
+type SigningKeys struct {
+	Keys []SigningKey `env:"-" json:"-"`
+}
+
+// String flag.Value interface for type SigningKeys. Secrets are hidden
+func (sk *SigningKeys) String() string {
+	ids := make([]string, 0, len(sk.Keys))
+	for _, key := range sk.Keys {
+		ids = append(ids, key.ID+":***")
+	}
+	return strings.Join(ids, ",")
+}
+
+// Set flag.Value interface for type SigningKeys
+func (sk *SigningKeys) Set(val string) error {
+	keys := make([]SigningKey, 0)
+	seen := make(map[string]struct{})
+	for _, item := range strings.Split(val, ",") {
+		id, secret, ok := strings.Cut(strings.TrimSpace(item), ":")
+		if !ok || id == "" || secret == "" {
+			return fmt.Errorf("%w: want kid:secret, got %q", ErrParseSigningKeys, item)
+		}
+		// kid входит в токен, разделитель в нем недопустим
+		if strings.Contains(id, ".") {
+			return fmt.Errorf("%w: kid %q contains '.'", ErrParseSigningKeys, id)
+		}
+		if _, ok := seen[id]; ok {
+			return fmt.Errorf("%w: duplicate kid %q", ErrParseSigningKeys, id)
+		}
+		seen[id] = struct{}{}
+		keys = append(keys, SigningKey{ID: id, Secret: secret})
+	}
+	*sk = SigningKeys{Keys: keys}
+	return nil
+}
+
+// UnmarshalJSON for SigningKeys
+func (sk *SigningKeys) UnmarshalJSON(data []byte) error {
+	var s string
+	err := json.Unmarshal(data, &s)
+	if err != nil {
+		return err
+	}
+
+	return sk.Set(s)
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSigningKeys_Set(t *testing.T) {
+	tests := []struct {
+		name   string
+		val    string
+		expect []SigningKey
+		err    bool
+	}{
+		{
+			name:   "one key",
+			val:    "k1:secret1",
+			expect: []SigningKey{{ID: "k1", Secret: "secret1"}},
+		},
+		{
+			name:   "rotation",
+			val:    "k2:secret2, k1:secret:with:colons",
+			expect: []SigningKey{{ID: "k2", Secret: "secret2"}, {ID: "k1", Secret: "secret:with:colons"}},
+		},
+		{name: "no secret", val: "k1", err: true},
+		{name: "empty secret", val: "k1:", err: true},
+		{name: "empty kid", val: ":secret", err: true},
+		{name: "dot in kid", val: "k.1:secret", err: true},
+		{name: "duplicate kid", val: "k1:a,k1:b", err: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sk := SigningKeys{}
+			err := sk.Set(test.val)
+			if test.err {
+				assert.ErrorIs(t, err, ErrParseSigningKeys)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expect, sk.Keys)
+			assert.NotContains(t, sk.String(), "secret")
+		})
+	}
+}
+
+func TestInitConfig_production(t *testing.T) {
+	resetFlags()
+	os.Args = []string{"cmd"}
+	t.Setenv("APP_ENV", "production")
+	conf := newConfig()
+	require.ErrorIs(t, conf.InitConfig(), ErrNoSigningKeys)
+
+	resetFlags()
+	t.Setenv("SIGNING_KEYS", "k2:secret2,k1:secret1")
+	conf = newConfig()
+	require.NoError(t, conf.InitConfig())
+	assert.Equal(t, []SigningKey{{ID: "k2", Secret: "secret2"}, {ID: "k1", Secret: "secret1"}}, conf.SigningKeys.Keys)
+
+	// секрет старых токенов публичный
+	resetFlags()
+	t.Setenv("LEGACY_TOKENS", "true")
+	conf = newConfig()
+	require.ErrorIs(t, conf.InitConfig(), ErrLegacyTokensInProduction)
+}
@@ -0,0 +1,33 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Exporters of traces
+const (
+	TracingOTLP   = "otlp"
+	TracingStdout = "stdout"
+	TracingFile   = "file"
+)
+
+// ErrBadTracing bad settings of tracing
+var ErrBadTracing = errors.New("bad tracing config")
+
+// checkTracing check exporter of traces and its settings
+func (c *config) checkTracing() error {
+	switch c.TracingExporter {
+	case "", TracingOTLP, TracingStdout:
+	case TracingFile:
+		if c.TracingFile == "" {
+			return fmt.Errorf("%w: exporter file requires tracing file", ErrBadTracing)
+		}
+	default:
+		return fmt.Errorf("%w: unknown exporter %q", ErrBadTracing, c.TracingExporter)
+	}
+	if c.TracingSampleRatio < 0 || c.TracingSampleRatio > 1 {
+		return fmt.Errorf("%w: sample ratio must be in [0, 1]", ErrBadTracing)
+	}
+	return nil
+}
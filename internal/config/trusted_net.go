@@ -5,34 +5,74 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"strings"
 )
 
 // ErrParseCIDR error for parse error
 var ErrParseCIDR = errors.New("bad trusted subnet")
 
-// TrustedSubnet own type net.IPNet
+// TrustedSubnet list of subnets, IPv4 and IPv6
 type TrustedSubnet struct {
-	Data *net.IPNet `env:"-" json:"-"`
+	Data []*net.IPNet `env:"-" json:"-"`
 }
 
 // String flag.Value interface for type TrustedSubnet
 func (tsn *TrustedSubnet) String() string {
-	n := (*net.IPNet)(tsn.Data)
-	return n.String()
+	items := make([]string, len(tsn.Data))
+	for i, n := range tsn.Data {
+		items[i] = n.String()
+	}
+	return strings.Join(items, ",")
 }
 
-// Set flag.Value interface for type TrustedSubnet
+// Set flag.Value interface for type TrustedSubnet. Value is comma separated list of cidr.
+// Subnets are added to already set ones, so flag can be repeated
 func (tsn *TrustedSubnet) Set(val string) error {
-	_, net, err := net.ParseCIDR(val)
-	if err != nil {
-		return fmt.Errorf("%w: %w", ErrParseCIDR, err)
+	for _, item := range strings.Split(val, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		_, n, err := net.ParseCIDR(item)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrParseCIDR, err)
+		}
+		tsn.Data = append(tsn.Data, n)
 	}
-	*tsn = TrustedSubnet{Data: net}
 	return nil
 }
 
-// UnmarshalJSON for TrustedSubnet
+// subnetsFlag flag.Value for TrustedSubnet. The first flag replaces value from config file,
+// repeated flags add subnets
+type subnetsFlag struct {
+	tsn *TrustedSubnet
+	set bool
+}
+
+// String flag.Value interface for type subnetsFlag
+func (f *subnetsFlag) String() string {
+	if f.tsn == nil {
+		return ""
+	}
+	return f.tsn.String()
+}
+
+// Set flag.Value interface for type subnetsFlag
+func (f *subnetsFlag) Set(val string) error {
+	if !f.set {
+		f.tsn.Data = nil
+		f.set = true
+	}
+	return f.tsn.Set(val)
+}
+
+// UnmarshalJSON for TrustedSubnet. Value is string or array of strings
 func (tsn *TrustedSubnet) UnmarshalJSON(data []byte) error {
+	*tsn = TrustedSubnet{}
+	var list []string
+	if err := json.Unmarshal(data, &list); err == nil {
+		return tsn.Set(strings.Join(list, ","))
+	}
 	var s string
 	err := json.Unmarshal(data, &s)
 	if err != nil {
@@ -42,11 +82,16 @@ func (tsn *TrustedSubnet) UnmarshalJSON(data []byte) error {
 	return tsn.Set(s)
 }
 
-// IsTrusted check ip in trusted net
+// IsTrusted check ip in one of trusted nets
 func (tsn *TrustedSubnet) IsTrusted(ip string) bool {
-	if tsn.Data == nil {
+	pip := net.ParseIP(ip)
+	if pip == nil {
 		return false
 	}
-	pip := net.ParseIP(ip)
-	return pip != nil && tsn.Data.Contains(pip)
+	for _, n := range tsn.Data {
+		if n.Contains(pip) {
+			return true
+		}
+	}
+	return false
 }
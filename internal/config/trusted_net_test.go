@@ -1,10 +1,13 @@
 package config
 
 import (
+	"encoding/json"
 	"net"
+	"os"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestIsTrusted(t *testing.T) {
@@ -36,10 +39,10 @@ func TestIsTrusted(t *testing.T) {
 		{
 			name: "trusted net defined. ip empty",
 			trustedSubNet: TrustedSubnet{
-				Data: &net.IPNet{
+				Data: []*net.IPNet{{
 					IP:   net.IP([]byte{0x7f, 0x0, 0x0, 0x0}),
 					Mask: net.IPMask([]byte{0xff, 0xff, 0xff, 0x0}),
-				},
+				}},
 			},
 			ip:        "",
 			isTrusted: false,
@@ -47,10 +50,10 @@ func TestIsTrusted(t *testing.T) {
 		{
 			name: "trusted net defined. ip not valid",
 			trustedSubNet: TrustedSubnet{
-				Data: &net.IPNet{
+				Data: []*net.IPNet{{
 					IP:   net.IP([]byte{0x7f, 0x0, 0x0, 0x0}),
 					Mask: net.IPMask([]byte{0xff, 0xff, 0xff, 0x0}),
-				},
+				}},
 			},
 			ip:        "not valid",
 			isTrusted: false,
@@ -58,10 +61,10 @@ func TestIsTrusted(t *testing.T) {
 		{
 			name: "trusted net defined. ip valid not trusted",
 			trustedSubNet: TrustedSubnet{
-				Data: &net.IPNet{
+				Data: []*net.IPNet{{
 					IP:   net.IP([]byte{0x7f, 0x0, 0x0, 0x0}),
 					Mask: net.IPMask([]byte{0xff, 0xff, 0xff, 0x0}),
-				},
+				}},
 			},
 			ip:        "192.168.0.1",
 			isTrusted: false,
@@ -69,10 +72,10 @@ func TestIsTrusted(t *testing.T) {
 		{
 			name: "trusted net defined. ip valid and trusted",
 			trustedSubNet: TrustedSubnet{
-				Data: &net.IPNet{
+				Data: []*net.IPNet{{
 					IP:   net.IP([]byte{0x7f, 0x0, 0x0, 0x0}),
 					Mask: net.IPMask([]byte{0xff, 0xff, 0xff, 0x0}),
-				},
+				}},
 			},
 			ip:        "127.0.0.0",
 			isTrusted: true,
@@ -85,3 +88,36 @@ func TestIsTrusted(t *testing.T) {
 		})
 	}
 }
+
+func TestTrustedSubnet_list(t *testing.T) {
+	var tsn TrustedSubnet
+	require.NoError(t, tsn.Set("10.0.0.0/8, 192.168.1.0/24,"))
+	require.NoError(t, tsn.Set("fd00::/8"))
+	assert.Equal(t, "10.0.0.0/8,192.168.1.0/24,fd00::/8", tsn.String())
+	assert.True(t, tsn.IsTrusted("10.1.2.3"))
+	assert.True(t, tsn.IsTrusted("192.168.1.10"))
+	assert.True(t, tsn.IsTrusted("fd00::1"))
+	assert.False(t, tsn.IsTrusted("192.168.2.10"))
+	assert.ErrorIs(t, tsn.Set("10.0.0.0/8,bad"), ErrParseCIDR)
+
+	require.NoError(t, json.Unmarshal([]byte(`["127.0.0.1/32","::1/128"]`), &tsn))
+	assert.Equal(t, "127.0.0.1/32,::1/128", tsn.String())
+	require.NoError(t, json.Unmarshal([]byte(`"127.0.0.1/32"`), &tsn))
+	assert.Equal(t, "127.0.0.1/32", tsn.String())
+}
+
+func TestTrustedSubnet_flags(t *testing.T) {
+	resetFlags()
+	os.Args = []string{"cmd", "-t", "10.0.0.0/8", "-t", "fd00::/8,192.168.1.0/24"}
+	conf := newConfig()
+	require.NoError(t, conf.InitConfig())
+	assert.Equal(t, "10.0.0.0/8,fd00::/8,192.168.1.0/24", conf.TrustedSubnet.String())
+
+	resetFlags()
+	t.Setenv("TRUSTED_SUBNET", "10.0.0.0/8,fd00::/8")
+	os.Args = []string{"cmd", "-t", "192.168.1.0/24"}
+	conf = newConfig()
+	require.NoError(t, conf.InitConfig())
+	// env приоритетнее флагов
+	assert.Equal(t, "10.0.0.0/8,fd00::/8", conf.TrustedSubnet.String())
+}
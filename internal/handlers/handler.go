@@ -5,8 +5,11 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -16,21 +19,25 @@ import (
 	"github.com/serg2014/shortener/internal/auth"
 	"github.com/serg2014/shortener/internal/logger"
 	"github.com/serg2014/shortener/internal/models"
-	"github.com/serg2014/shortener/internal/storage"
+	"github.com/serg2014/shortener/storage"
 )
 
 // createURL auxiliary function for reduce copy paste
 func createURL(ctx context.Context, a *app.MyApp, origURL string, userID auth.UserID, w http.ResponseWriter) (int, string, error) {
 	if origURL == "" {
-		logger.Log.Debug("empty url")
+		logger.FromContext(ctx).Debug("empty url")
 		http.Error(w, "empty url", http.StatusBadRequest)
 		return 0, "", errors.New("empty url")
 	}
 	status := http.StatusCreated
 	shortURL, err := a.GenerateShortURL(ctx, origURL, userID)
 	if err != nil {
+		if errors.Is(err, app.ErrUserBanned) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return 0, "", err
+		}
 		if !errors.Is(err, storage.ErrConflict) {
-			logger.Log.Error("can not generate short", zap.Error(err))
+			logger.FromContext(ctx).Error("can not generate short", zap.Error(err))
 			code := http.StatusInternalServerError
 			http.Error(w, http.StatusText(code), code)
 			return 0, "", err
@@ -40,25 +47,44 @@ func createURL(ctx context.Context, a *app.MyApp, origURL string, userID auth.Us
 	return status, shortURL, nil
 }
 
+// RetryAfter return value for header Retry-After in seconds. At least 1 second
+func RetryAfter(d time.Duration) string {
+	sec := int64(math.Ceil(d.Seconds()))
+	if sec < 1 {
+		sec = 1
+	}
+	return strconv.FormatInt(sec, 10)
+}
+
 // noUser auxiliary function return StatusInternalServerError with body `no user`
-func noUser(w http.ResponseWriter, err error) {
-	logger.Log.Error("can not find userid", zap.Error(err))
+func noUser(w http.ResponseWriter, r *http.Request, err error) {
+	logger.FromContext(r.Context()).Error("can not find userid", zap.Error(err))
 	http.Error(w, "no user", http.StatusInternalServerError)
 }
 
+// notSupported write 501 if storage does not support the api
+func notSupported(w http.ResponseWriter, err error) bool {
+	if !errors.Is(err, storage.ErrNotSupported) {
+		return false
+	}
+	code := http.StatusNotImplemented
+	http.Error(w, http.StatusText(code), code)
+	return true
+}
+
 // CreateURL handler for genereate short url
 func CreateURL(a *app.MyApp) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "text/plain")
 		origURL, err := io.ReadAll(r.Body)
 		if err != nil {
-			logger.Log.Error("bad input", zap.Error(err))
+			logger.FromContext(r.Context()).Error("bad input", zap.Error(err))
 			http.Error(w, "bad input", http.StatusBadRequest)
 			return
 		}
 		userID, err := auth.GetUserID(r.Context())
 		if err != nil {
-			noUser(w, err)
+			noUser(w, r, err)
 			return
 		}
 		status, shortURL, err := createURL(r.Context(), a, string(origURL), userID, w)
@@ -77,13 +103,13 @@ func CreateURLJson(a *app.MyApp) http.HandlerFunc {
 		var req models.Request
 		dec := json.NewDecoder(r.Body)
 		if err := dec.Decode(&req); err != nil {
-			logger.Log.Debug("cannot decode request JSON body", zap.Error(err))
+			logger.FromContext(r.Context()).Debug("cannot decode request JSON body", zap.Error(err))
 			http.Error(w, "bad json", http.StatusBadRequest)
 			return
 		}
 		userID, err := auth.GetUserID(r.Context())
 		if err != nil {
-			noUser(w, err)
+			noUser(w, r, err)
 			return
 		}
 		status, shortURL, err := createURL(r.Context(), a, req.URL, userID, w)
@@ -103,7 +129,7 @@ func CreateURLJson(a *app.MyApp) http.HandlerFunc {
 		// а тело будет битым. возможно стоит сначала сериализовать. данных мало поэтому кажется ок
 		enc := json.NewEncoder(w)
 		if err := enc.Encode(resp); err != nil {
-			logger.Log.Error("error encoding response", zap.Error(err))
+			logger.FromContext(r.Context()).Error("error encoding response", zap.Error(err))
 			return
 		}
 	}
@@ -117,17 +143,25 @@ func GetURL(a *app.MyApp) http.HandlerFunc {
 		var code int
 		if err != nil {
 			switch {
-			case errors.Is(err, storage.ErrDeleted):
+			case errors.Is(err, storage.ErrDeleted), errors.Is(err, storage.ErrDisabled):
 				code = http.StatusGone
+			case errors.Is(err, storage.ErrDisabledLegal):
+				code = http.StatusUnavailableForLegalReasons
 			default:
 				code = http.StatusInternalServerError
 			}
-			logger.Log.Error("error in a.Get", zap.Error(err))
+			// удаленные и отключенные ссылки - обычная ситуация, а не ошибка сервиса
+			if code == http.StatusInternalServerError {
+				logger.FromContext(r.Context()).Error("error in a.Get", zap.Error(err))
+			} else {
+				logger.FromContext(r.Context()).Debug("short url is gone", zap.Error(err))
+			}
 			http.Error(w, http.StatusText(code), code)
 			return
 		}
 		if !ok {
-			http.Error(w, "bad short url", http.StatusBadRequest)
+			code = http.StatusNotFound
+			http.Error(w, http.StatusText(code), code)
 			return
 		}
 		w.Header().Set("Content-Type", "text/plain")
@@ -135,13 +169,61 @@ func GetURL(a *app.MyApp) http.HandlerFunc {
 	}
 }
 
+// Healthz handler liveness of app. Does not check storage
+func Healthz() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("OK"))
+	}
+}
+
+// Readyz handler readiness of app. 503 if app is not ready, see app.Ready
+func Readyz(a *app.MyApp) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 1*time.Second)
+		defer cancel()
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if err := a.Ready(ctx); err != nil {
+			logger.FromContext(r.Context()).Warn("not ready", zap.Error(err))
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(notReadyReason(err)))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("OK"))
+	}
+}
+
+// notReadyReason short reason of not ready app without details of storage
+func notReadyReason(err error) string {
+	for _, known := range []error{app.ErrShuttingDown, app.ErrDeleteQueueSaturated, storage.ErrMigrations} {
+		if errors.Is(err, known) {
+			return known.Error()
+		}
+	}
+	return "storage is unreachable"
+}
+
+// Version handler return build info of app
+func Version(info models.BuildInfo) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(info); err != nil {
+			logger.FromContext(r.Context()).Error("error encoding response", zap.Error(err))
+			return
+		}
+	}
+}
+
 // Ping handler ping db
 func Ping(a *app.MyApp) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx, cancel := context.WithTimeout(r.Context(), 1*time.Second)
 		defer cancel()
 		if err := a.Ping(ctx); err != nil {
-			logger.Log.Error("ping", zap.Error(err))
+			logger.FromContext(r.Context()).Error("ping", zap.Error(err))
 			code := http.StatusInternalServerError
 			http.Error(w, http.StatusText(code), code)
 			return
@@ -155,21 +237,25 @@ func CreateURLBatch(a *app.MyApp) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userID, err := auth.GetUserID(r.Context())
 		if err != nil {
-			noUser(w, err)
+			noUser(w, r, err)
 			return
 		}
 
 		var req models.RequestBatch
 		dec := json.NewDecoder(r.Body)
 		if err := dec.Decode(&req); err != nil {
-			logger.Log.Debug("cannot decode request JSON body", zap.Error(err))
+			logger.FromContext(r.Context()).Debug("cannot decode request JSON body", zap.Error(err))
 			http.Error(w, "bad json", http.StatusBadRequest)
 			return
 		}
 		// TODO проверить что прислали урл. correlation_id должен быть уникальным
 		resp, err := a.GenerateShortURLBatch(r.Context(), req, userID)
 		if err != nil {
-			logger.Log.Error(
+			if errors.Is(err, app.ErrUserBanned) {
+				http.Error(w, err.Error(), http.StatusForbidden)
+				return
+			}
+			logger.FromContext(r.Context()).Error(
 				"can not generate short batch",
 				zap.Error(err),
 			)
@@ -184,7 +270,7 @@ func CreateURLBatch(a *app.MyApp) http.HandlerFunc {
 		// сериализуем ответ сервера
 		enc := json.NewEncoder(w)
 		if err := enc.Encode(resp); err != nil {
-			logger.Log.Error("error encoding response", zap.Error(err))
+			logger.FromContext(r.Context()).Error("error encoding response", zap.Error(err))
 			return
 		}
 	}
@@ -195,12 +281,12 @@ func GetUserURLS(a *app.MyApp) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userID, err := auth.GetUserID(r.Context())
 		if err != nil {
-			noUser(w, err)
+			noUser(w, r, err)
 			return
 		}
 		data, err := a.GetUserURLS(r.Context(), userID)
 		if err != nil {
-			logger.Log.Error("GetUserURLS", zap.Error(err))
+			logger.FromContext(r.Context()).Error("GetUserURLS", zap.Error(err))
 			code := http.StatusInternalServerError
 			http.Error(w, http.StatusText(code), code)
 			return
@@ -217,33 +303,158 @@ func GetUserURLS(a *app.MyApp) http.HandlerFunc {
 		// сериализуем ответ сервера
 		enc := json.NewEncoder(w)
 		if err := enc.Encode(data); err != nil {
-			logger.Log.Error("error encoding response", zap.Error(err))
+			logger.FromContext(r.Context()).Error("error encoding response", zap.Error(err))
 			return
 		}
 	}
 }
 
+// GetUserToken handler return new token of the user. Use it in header Authorization: Bearer
+func GetUserToken() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := auth.GetUserID(r.Context())
+		if err != nil {
+			noUser(w, r, err)
+			return
+		}
+		token, expiresAt := auth.IssueToken(userID)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		enc := json.NewEncoder(w)
+		if err := enc.Encode(models.ResponseToken{Token: token, ExpiresAt: expiresAt}); err != nil {
+			logger.FromContext(r.Context()).Error("error encoding response", zap.Error(err))
+			return
+		}
+	}
+}
+
+// CreateAPIKey handler create api key of user. Key is returned only once
+func CreateAPIKey(a *app.MyApp) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := auth.GetUserID(r.Context())
+		if err != nil {
+			noUser(w, r, err)
+			return
+		}
+		var req models.RequestAPIKey
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.FromContext(r.Context()).Debug("cannot decode request JSON body", zap.Error(err))
+			http.Error(w, "bad json", http.StatusBadRequest)
+			return
+		}
+		resp, err := a.CreateAPIKey(r.Context(), userID, req)
+		if notSupported(w, err) {
+			return
+		}
+		if err != nil {
+			if errors.Is(err, app.ErrBadAPIKeyRequest) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			logger.FromContext(r.Context()).Error("CreateAPIKey", zap.Error(err))
+			code := http.StatusInternalServerError
+			http.Error(w, http.StatusText(code), code)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		enc := json.NewEncoder(w)
+		if err := enc.Encode(resp); err != nil {
+			logger.FromContext(r.Context()).Error("error encoding response", zap.Error(err))
+			return
+		}
+	}
+}
+
+// GetUserAPIKeys handler return api keys of user without secrets
+func GetUserAPIKeys(a *app.MyApp) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := auth.GetUserID(r.Context())
+		if err != nil {
+			noUser(w, r, err)
+			return
+		}
+		keys, err := a.GetUserAPIKeys(r.Context(), userID)
+		if notSupported(w, err) {
+			return
+		}
+		if err != nil {
+			logger.FromContext(r.Context()).Error("GetUserAPIKeys", zap.Error(err))
+			code := http.StatusInternalServerError
+			http.Error(w, http.StatusText(code), code)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		enc := json.NewEncoder(w)
+		if err := enc.Encode(keys); err != nil {
+			logger.FromContext(r.Context()).Error("error encoding response", zap.Error(err))
+			return
+		}
+	}
+}
+
+// RevokeAPIKey handler revoke api key of user
+func RevokeAPIKey(a *app.MyApp) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := auth.GetUserID(r.Context())
+		if err != nil {
+			noUser(w, r, err)
+			return
+		}
+		err = a.RevokeAPIKey(r.Context(), userID, chi.URLParam(r, "id"))
+		if notSupported(w, err) {
+			return
+		}
+		if err != nil {
+			code := http.StatusInternalServerError
+			if errors.Is(err, storage.ErrNotFound) {
+				code = http.StatusNotFound
+			} else {
+				logger.FromContext(r.Context()).Error("RevokeAPIKey", zap.Error(err))
+			}
+			http.Error(w, http.StatusText(code), code)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
 // DeleteUserURLS handler for delete short url
 func DeleteUserURLS(a *app.MyApp) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userID, err := auth.GetUserID(r.Context())
 		if err != nil {
-			noUser(w, err)
+			noUser(w, r, err)
 			return
 		}
 
 		var req models.RequestForDeleteURLS
 		dec := json.NewDecoder(r.Body)
 		if err := dec.Decode(&req); err != nil {
-			logger.Log.Debug("cannot decode request JSON body", zap.Error(err))
+			logger.FromContext(r.Context()).Debug("cannot decode request JSON body", zap.Error(err))
 			code := http.StatusBadRequest
 			http.Error(w, http.StatusText(code), code)
 			return
 		}
 
 		err = a.DeleteUserURLS(r.Context(), req, userID)
+		if errors.Is(err, app.ErrBadDeleteRequest) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, app.ErrDeleteQueueFull) {
+			logger.FromContext(r.Context()).Warn("DeleteUserURLS", zap.Error(err))
+			w.Header().Set("Retry-After", RetryAfter(a.DeleteRetryAfter()))
+			code := http.StatusTooManyRequests
+			http.Error(w, http.StatusText(code), code)
+			return
+		}
 		if err != nil {
-			logger.Log.Error("DeleteUserURLS", zap.Error(err))
+			logger.FromContext(r.Context()).Error("DeleteUserURLS", zap.Error(err))
 			code := http.StatusInternalServerError
 			http.Error(w, http.StatusText(code), code)
 			return
@@ -257,7 +468,7 @@ func InternalStats(a *app.MyApp) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		data, err := a.InternalStats(r.Context())
 		if err != nil {
-			logger.Log.Error("InternalStats", zap.Error(err))
+			logger.FromContext(r.Context()).Error("InternalStats", zap.Error(err))
 			code := http.StatusInternalServerError
 			http.Error(w, http.StatusText(code), code)
 			return
@@ -268,8 +479,233 @@ func InternalStats(a *app.MyApp) http.HandlerFunc {
 		w.WriteHeader(http.StatusOK)
 		// сериализуем ответ сервера
 		if err := json.NewEncoder(w).Encode(data); err != nil {
-			logger.Log.Error("error encoding response", zap.Error(err))
+			logger.FromContext(r.Context()).Error("error encoding response", zap.Error(err))
+			return
+		}
+	}
+}
+
+// writeUserToken set cookie with token of userid and return the token in body
+func writeUserToken(w http.ResponseWriter, r *http.Request, userID auth.UserID, code int) {
+	token, expiresAt := auth.SetUserCookie(w, userID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(models.ResponseToken{Token: token, ExpiresAt: expiresAt}); err != nil {
+		logger.FromContext(r.Context()).Error("error encoding response", zap.Error(err))
+		return
+	}
+}
+
+// Register handler create account with login and password. Links of current user stay with the account
+func Register(a *app.MyApp) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := auth.GetUserID(r.Context())
+		if err != nil {
+			noUser(w, r, err)
+			return
+		}
+		var req models.RequestCredentials
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.FromContext(r.Context()).Debug("cannot decode request JSON body", zap.Error(err))
+			http.Error(w, "bad json", http.StatusBadRequest)
+			return
+		}
+		userID, err = a.Register(r.Context(), userID, req)
+		if notSupported(w, err) {
+			return
+		}
+		if err != nil {
+			switch {
+			case errors.Is(err, app.ErrBadAccountRequest):
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			case errors.Is(err, app.ErrLoginTaken):
+				http.Error(w, err.Error(), http.StatusConflict)
+			default:
+				logger.FromContext(r.Context()).Error("Register", zap.Error(err))
+				code := http.StatusInternalServerError
+				http.Error(w, http.StatusText(code), code)
+			}
+			return
+		}
+		writeUserToken(w, r, userID, http.StatusCreated)
+	}
+}
+
+// Login handler check login and password. Links of anonymous user are moved to the account
+func Login(a *app.MyApp) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := auth.GetUserID(r.Context())
+		if err != nil {
+			noUser(w, r, err)
+			return
+		}
+		var req models.RequestCredentials
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.FromContext(r.Context()).Debug("cannot decode request JSON body", zap.Error(err))
+			http.Error(w, "bad json", http.StatusBadRequest)
+			return
+		}
+		userID, err = a.Login(r.Context(), userID, req)
+		if notSupported(w, err) {
+			return
+		}
+		if err != nil {
+			if errors.Is(err, app.ErrBadCredentials) {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+			logger.FromContext(r.Context()).Error("Login", zap.Error(err))
+			code := http.StatusInternalServerError
+			http.Error(w, http.StatusText(code), code)
+			return
+		}
+		writeUserToken(w, r, userID, http.StatusOK)
+	}
+}
+
+// adminError write answer for error of admin api
+func adminError(w http.ResponseWriter, r *http.Request, name string, err error) {
+	switch {
+	case errors.Is(err, app.ErrBadAdminRequest):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, storage.ErrNotFound):
+		code := http.StatusNotFound
+		http.Error(w, http.StatusText(code), code)
+	case errors.Is(err, storage.ErrNotSupported):
+		code := http.StatusNotImplemented
+		http.Error(w, http.StatusText(code), code)
+	default:
+		logger.FromContext(r.Context()).Error(name, zap.Error(err))
+		code := http.StatusInternalServerError
+		http.Error(w, http.StatusText(code), code)
+	}
+}
+
+// adminLimit parse query param limit. Empty limit is 0
+func adminLimit(r *http.Request) (int, error) {
+	value := r.URL.Query().Get("limit")
+	if value == "" {
+		return 0, nil
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%w: bad limit", app.ErrBadAdminRequest)
+	}
+	return limit, nil
+}
+
+// writeAdminURLS write urls of admin api
+func writeAdminURLS(w http.ResponseWriter, r *http.Request, urls []models.ResponseAdminURL) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(urls); err != nil {
+		logger.FromContext(r.Context()).Error("error encoding response", zap.Error(err))
+		return
+	}
+}
+
+// AdminSearchURLS handler find urls of all users by query params domain, user_id and limit
+func AdminSearchURLS(a *app.MyApp) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		adminID, err := auth.GetUserID(r.Context())
+		if err != nil {
+			noUser(w, r, err)
+			return
+		}
+		limit, err := adminLimit(r)
+		if err != nil {
+			adminError(w, r, "AdminSearchURLS", err)
+			return
+		}
+		query := r.URL.Query()
+		urls, err := a.AdminSearchURLS(r.Context(), adminID, query.Get("domain"), query.Get("user_id"), limit)
+		if err != nil {
+			adminError(w, r, "AdminSearchURLS", err)
+			return
+		}
+		writeAdminURLS(w, r, urls)
+	}
+}
+
+// AdminDisableURL handler disable url. Reason gone is served as 410, legal as 451
+func AdminDisableURL(a *app.MyApp) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		adminID, err := auth.GetUserID(r.Context())
+		if err != nil {
+			noUser(w, r, err)
+			return
+		}
+		var req models.RequestDisableURL
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.FromContext(r.Context()).Debug("cannot decode request JSON body", zap.Error(err))
+			http.Error(w, "bad json", http.StatusBadRequest)
+			return
+		}
+		if req.Reason == "" {
+			adminError(w, r, "AdminDisableURL", fmt.Errorf("%w: empty reason", app.ErrBadAdminRequest))
+			return
+		}
+		if err := a.AdminDisableURL(r.Context(), adminID, chi.URLParam(r, "key"), req.Reason); err != nil {
+			adminError(w, r, "AdminDisableURL", err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// AdminEnableURL handler enable disabled url
+func AdminEnableURL(a *app.MyApp) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		adminID, err := auth.GetUserID(r.Context())
+		if err != nil {
+			noUser(w, r, err)
+			return
+		}
+		if err := a.AdminDisableURL(r.Context(), adminID, chi.URLParam(r, "key"), ""); err != nil {
+			adminError(w, r, "AdminEnableURL", err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// AdminBanUser handler ban user for PUT and unban for DELETE
+func AdminBanUser(a *app.MyApp) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		adminID, err := auth.GetUserID(r.Context())
+		if err != nil {
+			noUser(w, r, err)
+			return
+		}
+		banned := r.Method != http.MethodDelete
+		if err := a.AdminBanUser(r.Context(), adminID, chi.URLParam(r, "id"), banned); err != nil {
+			adminError(w, r, "AdminBanUser", err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// AdminTopURLS handler return urls with max count of redirects
+func AdminTopURLS(a *app.MyApp) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		adminID, err := auth.GetUserID(r.Context())
+		if err != nil {
+			noUser(w, r, err)
+			return
+		}
+		limit, err := adminLimit(r)
+		if err != nil {
+			adminError(w, r, "AdminTopURLS", err)
+			return
+		}
+		urls, err := a.AdminTopURLS(r.Context(), adminID, limit)
+		if err != nil {
+			adminError(w, r, "AdminTopURLS", err)
 			return
 		}
+		writeAdminURLS(w, r, urls)
 	}
 }
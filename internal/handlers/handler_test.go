@@ -2,12 +2,14 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/golang/mock/gomock"
@@ -17,14 +19,15 @@ import (
 	"github.com/serg2014/shortener/internal/app"
 	appmock "github.com/serg2014/shortener/internal/app/mock"
 	"github.com/serg2014/shortener/internal/auth"
+	"github.com/serg2014/shortener/internal/config"
 	"github.com/serg2014/shortener/internal/models"
-	"github.com/serg2014/shortener/internal/storage"
-	"github.com/serg2014/shortener/internal/storage/mock"
+	"github.com/serg2014/shortener/storage"
+	"github.com/serg2014/shortener/storage/mock"
 )
 
 func Test_noUser(t *testing.T) {
 	w := httptest.NewRecorder()
-	noUser(w, auth.ErrUserIDFromContext)
+	noUser(w, httptest.NewRequest(http.MethodGet, "/", nil), auth.ErrUserIDFromContext)
 	resp := w.Result()
 	require.Equal(t, http.StatusInternalServerError, resp.StatusCode)
 
@@ -635,12 +638,12 @@ func TestGetURL(t *testing.T) {
 				},
 			},
 			want: want{
-				statusCode: http.StatusBadRequest,
+				statusCode: http.StatusNotFound,
 				headers: headers{
 					"content-type":     "text/plain; charset=utf-8",
 					"Content-Encoding": "",
 				},
-				body: "bad short url\n",
+				body: "Not Found\n",
 			},
 		},
 		{
@@ -716,12 +719,12 @@ func TestGetURL(t *testing.T) {
 				},
 			},
 			want: want{
-				statusCode: http.StatusBadRequest,
+				statusCode: http.StatusNotFound,
 				headers: headers{
 					"content-type":     "text/plain; charset=utf-8",
 					"Content-Encoding": "",
 				},
-				body: "bad short url\n",
+				body: "Not Found\n",
 			},
 		},
 		{
@@ -815,3 +818,99 @@ func TestInternalStats(t *testing.T) {
 	}
 	runTests(t, tests, func(newa *app.MyApp) http.HandlerFunc { return InternalStats(newa) })
 }
+
+func TestDeleteUserURLS(t *testing.T) {
+	prev := config.Config.DeleteQueueSize
+	config.Config.DeleteQueueSize = 1
+	a := app.NewApp(nil, nil)
+	config.Config.DeleteQueueSize = prev
+
+	tests := []myTest{
+		{
+			name: "bad json",
+			a:    a,
+			req: httptestNewRequestTest(
+				http.MethodDelete,
+				"/api/user/urls",
+				strings.NewReader(`["a1234567"`),
+				map[string]string{},
+				"user1",
+			),
+			want: want{
+				statusCode: http.StatusBadRequest,
+				body:       "Bad Request\n",
+			},
+		},
+		{
+			name: "bad short url",
+			a:    a,
+			req: httptestNewRequestTest(
+				http.MethodDelete,
+				"/api/user/urls",
+				strings.NewReader(`["http://localhost:8080/a1234567"]`),
+				map[string]string{},
+				"user1",
+			),
+			want: want{
+				statusCode: http.StatusBadRequest,
+				body:       "bad delete request: short url \"http://localhost:8080/a1234567\" must have 8 chars\n",
+			},
+		},
+		{
+			name: "accepted",
+			a:    a,
+			req: httptestNewRequestTest(
+				http.MethodDelete,
+				"/api/user/urls",
+				strings.NewReader(`["a1234567"]`),
+				map[string]string{},
+				"user1",
+			),
+			want: want{
+				statusCode: http.StatusAccepted,
+				body:       "",
+			},
+		},
+		{
+			name: "queue is full",
+			a:    a,
+			req: httptestNewRequestTest(
+				http.MethodDelete,
+				"/api/user/urls",
+				strings.NewReader(`["a1234568"]`),
+				map[string]string{},
+				"user1",
+			),
+			want: want{
+				statusCode: http.StatusTooManyRequests,
+				headers: headers{
+					"Retry-After": "1",
+				},
+				body: "Too Many Requests\n",
+			},
+		},
+	}
+	runTests(t, tests, func(newa *app.MyApp) http.HandlerFunc { return DeleteUserURLS(newa) })
+}
+
+func TestGetUserToken(t *testing.T) {
+	w := httptest.NewRecorder()
+	GetUserToken()(w, httptestNewRequestTest(http.MethodGet, "/api/user/token", nil, map[string]string{}, "user1"))
+	res := w.Result()
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "application/json", res.Header.Get("Content-Type"))
+
+	var resp models.ResponseToken
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&resp))
+	assert.True(t, resp.ExpiresAt.After(time.Now()))
+
+	// выданный токен принимается в заголовке Authorization
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+resp.Token)
+	auth.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, err := auth.GetUserID(r.Context())
+		require.NoError(t, err)
+		assert.Equal(t, auth.UserID("user1"), userID)
+	})).ServeHTTP(httptest.NewRecorder(), req)
+}
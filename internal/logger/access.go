@@ -0,0 +1,168 @@
+package logger
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc/codes"
+)
+
+// clfTime format of time in Combined Log Format
+const clfTime = "02/Jan/2006:15:04:05 -0700"
+
+// access separate access log. Without it requests are logged into application log
+var access struct {
+	sync.RWMutex
+	// log access log in json or console
+	log *zap.Logger
+	// clf writer of access log in Combined Log Format
+	clf zapcore.WriteSyncer
+}
+
+// setAccess set access log. Nil ws - requests are logged into application log
+func setAccess(ws zapcore.WriteSyncer, enc zapcore.Encoder, combined bool) {
+	access.Lock()
+	defer access.Unlock()
+	access.log, access.clf = nil, nil
+	switch {
+	case ws == nil:
+	case combined:
+		access.clf = ws
+	default:
+		// access log не зависит от уровня логирования приложения
+		access.log = zap.New(zapcore.NewCore(enc, ws, zapcore.DebugLevel))
+	}
+}
+
+// accessEntry request for access log
+type accessEntry struct {
+	ip        string
+	userID    string
+	start     time.Time
+	method    string
+	uri       string
+	proto     string
+	status    int
+	size      int
+	referer   string
+	userAgent string
+}
+
+// accessLogger return logger of access log with id of request of ctx.
+// Without access log logger of ctx is returned. Nil - access log in Combined Log Format is used
+func accessLogger(ctx context.Context) *zap.Logger {
+	access.RLock()
+	defer access.RUnlock()
+	if access.clf != nil {
+		return nil
+	}
+	if access.log == nil {
+		return loggerOf(ctx)
+	}
+	if id := RequestID(ctx); id != "" {
+		return access.log.With(zap.String("requestID", id))
+	}
+	return access.log
+}
+
+// writeCLF write request in Combined Log Format:
+// host ident user [time] "method uri proto" status size "referer" "user-agent"
+func writeCLF(e accessEntry) {
+	access.RLock()
+	defer access.RUnlock()
+	if access.clf == nil {
+		return
+	}
+	var b strings.Builder
+	b.WriteString(clfValue(e.ip))
+	b.WriteString(" - ")
+	b.WriteString(clfValue(e.userID))
+	b.WriteString(" [")
+	b.WriteString(e.start.Format(clfTime))
+	b.WriteString(`] "`)
+	b.WriteString(clfEscape(e.method + " " + e.uri + " " + e.proto))
+	b.WriteString(`" `)
+	b.WriteString(strconv.Itoa(e.status))
+	b.WriteByte(' ')
+	if e.size > 0 {
+		b.WriteString(strconv.Itoa(e.size))
+	} else {
+		b.WriteByte('-')
+	}
+	b.WriteString(` "`)
+	b.WriteString(clfEscape(clfValue(e.referer)))
+	b.WriteString(`" "`)
+	b.WriteString(clfEscape(clfValue(e.userAgent)))
+	b.WriteString("\"\n")
+	if _, err := access.clf.Write([]byte(b.String())); err != nil {
+		Log.Error("can not write access log", zap.Error(err))
+	}
+}
+
+// clfValue return "-" for empty value
+func clfValue(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+// clfEscape escape quotes, backslashes and control characters like apache
+func clfEscape(value string) string {
+	const hex = "0123456789abcdef"
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < 0x20 || c == 0x7f:
+			b.WriteString(`\x`)
+			b.WriteByte(hex[c>>4])
+			b.WriteByte(hex[c&0xf])
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// httpStatus status of http for code of grpc, like in grpc-gateway.
+// In Combined Log Format the column of status is http status, not code of grpc
+func httpStatus(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		// нестандартный статус nginx: клиент закрыл запрос
+		return 499
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	default:
+		// Unknown, Internal, DataLoss
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,132 @@
+package logger
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/serg2014/shortener/internal/auth"
+)
+
+// RequestIDHeader header with id of request. Incoming value is kept, otherwise new id is generated
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDMeta key of metadata of grpc with id of request
+const requestIDMeta = "x-request-id"
+
+// maxRequestIDLen max length of incoming id of request
+const maxRequestIDLen = 128
+
+type (
+	loggerCtxKey    struct{}
+	requestIDCtxKey struct{}
+)
+
+// NewContext return ctx with logger l. See FromContext
+func NewContext(ctx context.Context, l *zap.Logger) context.Context {
+	return context.WithValue(ctx, loggerCtxKey{}, l)
+}
+
+// FromContext return logger of ctx with request id, user id and trace id.
+// Without logger in ctx Log is used
+func FromContext(ctx context.Context) *zap.Logger {
+	l := loggerOf(ctx)
+	fields := TraceFields(ctx)
+	// пользователь известен только после auth middleware, поэтому берется при каждом вызове
+	if userID, err := auth.GetUserID(ctx); err == nil && userID != "" {
+		fields = append(fields, UserIDField("userID", string(userID)))
+	}
+	if len(fields) == 0 {
+		return l
+	}
+	return l.With(fields...)
+}
+
+// loggerOf return logger of ctx without dynamic fields. Without logger in ctx Log is used
+func loggerOf(ctx context.Context) *zap.Logger {
+	if l, ok := ctx.Value(loggerCtxKey{}).(*zap.Logger); ok {
+		return l
+	}
+	return Log
+}
+
+// Component return logger of ctx with name of component (app, storage, grpc...).
+// Entries are filtered by level of component, see SetComponentLevel
+func Component(ctx context.Context, name string) *zap.Logger {
+	return withComponent(FromContext(ctx), name)
+}
+
+// RequestID return id of request of ctx. Empty if ctx has no request
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDCtxKey{}).(string)
+	return id
+}
+
+// withRequestID return ctx with id of request and logger with this id
+func withRequestID(ctx context.Context, id string) context.Context {
+	ctx = context.WithValue(ctx, requestIDCtxKey{}, id)
+	return NewContext(ctx, Log.With(zap.String("requestID", id)))
+}
+
+// validRequestID incoming id is not too long and has only safe for logs characters
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID generate random id of request
+func newRequestID() string {
+	b := make([]byte, 16)
+	// rand.Read не возвращает ошибку
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// requestIDOrNew return incoming id if it is valid, otherwise new id
+func requestIDOrNew(id string) string {
+	if validRequestID(id) {
+		return id
+	}
+	return newRequestID()
+}
+
+// RequestIDMiddleware take id of request from X-Request-ID or generate it, echo it in response
+// and attach logger with it to the context of request
+func RequestIDMiddleware(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := requestIDOrNew(r.Header.Get(RequestIDHeader))
+		w.Header().Set(RequestIDHeader, id)
+		h.ServeHTTP(w, r.WithContext(withRequestID(r.Context(), id)))
+	})
+}
+
+// RequestIDInterceptor take id of request from metadata x-request-id or generate it, send it in header
+// and attach logger with it to the context of call
+func RequestIDInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	var id string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(requestIDMeta); len(values) > 0 {
+			id = values[0]
+		}
+	}
+	id = requestIDOrNew(id)
+	if err := grpc.SetHeader(ctx, metadata.Pairs(requestIDMeta, id)); err != nil {
+		Log.Debug("can not set header x-request-id", zap.Error(err))
+	}
+	return handler(withRequestID(ctx, id), req)
+}
@@ -0,0 +1,100 @@
+package logger
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/serg2014/shortener/internal/auth"
+)
+
+// observeLog replace Log with logger which keeps entries in memory
+func observeLog(t *testing.T) *observer.ObservedLogs {
+	core, logs := observer.New(zap.DebugLevel)
+	prev := Log
+	Log = zap.New(core)
+	t.Cleanup(func() { Log = prev })
+	return logs
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	tests := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{name: "incoming id", incoming: "abc-123", keep: true},
+		{name: "no id", incoming: ""},
+		{name: "bad id", incoming: "abc\n123"},
+		{name: "long id", incoming: strings.Repeat("a", maxRequestIDLen+1)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			logs := observeLog(t)
+			var id string
+			h := RequestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				id = RequestID(r.Context())
+				Component(r.Context(), "test").Info("handler")
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if test.incoming != "" {
+				req.Header.Set(RequestIDHeader, test.incoming)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+
+			if test.keep {
+				assert.Equal(t, test.incoming, id)
+			} else {
+				assert.Len(t, id, 32)
+			}
+			assert.Equal(t, id, w.Header().Get(RequestIDHeader))
+			require.Equal(t, 1, logs.Len())
+			fields := logs.All()[0].ContextMap()
+			assert.Equal(t, id, fields["requestID"])
+			assert.Equal(t, "test", fields["component"])
+		})
+	}
+}
+
+func TestRequestIDInterceptor(t *testing.T) {
+	observeLog(t)
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(requestIDMeta, "abc-123"))
+	var id string
+	_, err := RequestIDInterceptor(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req any) (any, error) {
+		id = RequestID(ctx)
+		return nil, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "abc-123", id)
+
+	_, err = RequestIDInterceptor(context.Background(), nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req any) (any, error) {
+		id = RequestID(ctx)
+		return nil, nil
+	})
+	require.NoError(t, err)
+	assert.Len(t, id, 32)
+}
+
+func TestFromContext(t *testing.T) {
+	logs := observeLog(t)
+
+	FromContext(context.Background()).Info("no request")
+	ctx := withRequestID(context.Background(), "abc-123")
+	userID := auth.UserID("user1")
+	FromContext(auth.WithUser(ctx, &userID)).Info("request")
+
+	entries := logs.All()
+	require.Len(t, entries, 2)
+	assert.Empty(t, entries[0].ContextMap())
+	assert.Equal(t, map[string]any{"requestID": "abc-123", "userID": "user1"}, entries[1].ContextMap())
+}
@@ -0,0 +1,176 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// components levels of components which override Level. See Component
+var components = struct {
+	sync.RWMutex
+	levels map[string]zapcore.Level
+}{levels: make(map[string]zapcore.Level)}
+
+// componentLevel return level of component: its own level or Level
+func componentLevel(name string) zapcore.Level {
+	if name != "" {
+		components.RLock()
+		lvl, ok := components.levels[name]
+		components.RUnlock()
+		if ok {
+			return lvl
+		}
+	}
+	return Level.Level()
+}
+
+// ComponentLevel return level of component and whether it overrides Level
+func ComponentLevel(name string) (zapcore.Level, bool) {
+	components.RLock()
+	defer components.RUnlock()
+	lvl, ok := components.levels[name]
+	if !ok {
+		return Level.Level(), false
+	}
+	return lvl, true
+}
+
+// SetComponentLevel set own level of component. It overrides Level
+func SetComponentLevel(name string, lvl zapcore.Level) {
+	components.Lock()
+	defer components.Unlock()
+	components.levels[name] = lvl
+}
+
+// ResetComponentLevel remove own level of component, Level is used
+func ResetComponentLevel(name string) {
+	components.Lock()
+	defer components.Unlock()
+	delete(components.levels, name)
+}
+
+// setComponentLevels replace all levels of components. Levels are like debug, warn
+func setComponentLevels(levels map[string]string) error {
+	parsed := make(map[string]zapcore.Level, len(levels))
+	for name, level := range levels {
+		lvl, err := zapcore.ParseLevel(level)
+		if err != nil {
+			return fmt.Errorf("level of component %s: %w", name, err)
+		}
+		parsed[name] = lvl
+	}
+	components.Lock()
+	defer components.Unlock()
+	components.levels = parsed
+	return nil
+}
+
+// MoreVerbose lower Level by one step (info -> debug). Return new level
+func MoreVerbose() zapcore.Level {
+	lvl := max(Level.Level()-1, zapcore.DebugLevel)
+	Level.SetLevel(lvl)
+	return lvl
+}
+
+// LessVerbose raise Level by one step (info -> warn). Return new level
+func LessVerbose() zapcore.Level {
+	lvl := min(Level.Level()+1, zapcore.FatalLevel)
+	Level.SetLevel(lvl)
+	return lvl
+}
+
+// levelCore filter entries by level of component. Core of Log is not filtered itself,
+// so component can log with level lower than Level
+type levelCore struct {
+	zapcore.Core
+	component string
+}
+
+// Enabled implement Enabled of zapcore.Core
+func (c *levelCore) Enabled(lvl zapcore.Level) bool {
+	return componentLevel(c.component).Enabled(lvl)
+}
+
+// With implement With of zapcore.Core
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelCore{Core: c.Core.With(fields), component: c.component}
+}
+
+// Check implement Check of zapcore.Core
+func (c *levelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(ent.Level) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
+
+// withComponent return logger whose entries are filtered by level of component
+func withComponent(l *zap.Logger, name string) *zap.Logger {
+	return l.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		if lc, ok := core.(*levelCore); ok {
+			return &levelCore{Core: lc.Core, component: name}
+		}
+		return core
+	})).With(zap.String("component", name))
+}
+
+// componentLevelBody body of ComponentLevelHandler like {"level":"debug"}
+type componentLevelBody struct {
+	Level *zapcore.Level `json:"level"`
+}
+
+// ComponentLevelHandler GET return level of component, PUT {"level":"debug"} set its own level,
+// DELETE remove own level of component
+func ComponentLevelHandler(name string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			var body componentLevelBody
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Level == nil {
+				http.Error(w, "bad level", http.StatusBadRequest)
+				return
+			}
+			SetComponentLevel(name, *body.Level)
+		case http.MethodDelete:
+			ResetComponentLevel(name)
+		default:
+			w.Header().Set("Allow", "GET, PUT, DELETE")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		lvl, own := ComponentLevel(name)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(struct {
+			Level zapcore.Level `json:"level"`
+			Own   bool          `json:"own"`
+		}{Level: lvl, Own: own})
+	})
+}
+
+// accessSample log every n-th successful request. Failed requests are always logged
+var accessSample struct {
+	n     atomic.Int64
+	count atomic.Uint64
+}
+
+// setAccessSample set sampling of access log. 0 and 1 - all requests are logged
+func setAccessSample(n int) {
+	accessSample.n.Store(int64(n))
+	accessSample.count.Store(0)
+}
+
+// sampleAccess whether request is logged into access log
+func sampleAccess(failed bool) bool {
+	n := accessSample.n.Load()
+	if failed || n <= 1 {
+		return true
+	}
+	return accessSample.count.Add(1)%uint64(n) == 1
+}
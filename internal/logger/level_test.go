@@ -0,0 +1,99 @@
+package logger
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// observeLevelLog replace Log with logger filtered by levels of components
+func observeLevelLog(t *testing.T) *observer.ObservedLogs {
+	core, logs := observer.New(zapcore.DebugLevel)
+	prevLog, prevLevel := Log, Level.Level()
+	Log = zap.New(&levelCore{Core: core})
+	Level.SetLevel(zapcore.InfoLevel)
+	t.Cleanup(func() {
+		Log = prevLog
+		Level.SetLevel(prevLevel)
+		require.NoError(t, setComponentLevels(nil))
+	})
+	return logs
+}
+
+func TestComponent_level(t *testing.T) {
+	logs := observeLevelLog(t)
+	require.NoError(t, setComponentLevels(map[string]string{"storage": "debug", "grpc": "error"}))
+
+	ctx := context.Background()
+	Log.Debug("global debug")
+	Component(ctx, "storage").Debug("storage debug")
+	Component(ctx, "grpc").Warn("grpc warn")
+	Component(ctx, "app").Info("app info")
+	Component(ctx, "app").Debug("app debug")
+
+	messages := make([]string, 0)
+	for _, entry := range logs.All() {
+		messages = append(messages, entry.Message)
+	}
+	assert.Equal(t, []string{"storage debug", "app info"}, messages)
+	assert.Equal(t, "storage", logs.All()[0].ContextMap()["component"])
+
+	assert.Error(t, setComponentLevels(map[string]string{"storage": "loud"}))
+}
+
+func TestVerbose(t *testing.T) {
+	observeLevelLog(t)
+	assert.Equal(t, zapcore.DebugLevel, MoreVerbose())
+	assert.Equal(t, zapcore.DebugLevel, MoreVerbose())
+	assert.Equal(t, zapcore.InfoLevel, LessVerbose())
+	Level.SetLevel(zapcore.FatalLevel)
+	assert.Equal(t, zapcore.FatalLevel, LessVerbose())
+}
+
+func TestComponentLevelHandler(t *testing.T) {
+	observeLevelLog(t)
+	h := ComponentLevelHandler("storage")
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"level":"info","own":false}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/", strings.NewReader(`{"level":"debug"}`)))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"level":"debug","own":true}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/", strings.NewReader(`{"level":"loud"}`)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"level":"info","own":false}`, w.Body.String())
+}
+
+func TestSampleAccess(t *testing.T) {
+	t.Cleanup(func() { setAccessSample(0) })
+	setAccessSample(3)
+	logged := 0
+	for range 9 {
+		if sampleAccess(false) {
+			logged++
+		}
+	}
+	assert.Equal(t, 3, logged)
+	assert.True(t, sampleAccess(true))
+
+	setAccessSample(0)
+	assert.True(t, sampleAccess(false))
+}
@@ -4,40 +4,30 @@ package logger
 import (
 	"context"
 	"net/http"
+	"strconv"
 	"time"
 
+	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 
 	"github.com/serg2014/shortener/internal/auth"
+	"github.com/serg2014/shortener/internal/clientip"
+	"github.com/serg2014/shortener/internal/metrics"
+	"github.com/serg2014/shortener/internal/tracing"
 )
 
+// unknownRoute label of requests without route
+const unknownRoute = "unknown"
+
 // Log будет доступен всему коду как синглтон.
 // По умолчанию установлен no-op-логер, который не выводит никаких сообщений.
 var Log *zap.Logger = zap.NewNop()
 
-// Initialize инициализирует синглтон логера с необходимым уровнем логирования.
-func Initialize(level string) error {
-	// преобразуем текстовый уровень логирования в zap.AtomicLevel
-	lvl, err := zap.ParseAtomicLevel(level)
-	if err != nil {
-		return err
-	}
-	// создаём новую конфигурацию логера
-	cfg := zap.NewProductionConfig()
-	// устанавливаем уровень
-	cfg.Level = lvl
-	// создаём логер на основе конфигурации
-	zl, err := cfg.Build()
-	if err != nil {
-		return err
-	}
-	// устанавливаем синглтон
-	Log = zl
-	return nil
-}
+// Level уровень логирования синглтона. Меняется на лету через Level.ServeHTTP
+var Level = zap.NewAtomicLevel()
 
 type (
 	// берём структуру для хранения сведений об ответе
@@ -69,7 +59,7 @@ func (r *loggingResponseWriter) WriteHeader(statusCode int) {
 }
 
 // WithLogging добавляет дополнительный код для регистрации сведений о запросе
-// и возвращает новый http.Handler.
+// и возвращает новый http.Handler. Также считает метрики запросов по шаблону роута
 func WithLogging(h http.Handler) http.Handler {
 	logFn := func(w http.ResponseWriter, r *http.Request) {
 		// функция Now() возвращает текущее время
@@ -91,28 +81,68 @@ func WithLogging(h http.Handler) http.Handler {
 		// и моментом вызова Since. Таким образом можно посчитать
 		// время выполнения запроса.
 		duration := time.Since(start)
+		observeHTTP(r, responseData.status, duration)
 
 		// отправляем сведения о запросе в zap
+		if !sampleAccess(responseData.status >= http.StatusBadRequest) {
+			return
+		}
 		userID, err := auth.GetUserID(r.Context())
 		if err != nil {
 			userID = ""
 		}
-		Log.Info(
+		l := accessLogger(r.Context())
+		if l == nil {
+			status := responseData.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			writeCLF(accessEntry{
+				ip:        RedactIP(clientip.FromRequest(r)),
+				userID:    RedactUserID(string(userID)),
+				start:     start,
+				method:    r.Method,
+				uri:       RedactURL(r.RequestURI),
+				proto:     r.Proto,
+				status:    status,
+				size:      responseData.size,
+				referer:   RedactURL(r.Referer()),
+				userAgent: r.UserAgent(),
+			})
+			return
+		}
+		l.Info(
 			"got incoming HTTP request",
-			zap.String("uri", r.RequestURI),
+			URLField("uri", r.RequestURI),
 			zap.String("method", r.Method),
 			zap.Duration("duration", duration),
 			zap.Int("status", responseData.status),
 			zap.Int("size", responseData.size),
-			zap.String("userID", string(userID)),
-			zap.String("IP", r.Header.Get("X-Real-IP")),
+			UserIDField("userID", string(userID)),
+			IPField("IP", clientip.FromRequest(r)),
+			zap.String("traceID", tracing.TraceID(r.Context())),
 		)
 	}
 	// возвращаем функционально расширенный хендлер
 	return http.HandlerFunc(logFn)
 }
 
-// loggerInterceptor
+// observeHTTP count request in metrics. Route is pattern of chi, not uri, so count of labels is bounded
+func observeHTTP(r *http.Request, statusCode int, duration time.Duration) {
+	route := unknownRoute
+	if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+		route = rctx.RoutePattern()
+	}
+	if statusCode == 0 {
+		// хендлер ничего не записал
+		statusCode = http.StatusOK
+	}
+	code := strconv.Itoa(statusCode)
+	metrics.HTTPRequests.WithLabelValues(route, r.Method, code).Inc()
+	metrics.HTTPDuration.WithLabelValues(route, r.Method, code).Observe(duration.Seconds())
+}
+
+// LoggerInterceptor log grpc request and count it in metrics
 func LoggerInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
 	// выполняем действия перед вызовом метода
 	start := time.Now()
@@ -127,28 +157,62 @@ func LoggerInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo,
 	}
 
 	status := status.Convert(err)
-	Log.Info(
+	code := status.Code().String()
+	metrics.GRPCRequests.WithLabelValues(info.FullMethod, code).Inc()
+	metrics.GRPCDuration.WithLabelValues(info.FullMethod, code).Observe(duration.Seconds())
+	if !sampleAccess(err != nil) {
+		return resp, err
+	}
+	l := accessLogger(ctx)
+	if l == nil {
+		writeCLF(accessEntry{
+			ip:        RedactIP(GetIP(ctx)),
+			userID:    RedactUserID(string(userID)),
+			start:     start,
+			method:    http.MethodPost,
+			uri:       info.FullMethod,
+			proto:     "HTTP/2.0",
+			status:    httpStatus(status.Code()),
+			userAgent: userAgent(ctx),
+		})
+		return resp, err
+	}
+	l.Info(
 		"got gRPC request",
 		zap.String("method", info.FullMethod),
 		zap.Duration("duration", duration),
 		zap.Int("status", int(status.Code())),
 		// TODO is it really need?
 		// zap.Int("size", responseData.size),
-		zap.String("userID", string(userID)),
-		zap.String("IP", GetIP(ctx)),
+		UserIDField("userID", string(userID)),
+		IPField("IP", GetIP(ctx)),
+		zap.String("traceID", tracing.TraceID(ctx)),
 	)
 	return resp, err
 }
 
-// GetIP
-func GetIP(ctx context.Context) string {
+// TraceFields fields of zap with ids of trace and span of ctx. Empty if ctx has no trace
+func TraceFields(ctx context.Context) []zap.Field {
+	traceID := tracing.TraceID(ctx)
+	if traceID == "" {
+		return nil
+	}
+	return []zap.Field{zap.String("traceID", traceID), zap.String("spanID", tracing.SpanID(ctx))}
+}
+
+// userAgent return user-agent of grpc client from metadata
+func userAgent(ctx context.Context) string {
 	md, ok := metadata.FromIncomingContext(ctx)
-	if ok {
-		values := md.Get("X-Real-IP")
-		if len(values) > 0 {
-			// ключ содержит слайс строк, получаем первую строку
-			return values[0]
-		}
+	if !ok {
+		return ""
+	}
+	if values := md.Get("user-agent"); len(values) > 0 {
+		return values[0]
 	}
 	return ""
 }
+
+// GetIP return ip of client of grpc request. See clientip.FromContext
+func GetIP(ctx context.Context) string {
+	return clientip.FromContext(ctx)
+}
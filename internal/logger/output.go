@@ -0,0 +1,245 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+
+	"github.com/serg2014/shortener/internal/config"
+)
+
+// Standard outputs of logs
+const (
+	outputStderr = "stderr"
+	outputStdout = "stdout"
+)
+
+// maxFileSize size of rotated file in megabytes when rotation by size is disabled
+const maxFileSize = 1 << 20
+
+// ErrUnknownEncoding encoding of log is not supported
+var ErrUnknownEncoding = errors.New("unknown encoding of log")
+
+// Rotation settings of rotation of log files
+type Rotation struct {
+	// MaxSize size of file in megabytes when it is rotated. 0 - no rotation by size
+	MaxSize int
+	// Interval file is rotated at every multiple of interval. 0 - no rotation by time
+	Interval time.Duration
+	// MaxBackups count of kept rotated files. 0 - all files are kept
+	MaxBackups int
+	// MaxAge rotated files older than this are removed, rounded up to days. 0 - files are not removed by age
+	MaxAge time.Duration
+	// Compress compress rotated files by gzip
+	Compress bool
+}
+
+// enabled rotation of files is enabled
+func (r Rotation) enabled() bool {
+	return r.MaxSize > 0 || r.Interval > 0
+}
+
+// Options settings of logs, see config.Log*
+type Options struct {
+	// Level level of application log
+	Level string
+	// ComponentLevels own levels of components like storage=debug. See Component
+	ComponentLevels map[string]string
+	// Encoding json or console. Empty - json
+	Encoding string
+	// Outputs stderr, stdout or paths to files. Empty - stderr
+	Outputs []string
+	// Rotation rotation of files of application and access logs
+	Rotation Rotation
+	// AccessOutputs outputs of access log. Empty - requests are logged into application log
+	AccessOutputs []string
+	// AccessFormat json, console or combined. Empty - Encoding
+	AccessFormat string
+	// AccessSample log every n-th successful request, failed requests are always logged.
+	// 0 - all requests are logged
+	AccessSample int
+	// Privacy anonymize ip, hash user ids and remove RedactParams from urls in logs
+	Privacy bool
+	// RedactParams query params removed from urls in privacy mode. Empty - DefaultRedactParams
+	RedactParams []string
+}
+
+// Initialize инициализирует синглтон логера с необходимым уровнем логирования.
+// Лог пишется в stderr в json
+func Initialize(level string) error {
+	_, err := Init(Options{Level: level})
+	return err
+}
+
+// Init set application log Log and access log by opts. Returned func flushes logs and closes files
+func Init(opts Options) (func() error, error) {
+	// преобразуем текстовый уровень логирования в zap.AtomicLevel
+	lvl, err := zap.ParseAtomicLevel(opts.Level)
+	if err != nil {
+		return nil, err
+	}
+	if err := setComponentLevels(opts.ComponentLevels); err != nil {
+		return nil, err
+	}
+	enc, err := newEncoder(opts.Encoding)
+	if err != nil {
+		return nil, err
+	}
+	outputs := opts.Outputs
+	if len(outputs) == 0 {
+		outputs = []string{outputStderr}
+	}
+	files := &files{rotation: opts.Rotation}
+	ws, err := files.open(outputs)
+	if err != nil {
+		return nil, err
+	}
+
+	var accessWS zapcore.WriteSyncer
+	var accessEnc zapcore.Encoder
+	if len(opts.AccessOutputs) > 0 {
+		accessWS, err = files.open(opts.AccessOutputs)
+		if err == nil && opts.AccessFormat != config.LogCombined {
+			format := opts.AccessFormat
+			if format == "" {
+				format = opts.Encoding
+			}
+			accessEnc, err = newEncoder(format)
+		}
+		if err != nil {
+			files.close()
+			return nil, err
+		}
+	}
+
+	// устанавливаем уровень
+	Level.SetLevel(lvl.Level())
+	// как в zap.NewProductionConfig: сэмплирование одинаковых сообщений, caller и stacktrace ошибок.
+	// уровень проверяет levelCore, чтобы компоненты могли писать ниже Level
+	core := &levelCore{Core: zapcore.NewSamplerWithOptions(zapcore.NewCore(enc, ws, zapcore.DebugLevel), time.Second, 100, 100)}
+	zl := zap.New(core, zap.AddCaller(), zap.AddStacktrace(zap.ErrorLevel), zap.ErrorOutput(zapcore.Lock(os.Stderr)))
+
+	// устанавливаем синглтоны
+	Log = zl
+	setAccess(accessWS, accessEnc, opts.AccessFormat == config.LogCombined)
+	setAccessSample(opts.AccessSample)
+	setPrivacy(opts.Privacy, opts.RedactParams)
+	return func() error {
+		// ошибку sync у stderr игнорируем, на некоторых системах он не поддерживает fsync
+		_ = zl.Sync()
+		return files.close()
+	}, nil
+}
+
+// newEncoder create encoder of log: json or console
+func newEncoder(encoding string) (zapcore.Encoder, error) {
+	cfg := zap.NewProductionEncoderConfig()
+	switch encoding {
+	case "", config.LogJSON:
+		return zapcore.NewJSONEncoder(cfg), nil
+	case config.LogConsole:
+		cfg.EncodeTime = zapcore.ISO8601TimeEncoder
+		cfg.EncodeLevel = zapcore.CapitalLevelEncoder
+		return zapcore.NewConsoleEncoder(cfg), nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownEncoding, encoding)
+}
+
+// files opened files of logs
+type files struct {
+	rotation Rotation
+	closers  []func() error
+}
+
+// open return writer into all outputs
+func (f *files) open(outputs []string) (zapcore.WriteSyncer, error) {
+	ws := make([]zapcore.WriteSyncer, 0, len(outputs))
+	for _, output := range outputs {
+		switch output {
+		case outputStderr:
+			ws = append(ws, zapcore.Lock(os.Stderr))
+		case outputStdout:
+			ws = append(ws, zapcore.Lock(os.Stdout))
+		default:
+			w, err := f.file(output)
+			if err != nil {
+				f.close()
+				return nil, err
+			}
+			ws = append(ws, w)
+		}
+	}
+	return zapcore.NewMultiWriteSyncer(ws...), nil
+}
+
+// file open file of log. With rotation file is rotated by lumberjack
+func (f *files) file(path string) (zapcore.WriteSyncer, error) {
+	if !f.rotation.enabled() {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return nil, fmt.Errorf("can not open file of log: %w", err)
+		}
+		f.closers = append(f.closers, file.Close)
+		return zapcore.Lock(file), nil
+	}
+	lj := &lumberjack.Logger{
+		Filename:   path,
+		MaxSize:    f.rotation.MaxSize,
+		MaxBackups: f.rotation.MaxBackups,
+		MaxAge:     int((f.rotation.MaxAge + 24*time.Hour - 1) / (24 * time.Hour)),
+		Compress:   f.rotation.Compress,
+		LocalTime:  true,
+	}
+	if lj.MaxSize == 0 {
+		// у lumberjack 0 - это 100 мегабайт
+		lj.MaxSize = maxFileSize
+	}
+	closeFn := lj.Close
+	if f.rotation.Interval > 0 {
+		stop := rotateEvery(lj, f.rotation.Interval)
+		closeFn = func() error {
+			stop()
+			return lj.Close()
+		}
+	}
+	f.closers = append(f.closers, closeFn)
+	// lumberjack сам защищает запись мьютексом
+	return zapcore.AddSync(lj), nil
+}
+
+// close close all opened files
+func (f *files) close() error {
+	var errs []error
+	for _, closeFn := range f.closers {
+		errs = append(errs, closeFn())
+	}
+	f.closers = nil
+	return errors.Join(errs...)
+}
+
+// rotateEvery rotate lj at every multiple of interval. Returned func stops rotation
+func rotateEvery(lj *lumberjack.Logger, interval time.Duration) func() {
+	done := make(chan struct{})
+	go func() {
+		for {
+			now := time.Now()
+			// при интервале 24h ротация происходит в полночь UTC
+			timer := time.NewTimer(now.Truncate(interval).Add(interval).Sub(now))
+			select {
+			case <-done:
+				timer.Stop()
+				return
+			case <-timer.C:
+				if err := lj.Rotate(); err != nil {
+					Log.Error("can not rotate log", zap.String("file", lj.Filename), zap.Error(err))
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
@@ -0,0 +1,136 @@
+package logger
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/serg2014/shortener/internal/config"
+)
+
+// initLog call Init and restore logs after test
+func initLog(t *testing.T, opts Options) func() error {
+	prev := Log
+	closeLog, err := Init(opts)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		Log = prev
+		setAccess(nil, nil, false)
+	})
+	return closeLog
+}
+
+func TestInit(t *testing.T) {
+	dir := t.TempDir()
+	appLog := filepath.Join(dir, "app.log")
+	accessLog := filepath.Join(dir, "access.log")
+	closeLog := initLog(t, Options{
+		Level:         "info",
+		Encoding:      config.LogConsole,
+		Outputs:       []string{appLog},
+		AccessOutputs: []string{accessLog},
+		AccessFormat:  config.LogJSON,
+	})
+
+	Log.Debug("hidden")
+	Log.Info("visible")
+	h := WithLogging(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/abc", nil))
+	require.NoError(t, closeLog())
+
+	data, err := os.ReadFile(appLog)
+	require.NoError(t, err)
+	assert.Regexp(t, `^\S+\tINFO\t\S+\tvisible\n$`, string(data))
+
+	data, err = os.ReadFile(accessLog)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"msg":"got incoming HTTP request"`)
+	assert.Contains(t, string(data), `"uri":"/abc"`)
+
+	_, err = Init(Options{Level: "info", Encoding: "xml"})
+	assert.ErrorIs(t, err, ErrUnknownEncoding)
+	_, err = Init(Options{Level: "loud"})
+	assert.Error(t, err)
+}
+
+func TestInit_combined(t *testing.T) {
+	accessLog := filepath.Join(t.TempDir(), "access.log")
+	closeLog := initLog(t, Options{
+		Level:         "info",
+		AccessOutputs: []string{accessLog},
+		AccessFormat:  config.LogCombined,
+	})
+
+	h := WithLogging(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTemporaryRedirect)
+	}))
+	req := httptest.NewRequest(http.MethodGet, `/abc?q="x"`, nil)
+	req.Header.Set("User-Agent", "curl/8.0")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	require.NoError(t, closeLog())
+
+	data, err := os.ReadFile(accessLog)
+	require.NoError(t, err)
+	assert.Regexp(t,
+		regexp.MustCompile(`^192\.0\.2\.1 - - \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\] "GET /abc\?q=\\"x\\" HTTP/1\.1" 307 - "-" "curl/8\.0"\n$`),
+		string(data))
+}
+
+func TestInit_combinedGRPC(t *testing.T) {
+	accessLog := filepath.Join(t.TempDir(), "access.log")
+	closeLog := initLog(t, Options{
+		Level:         "info",
+		AccessOutputs: []string{accessLog},
+		AccessFormat:  config.LogCombined,
+	})
+
+	info := &grpc.UnaryServerInfo{FullMethod: "/shortener.ShortenerService/GetURL"}
+	_, err := LoggerInterceptor(t.Context(), nil, info, func(ctx context.Context, req any) (any, error) {
+		return nil, status.Error(codes.NotFound, "short url not found")
+	})
+	require.Error(t, err)
+	require.NoError(t, closeLog())
+
+	data, err := os.ReadFile(accessLog)
+	require.NoError(t, err)
+	// в колонке статуса http статус, а не код grpc (NotFound = 5)
+	assert.Regexp(t,
+		regexp.MustCompile(`^\S+ - - \[[^\]]+\] "POST /shortener\.ShortenerService/GetURL HTTP/2\.0" 404 - "-" "-"\n$`),
+		string(data))
+}
+
+func TestHttpStatus(t *testing.T) {
+	assert.Equal(t, http.StatusOK, httpStatus(codes.OK))
+	assert.Equal(t, http.StatusBadRequest, httpStatus(codes.InvalidArgument))
+	assert.Equal(t, http.StatusUnauthorized, httpStatus(codes.Unauthenticated))
+	assert.Equal(t, http.StatusForbidden, httpStatus(codes.PermissionDenied))
+	assert.Equal(t, http.StatusConflict, httpStatus(codes.AlreadyExists))
+	assert.Equal(t, http.StatusInternalServerError, httpStatus(codes.Internal))
+}
+
+func TestInit_rotation(t *testing.T) {
+	dir := t.TempDir()
+	closeLog := initLog(t, Options{
+		Level:    "info",
+		Outputs:  []string{filepath.Join(dir, "app.log")},
+		Rotation: Rotation{Interval: 50 * time.Millisecond},
+	})
+	Log.Info("first")
+	assert.Eventually(t, func() bool {
+		Log.Info("next")
+		entries, err := os.ReadDir(dir)
+		return err == nil && len(entries) > 1
+	}, 2*time.Second, 20*time.Millisecond)
+	require.NoError(t, closeLog())
+}
@@ -0,0 +1,143 @@
+package logger
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/netip"
+	"net/url"
+	"strings"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+)
+
+// Prefixes of anonymized ip in privacy mode
+const (
+	ipv4Prefix = 24
+	ipv6Prefix = 48
+)
+
+// redactedPassword password of userinfo of url in privacy mode
+const redactedPassword = "xxxxx"
+
+// DefaultRedactParams query params removed from logged urls in privacy mode by default
+var DefaultRedactParams = []string{"token", "key", "sig"}
+
+// privacySettings settings of privacy mode
+type privacySettings struct {
+	// params lower case names of removed query params
+	params map[string]struct{}
+}
+
+// privacy settings of privacy mode. Nil - privacy mode is disabled
+var privacy atomic.Pointer[privacySettings]
+
+// setPrivacy enable or disable privacy mode. Empty params - DefaultRedactParams
+func setPrivacy(enabled bool, params []string) {
+	if !enabled {
+		privacy.Store(nil)
+		return
+	}
+	if len(params) == 0 {
+		params = DefaultRedactParams
+	}
+	s := &privacySettings{params: make(map[string]struct{}, len(params))}
+	for _, param := range params {
+		s.params[strings.ToLower(param)] = struct{}{}
+	}
+	privacy.Store(s)
+}
+
+// RedactIP in privacy mode return network of ip: /24 for ipv4 and /48 for ipv6
+func RedactIP(ip string) string {
+	if privacy.Load() == nil || ip == "" {
+		return ip
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		// нераспознанный адрес не пишем
+		return ""
+	}
+	addr = addr.Unmap()
+	bits := ipv6Prefix
+	if addr.Is4() {
+		bits = ipv4Prefix
+	}
+	prefix, err := addr.WithZone("").Prefix(bits)
+	if err != nil {
+		return ""
+	}
+	return prefix.Addr().String()
+}
+
+// RedactUserID in privacy mode return hash of user id. Equal ids have equal hashes
+func RedactUserID(userID string) string {
+	if privacy.Load() == nil || userID == "" {
+		return userID
+	}
+	sum := sha256.Sum256([]byte(userID))
+	return hex.EncodeToString(sum[:8])
+}
+
+// RedactURL in privacy mode remove configured query params and password of url
+func RedactURL(rawURL string) string {
+	s := privacy.Load()
+	if s == nil || rawURL == "" {
+		return rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		// параметры неразобранного url не пишем
+		before, _, _ := strings.Cut(rawURL, "?")
+		return before
+	}
+	if u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), redactedPassword)
+		}
+	}
+	u.RawQuery = s.redactQuery(u.RawQuery)
+	if strings.Contains(u.Fragment, "=") {
+		// токены бывают и во фрагменте (#access_token=...)
+		u.Fragment = s.redactQuery(u.Fragment)
+		u.RawFragment = ""
+	}
+	return u.String()
+}
+
+// redactQuery remove configured params from query
+func (s *privacySettings) redactQuery(query string) string {
+	if query == "" {
+		return query
+	}
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return ""
+	}
+	changed := false
+	for name := range values {
+		if _, ok := s.params[strings.ToLower(name)]; ok {
+			delete(values, name)
+			changed = true
+		}
+	}
+	if !changed {
+		return query
+	}
+	return values.Encode()
+}
+
+// IPField field of zap with ip, see RedactIP
+func IPField(key, ip string) zap.Field {
+	return zap.String(key, RedactIP(ip))
+}
+
+// UserIDField field of zap with user id, see RedactUserID
+func UserIDField(key, userID string) zap.Field {
+	return zap.String(key, RedactUserID(userID))
+}
+
+// URLField field of zap with url, see RedactURL
+func URLField(key, rawURL string) zap.Field {
+	return zap.String(key, RedactURL(rawURL))
+}
@@ -0,0 +1,114 @@
+// Package metrics prometheus metrics of shortener.
+// Metrics are registered in the default registry and exposed by Handler
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace prefix of all metrics
+const namespace = "shortener"
+
+var (
+	// HTTPRequests count of http requests by route, method and status
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Count of HTTP requests by route, method and status.",
+	}, []string{"route", "method", "status"})
+
+	// HTTPDuration latency of http requests by route, method and status
+	HTTPDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of HTTP requests by route, method and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	// GRPCRequests count of grpc requests by method and code
+	GRPCRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "grpc",
+		Name:      "requests_total",
+		Help:      "Count of gRPC requests by method and code.",
+	}, []string{"method", "code"})
+
+	// GRPCDuration latency of grpc requests by method and code
+	GRPCDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "grpc",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of gRPC requests by method and code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "code"})
+
+	// StorageDuration latency of storage operations by backend, operation and result
+	StorageDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "storage",
+		Name:      "operation_duration_seconds",
+		Help:      "Latency of storage operations by backend, operation and result.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"backend", "operation", "result"})
+
+	// DeleteQueueDepth count of messages waiting in delete queue
+	DeleteQueueDepth = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "delete",
+		Name:      "queue_depth",
+		Help:      "Count of delete requests waiting in the queue.",
+	})
+
+	// DeleteQueueRejected count of delete requests rejected because queue is full
+	DeleteQueueRejected = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "delete",
+		Name:      "queue_rejected_total",
+		Help:      "Count of delete requests rejected because the queue is full.",
+	})
+
+	// DeletedURLs count of urls sent to storage by delete workers by result
+	DeletedURLs = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "delete",
+		Name:      "urls_total",
+		Help:      "Count of urls processed by delete workers by result.",
+	}, []string{"result"})
+
+	// KeyCollisions count of generated short keys which already exist
+	KeyCollisions = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "key_collisions_total",
+		Help:      "Count of generated short keys which already exist.",
+	})
+
+	// GzipRatio ratio of compressed size to original size of gzipped responses
+	GzipRatio = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "gzip",
+		Name:      "compression_ratio",
+		Help:      "Ratio of compressed size to original size of gzipped responses.",
+		Buckets:   prometheus.LinearBuckets(0.1, 0.1, 10),
+	})
+)
+
+// Result label value of operation result
+func Result(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
+
+// Handler return handler of metrics in prometheus text format.
+// Response is not compressed, router compresses it by itself
+func Handler() http.Handler {
+	return promhttp.InstrumentMetricHandler(prometheus.DefaultRegisterer,
+		promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{DisableCompression: true}))
+}
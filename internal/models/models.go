@@ -1,6 +1,8 @@
 // Package models contains data type
 package models
 
+import "time"
+
 // Request type
 type Request struct {
 	URL string `json:"url"`
@@ -41,10 +43,76 @@ type ResponseUser []ResponseUserItem
 // RequestForDeleteURLS type for delete urls
 type RequestForDeleteURLS []string
 
+// ResponseToken type for handler GetUserToken
+type ResponseToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// RequestAPIKey type for handler CreateAPIKey
+type RequestAPIKey struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+// ResponseAPIKey api key without secret
+type ResponseAPIKey struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	Scopes     []string  `json:"scopes"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at,omitzero"`
+	RevokedAt  time.Time `json:"revoked_at,omitzero"`
+}
+
+// ResponseCreateAPIKey type for handler CreateAPIKey. Key is shown only once
+type ResponseCreateAPIKey struct {
+	ResponseAPIKey
+	Key string `json:"key"`
+}
+
+// RequestCredentials type for handlers Register and Login
+type RequestCredentials struct {
+	Login    string `json:"login"`
+	Password string `json:"password"`
+}
+
+// ResponseAdminURL url with data for moderators
+type ResponseAdminURL struct {
+	ShortURL    string `json:"short_url"`
+	OriginalURL string `json:"original_url"`
+	UserID      string `json:"user_id"`
+	Deleted     bool   `json:"is_deleted,omitempty"`
+	Disabled    string `json:"disabled,omitempty"`
+	Hits        uint64 `json:"hits"`
+}
+
+// RequestDisableURL type for handler AdminDisableURL. Reason is gone or legal
+type RequestDisableURL struct {
+	Reason string `json:"reason"`
+}
+
 // InternalStats type for handler InternalStats
 type InternalStats struct {
 	Urls  uint `json:"urls"`
 	Users uint `json:"users"`
+	// CacheHits count of Get served from the cache. Only if cache is enabled
+	CacheHits uint64 `json:"cache_hits,omitempty"`
+	// CacheMisses count of Get passed to storage. Only if cache is enabled
+	CacheMisses uint64 `json:"cache_misses,omitempty"`
+	// BloomBits size of the bloom filter in bits. Only if filter is enabled
+	BloomBits uint64 `json:"bloom_bits,omitempty"`
+	// BloomFPRate estimated false positive rate of the bloom filter
+	BloomFPRate float64 `json:"bloom_fp_rate,omitempty"`
+	// BloomRejected count of Get rejected by the bloom filter
+	BloomRejected uint64 `json:"bloom_rejected,omitempty"`
+}
+
+// BuildInfo type for handler Version
+type BuildInfo struct {
+	Version string `json:"version"`
+	Date    string `json:"date"`
+	Commit  string `json:"commit"`
 }
 
 // TODO добавить тесты
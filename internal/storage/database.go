@@ -1,243 +0,0 @@
-package storage
-
-import (
-	"context"
-	"database/sql"
-	"errors"
-	"fmt"
-
-	"github.com/golang-migrate/migrate"
-	"github.com/golang-migrate/migrate/database/postgres"
-	_ "github.com/golang-migrate/migrate/source/file"
-	_ "github.com/jackc/pgx/v5/stdlib"
-	"go.uber.org/zap"
-
-	"github.com/serg2014/shortener/internal/logger"
-	"github.com/serg2014/shortener/internal/models"
-)
-
-// KeyLength length of shorten url
-// must correspond to column short_url in he table short2orig
-const KeyLength = 8
-
-// ErrConflict use this error when save already exist url
-var ErrConflict = errors.New("data conflict")
-
-// ErrDeleted use this error for request deleted url
-var ErrDeleted = errors.New("data deleted")
-
-type storageDB struct {
-	db *sql.DB
-}
-
-// NewStorageDB create db storage type *storageDB
-func NewStorageDB(ctx context.Context, dsn string) (Storager, error) {
-	// dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s sslmode=disable",
-	//  `localhost`, `video`, `XXXXXXXX`, `video`)
-	db, err := sql.Open("pgx", dsn)
-	if err != nil {
-		return nil, err
-	}
-	// проверяем подключение к бд
-	if err = db.Ping(); err != nil {
-		return nil, fmt.Errorf("failed to ping db: %v", err)
-	}
-	logger.Log.Info("Connected to db")
-
-	// миграции
-	driver, err := postgres.WithInstance(db, &postgres.Config{})
-	if err != nil {
-		return nil, err
-	}
-
-	// TODO file://migrations путь задается относительно cwd
-	// предполагается что запуск бинаря происходит в корне репозитория
-	m, err := migrate.NewWithDatabaseInstance(
-		"file://migrations",
-		dsn,
-		driver,
-	)
-	if err != nil {
-		return nil, err
-	}
-	if err = m.Up(); err != nil && err != migrate.ErrNoChange {
-		logger.Log.Fatal("failed to apply migrations", zap.Error(err))
-		return nil, err
-	}
-
-	return &storageDB{db: db}, nil
-}
-
-// Get return orig url by short
-func (storage *storageDB) Get(ctx context.Context, key string) (string, bool, error) {
-	query := "SELECT orig_url, is_deleted FROM short2orig WHERE short_url = $1"
-	row := storage.db.QueryRowContext(ctx, query, key)
-	var value string
-	var deleted bool
-	err := row.Scan(&value, &deleted)
-	if err == nil {
-		if deleted {
-			return "", false, ErrDeleted
-		}
-		return value, true, nil
-	}
-
-	if err == sql.ErrNoRows {
-		return "", false, nil
-	}
-	return "", false, fmt.Errorf("failed get shorturl: %w", err)
-}
-
-// GetUserURLS find all user data in storage
-func (storage *storageDB) GetUserURLS(ctx context.Context, userID string) ([]Item, error) {
-	query := "SELECT short_url, orig_url FROM short2orig WHERE user_id = $1"
-	rows, err := storage.db.QueryContext(ctx, query, userID)
-	if err != nil {
-		logger.Log.Info("select user urls", zap.Error(err))
-		return nil, fmt.Errorf("failed GetUserURLS: %w", err)
-	}
-	// обязательно закрываем перед возвратом функции
-	defer rows.Close()
-
-	result := make([]Item, 0, 1)
-	// пробегаем по всем записям
-	for rows.Next() {
-		var item Item
-		err = rows.Scan(&item.ShortURL, &item.OriginalURL)
-		if err != nil {
-			return nil, fmt.Errorf("failed GetUserURLS: %w", err)
-		}
-		result = append(result, item)
-	}
-	err = rows.Err()
-	if err != nil {
-		return nil, fmt.Errorf("failed GetUserURLS: %w", err)
-	}
-	return result, nil
-}
-
-// GetShort return short url by orig from storage
-func (storage *storageDB) GetShort(ctx context.Context, url string) (string, bool, error) {
-	query := "SELECT short_url FROM short2orig WHERE orig_url = $1"
-	row := storage.db.QueryRowContext(ctx, query, url)
-	var value string
-	err := row.Scan(&value)
-	if err == nil {
-		return value, true, nil
-	}
-
-	if err == sql.ErrNoRows {
-		return "", false, nil
-	}
-	return "", false, fmt.Errorf("failed GetShort: %w", err)
-}
-
-// Set save record in db
-func (storage *storageDB) Set(ctx context.Context, key string, value string, userID string) error {
-	query := `INSERT INTO short2orig (short_url, orig_url, user_id)
-		VALUES ($1, $2, $3)
-		ON CONFLICT (orig_url) DO NOTHING
-	`
-	result, err := storage.db.ExecContext(ctx, query, key, value, userID)
-	if err != nil {
-		return fmt.Errorf("failed Set: %w", err)
-	}
-	ra, _ := result.RowsAffected()
-	if ra == 0 {
-		return ErrConflict
-	}
-
-	return nil
-}
-
-// SetBatch save records in db
-func (storage *storageDB) SetBatch(ctx context.Context, data Short2orig, userID string) error {
-	// начать транзакцию
-	tx, err := storage.db.Begin()
-	if err != nil {
-		return fmt.Errorf("failed SetBatch: %w", err)
-	}
-	defer tx.Rollback()
-
-	query := `INSERT INTO short2orig (short_url, orig_url, user_id)
-		VALUES ($1, $2, $3)
-		ON CONFLICT (orig_url) DO NOTHING
-	`
-	stmt, err := tx.PrepareContext(ctx, query)
-	if err != nil {
-		return fmt.Errorf("failed SetBatch: %w", err)
-	}
-	defer stmt.Close()
-
-	for key, value := range data {
-		_, err := stmt.ExecContext(ctx, key, value, userID)
-		if err != nil {
-			return fmt.Errorf("failed SetBatch: %w", err)
-		}
-	}
-	err = tx.Commit()
-	if err != nil {
-		return fmt.Errorf("failed SetBatch: %w", err)
-	}
-	return nil
-}
-
-// Close close connect to db
-func (storage *storageDB) Close() error {
-	return storage.db.Close()
-}
-
-// Ping check connect ot db
-func (storage *storageDB) Ping(ctx context.Context) error {
-	err := storage.db.PingContext(ctx)
-	if err != nil {
-		return fmt.Errorf("failed Ping: %w", err)
-	}
-	return nil
-}
-
-// DeleteUserURLS delete urls for user
-func (storage *storageDB) DeleteUserURLS(ctx context.Context, data models.RequestForDeleteURLS, userID string) error {
-	// начать транзакцию
-	tx, err := storage.db.Begin()
-	if err != nil {
-		logger.Log.Error("begin", zap.Error(err))
-		return fmt.Errorf("failed DeleteUserURLS: %w", err)
-	}
-	defer tx.Rollback()
-
-	query := `UPDATE short2orig SET is_deleted=true
-		WHERE short_url=$1 and user_id=$2
-	`
-	stmt, err := tx.PrepareContext(ctx, query)
-	if err != nil {
-		logger.Log.Error("prepare", zap.Error(err))
-		return fmt.Errorf("failed DeleteUserURLS: %w", err)
-	}
-	defer stmt.Close()
-
-	for i := range data {
-		_, err := stmt.ExecContext(ctx, data[i], userID)
-		if err != nil {
-			logger.Log.Error("update", zap.Error(err))
-		}
-
-	}
-	err = tx.Commit()
-	if err != nil {
-		return fmt.Errorf("failed DeleteUserURLS: %w", err)
-	}
-	return nil
-}
-
-// InternalStats get stat
-func (storage *storageDB) InternalStats(ctx context.Context) (*models.InternalStats, error) {
-	query := `SELECT count(short_url), count(distinct(user_id)) FROM short2orig`
-	row := storage.db.QueryRowContext(ctx, query)
-	rv := models.InternalStats{}
-	err := row.Scan(&rv.Urls, &rv.Users)
-	if err != nil {
-		return nil, err
-	}
-	return &rv, nil
-}
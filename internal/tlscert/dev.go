@@ -0,0 +1,135 @@
+package tlscert
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+const (
+	// devCertLifetime lifetime of self-signed certificate
+	devCertLifetime = 365 * 24 * time.Hour
+	// devCertRenewBefore certificate is made again when it expires sooner than this
+	devCertRenewBefore = 24 * time.Hour
+)
+
+// Names of files of self-signed certificate in dir
+const (
+	DevCertFile = "dev.crt"
+	DevKeyFile  = "dev.key"
+)
+
+// DefaultDevDir dir for self-signed certificate in cache dir of user
+func DefaultDevDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "shortener", "tls")
+}
+
+// DevHosts return hosts for self-signed certificate: loopback and hosts without duplicates
+func DevHosts(hosts ...string) []string {
+	result := []string{"localhost", "127.0.0.1", "::1"}
+	for _, host := range hosts {
+		if host != "" && !slices.Contains(result, host) {
+			result = append(result, host)
+		}
+	}
+	return result
+}
+
+// DevCert return paths of self-signed certificate for hosts in dir.
+// Certificate is kept in dir between restarts and is made again only when it
+// expires soon or does not cover all hosts
+func DevCert(dir string, hosts []string) (string, string, error) {
+	certFile := filepath.Join(dir, DevCertFile)
+	keyFile := filepath.Join(dir, DevKeyFile)
+	if devCertValid(certFile, keyFile, hosts) {
+		return certFile, keyFile, nil
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", "", err
+	}
+	if err := writeDevCert(certFile, keyFile, hosts); err != nil {
+		return "", "", err
+	}
+	return certFile, keyFile, nil
+}
+
+// devCertValid certificate in files is not expiring and is valid for all hosts
+func devCertValid(certFile, keyFile string, hosts []string) bool {
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return false
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return false
+	}
+	if time.Until(cert.NotAfter) < devCertRenewBefore {
+		return false
+	}
+	for _, host := range hosts {
+		if cert.VerifyHostname(host) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// writeDevCert make self-signed certificate for hosts and write it with key into files
+func writeDevCert(certFile, keyFile string, hosts []string) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	// случайный номер, чтобы браузер не путал перевыпущенные сертификаты
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			Organization: []string{"shortener dev"},
+			CommonName:   hosts[0],
+		},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(devCertLifetime),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		} else {
+			tmpl.DNSNames = append(tmpl.DNSNames, host)
+		}
+	}
+
+	certDER, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return err
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+	// ключ пишем первым: Watch не подхватит новый сертификат со старым ключом
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		return err
+	}
+	return os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}), 0644)
+}
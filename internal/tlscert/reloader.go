@@ -0,0 +1,107 @@
+// Package tlscert load tls certificate from files and reload it without restart.
+// Also it makes persistent self-signed certificate for development
+package tlscert
+
+import (
+	"context"
+	"crypto/tls"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/serg2014/shortener/internal/logger"
+)
+
+// Reloader keep certificate loaded from files. Use GetCertificate in tls.Config,
+// new connections get the new certificate after Reload, open connections are not dropped
+type Reloader struct {
+	certFile string
+	keyFile  string
+	cert     atomic.Pointer[tls.Certificate]
+
+	// m защищает время изменения файлов
+	m           sync.Mutex
+	certModTime time.Time
+	keyModTime  time.Time
+}
+
+// NewReloader load certificate and key from files
+func NewReloader(certFile, keyFile string) (*Reloader, error) {
+	r := &Reloader{certFile: certFile, keyFile: keyFile}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload load certificate and key from files again. On error the old certificate is kept
+func (r *Reloader) Reload() error {
+	r.m.Lock()
+	defer r.m.Unlock()
+	return r.reload()
+}
+
+func (r *Reloader) reload() error {
+	// время берем до чтения, чтобы не пропустить запись во время загрузки
+	certModTime, keyModTime := r.modTimes()
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+	r.cert.Store(&cert)
+	r.certModTime, r.keyModTime = certModTime, keyModTime
+	return nil
+}
+
+// modTimes return time of modification of cert and key files. Zero time if file is not found
+func (r *Reloader) modTimes() (time.Time, time.Time) {
+	var certModTime, keyModTime time.Time
+	if info, err := os.Stat(r.certFile); err == nil {
+		certModTime = info.ModTime()
+	}
+	if info, err := os.Stat(r.keyFile); err == nil {
+		keyModTime = info.ModTime()
+	}
+	return certModTime, keyModTime
+}
+
+// reloadIfChanged reload certificate if files were changed after last load
+func (r *Reloader) reloadIfChanged() (bool, error) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	certModTime, keyModTime := r.modTimes()
+	if certModTime.Equal(r.certModTime) && keyModTime.Equal(r.keyModTime) {
+		return false, nil
+	}
+	return true, r.reload()
+}
+
+// GetCertificate return current certificate. Use it as tls.Config.GetCertificate
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.cert.Load(), nil
+}
+
+// Watch reload certificate when files are changed. Files are checked every interval until ctx is done
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			changed, err := r.reloadIfChanged()
+			if err != nil {
+				// например ключ уже записан, а сертификат еще нет. попробуем на следующем тике
+				logger.Log.Error("can not reload certificate", zap.String("cert", r.certFile), zap.Error(err))
+				continue
+			}
+			if changed {
+				logger.Log.Info("certificate is reloaded", zap.String("cert", r.certFile))
+			}
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package tlscert
+
+import (
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// leaf parse current certificate of reloader
+func leaf(t *testing.T, r *Reloader) *x509.Certificate {
+	cert, err := r.GetCertificate(nil)
+	require.NoError(t, err)
+	parsed, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	return parsed
+}
+
+// copyFile copy file and move its time of modification to future
+func copyFile(t *testing.T, from, to string, mtime time.Time) {
+	data, err := os.ReadFile(from)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(to, data, 0600))
+	require.NoError(t, os.Chtimes(to, mtime, mtime))
+}
+
+func TestDevCert(t *testing.T) {
+	dir := t.TempDir()
+	hosts := DevHosts("short.example", "localhost", "")
+	assert.Equal(t, []string{"localhost", "127.0.0.1", "::1", "short.example"}, hosts)
+
+	certFile, keyFile, err := DevCert(dir, hosts)
+	require.NoError(t, err)
+	r, err := NewReloader(certFile, keyFile)
+	require.NoError(t, err)
+	first := leaf(t, r)
+	for _, host := range hosts {
+		assert.NoError(t, first.VerifyHostname(host), host)
+	}
+
+	// сертификат переживает перезапуск
+	_, _, err = DevCert(dir, hosts)
+	require.NoError(t, err)
+	require.NoError(t, r.Reload())
+	assert.Equal(t, first.SerialNumber, leaf(t, r).SerialNumber)
+
+	// новый хост - новый сертификат
+	_, _, err = DevCert(dir, DevHosts("other.example"))
+	require.NoError(t, err)
+	require.NoError(t, r.Reload())
+	assert.NotEqual(t, first.SerialNumber, leaf(t, r).SerialNumber)
+	assert.NoError(t, leaf(t, r).VerifyHostname("other.example"))
+}
+
+func TestReloader(t *testing.T) {
+	srcA, srcB, dir := t.TempDir(), t.TempDir(), t.TempDir()
+	certA, keyA, err := DevCert(srcA, DevHosts("a.example"))
+	require.NoError(t, err)
+	certB, keyB, err := DevCert(srcB, DevHosts("b.example"))
+	require.NoError(t, err)
+
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	now := time.Now()
+	copyFile(t, certA, certFile, now)
+	copyFile(t, keyA, keyFile, now)
+
+	_, err = NewReloader(filepath.Join(dir, "unknown.crt"), keyFile)
+	require.Error(t, err)
+	r, err := NewReloader(certFile, keyFile)
+	require.NoError(t, err)
+	assert.NoError(t, leaf(t, r).VerifyHostname("a.example"))
+
+	changed, err := r.reloadIfChanged()
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	// файлы поменялись
+	copyFile(t, certB, certFile, now.Add(time.Minute))
+	copyFile(t, keyB, keyFile, now.Add(time.Minute))
+	changed, err = r.reloadIfChanged()
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.NoError(t, leaf(t, r).VerifyHostname("b.example"))
+
+	// битый сертификат не заменяет рабочий
+	require.NoError(t, os.WriteFile(certFile, []byte("bad"), 0600))
+	assert.Error(t, r.Reload())
+	assert.NoError(t, leaf(t, r).VerifyHostname("b.example"))
+}
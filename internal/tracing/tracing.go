@@ -0,0 +1,201 @@
+// Package tracing set up opentelemetry traces of shortener.
+// Trace context of w3c is taken from headers of http and metadata of grpc
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/serg2014/shortener/internal/config"
+)
+
+// serviceName name of service in traces
+const serviceName = "shortener"
+
+// tracerName name of tracer of the app
+const tracerName = "github.com/serg2014/shortener"
+
+// ErrUnknownExporter exporter of traces is not supported
+var ErrUnknownExporter = errors.New("unknown exporter of traces")
+
+// Options settings of tracing, see config.Tracing*
+type Options struct {
+	// Exporter otlp, stdout or file. Empty - spans are not exported
+	Exporter string
+	// Endpoint host:port of otlp collector
+	Endpoint string
+	// Insecure connect to otlp collector without tls
+	Insecure bool
+	// File path to file of exporter file
+	File string
+	// SampleRatio part of sampled traces started by the app. 0 - all traces
+	SampleRatio float64
+	// Version version of the app
+	Version string
+}
+
+func init() {
+	// контекст трассировки передается дальше даже при выключенном экспорте
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+}
+
+// Tracer return tracer of the app
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// Init set global tracer provider by opts. Returned func flushes spans and stops exporter
+func Init(ctx context.Context, opts Options) (func(context.Context) error, error) {
+	if opts.Exporter == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+	exporter, closeFn, err := newExporter(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	sampler := sdktrace.AlwaysSample()
+	if opts.SampleRatio > 0 {
+		sampler = sdktrace.TraceIDRatioBased(opts.SampleRatio)
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		// решение вызывающего сервиса о сэмплировании сохраняется
+		sdktrace.WithSampler(sdktrace.ParentBased(sampler)),
+		sdktrace.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceName(serviceName),
+			semconv.ServiceVersion(opts.Version),
+		)),
+	)
+	otel.SetTracerProvider(tp)
+	return func(ctx context.Context) error {
+		return errors.Join(tp.Shutdown(ctx), closeFn())
+	}, nil
+}
+
+// newExporter create exporter of spans and func which closes its file
+func newExporter(ctx context.Context, opts Options) (sdktrace.SpanExporter, func() error, error) {
+	noClose := func() error { return nil }
+	switch opts.Exporter {
+	case config.TracingOTLP:
+		var clientOpts []otlptracegrpc.Option
+		if opts.Endpoint != "" {
+			clientOpts = append(clientOpts, otlptracegrpc.WithEndpoint(opts.Endpoint))
+		}
+		if opts.Insecure {
+			clientOpts = append(clientOpts, otlptracegrpc.WithInsecure())
+		}
+		exporter, err := otlptracegrpc.New(ctx, clientOpts...)
+		return exporter, noClose, err
+	case config.TracingStdout:
+		exporter, err := stdouttrace.New()
+		return exporter, noClose, err
+	case config.TracingFile:
+		f, err := os.OpenFile(opts.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return nil, nil, fmt.Errorf("can not open file of traces: %w", err)
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(f))
+		if err != nil {
+			f.Close()
+			return nil, nil, err
+		}
+		return exporter, f.Close, nil
+	default:
+		return nil, nil, fmt.Errorf("%w: %s", ErrUnknownExporter, opts.Exporter)
+	}
+}
+
+// TraceID return id of trace of ctx. Empty if ctx has no trace
+func TraceID(ctx context.Context) string {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.HasTraceID() {
+		return ""
+	}
+	return sc.TraceID().String()
+}
+
+// SpanID return id of span of ctx. Empty if ctx has no span
+func SpanID(ctx context.Context) string {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.HasSpanID() {
+		return ""
+	}
+	return sc.SpanID().String()
+}
+
+// End set status of span by err and end it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// statusWriter remember status of response
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader implement WriteHeader of http.ResponseWriter
+func (w *statusWriter) WriteHeader(statusCode int) {
+	if w.status == 0 {
+		w.status = statusCode
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write implement Write of http.ResponseWriter
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Middleware start span of http request with parent from headers traceparent and tracestate.
+// Name of span is pattern of chi route, it is known after routing
+func Middleware(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := Tracer().Start(ctx, r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.URLPath(r.URL.Path),
+			),
+		)
+		defer span.End()
+
+		sw := &statusWriter{ResponseWriter: w}
+		h.ServeHTTP(sw, r.WithContext(ctx))
+
+		if rctx := chi.RouteContext(ctx); rctx != nil && rctx.RoutePattern() != "" {
+			span.SetName(r.Method + " " + rctx.RoutePattern())
+			span.SetAttributes(semconv.HTTPRoute(rctx.RoutePattern()))
+		}
+		if sw.status == 0 {
+			sw.status = http.StatusOK
+		}
+		span.SetAttributes(semconv.HTTPResponseStatusCode(sw.status))
+		if sw.status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(sw.status))
+		}
+	})
+}
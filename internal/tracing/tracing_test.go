@@ -0,0 +1,78 @@
+package tracing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/serg2014/shortener/internal/config"
+)
+
+// setTestProvider set provider which keeps spans in memory
+func setTestProvider(t *testing.T) *tracetest.InMemoryExporter {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(tp)
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+	return exporter
+}
+
+func TestMiddleware(t *testing.T) {
+	exporter := setTestProvider(t)
+
+	var traceID string
+	r := chi.NewRouter()
+	r.Use(Middleware)
+	r.Get("/{key}", func(w http.ResponseWriter, r *http.Request) {
+		traceID = TraceID(r.Context())
+		w.WriteHeader(http.StatusTemporaryRedirect)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/abc", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "GET /{key}", span.Name)
+	assert.Equal(t, trace.SpanKindServer, span.SpanKind)
+	// родитель из заголовка traceparent
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext.TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", span.Parent.SpanID().String())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", traceID)
+}
+
+func TestInit(t *testing.T) {
+	shutdown, err := Init(t.Context(), Options{})
+	require.NoError(t, err)
+	require.NoError(t, shutdown(t.Context()))
+
+	_, err = Init(t.Context(), Options{Exporter: "zipkin"})
+	assert.ErrorIs(t, err, ErrUnknownExporter)
+
+	prev := otel.GetTracerProvider()
+	defer otel.SetTracerProvider(prev)
+	path := filepath.Join(t.TempDir(), "traces.json")
+	shutdown, err = Init(t.Context(), Options{Exporter: config.TracingFile, File: path})
+	require.NoError(t, err)
+	_, span := Tracer().Start(t.Context(), "test")
+	span.End()
+	require.NoError(t, shutdown(t.Context()))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"Name":"test"`)
+}
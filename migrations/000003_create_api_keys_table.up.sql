@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS api_keys (
+		id text PRIMARY KEY,
+		user_id text NOT NULL,
+		name text NOT NULL,
+		hash text NOT NULL,
+		scopes text NOT NULL,
+		created_at timestamptz NOT NULL,
+		last_used_at timestamptz,
+		revoked_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS api_keys_user_id_key ON api_keys (user_id);
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS accounts (
+		login text PRIMARY KEY,
+		user_id text NOT NULL UNIQUE,
+		password_hash text NOT NULL,
+		created_at timestamptz NOT NULL
+);
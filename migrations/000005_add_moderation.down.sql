@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS banned_users;
+ALTER TABLE short2orig DROP COLUMN IF EXISTS hits;
+ALTER TABLE short2orig DROP COLUMN IF EXISTS disabled;
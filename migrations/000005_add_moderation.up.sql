@@ -0,0 +1,5 @@
+ALTER TABLE short2orig ADD COLUMN IF NOT EXISTS disabled text NOT NULL DEFAULT '';
+ALTER TABLE short2orig ADD COLUMN IF NOT EXISTS hits bigint NOT NULL DEFAULT 0;
+CREATE TABLE IF NOT EXISTS banned_users (
+		user_id text PRIMARY KEY
+);
@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"context"
+	"maps"
+	"time"
+)
+
+// AccountStorage is implemented by storages which keep accounts of registered users
+type AccountStorage interface {
+	CreateAccount(ctx context.Context, account Account) error
+	GetAccount(ctx context.Context, login string) (Account, bool, error)
+	GetAccountByUserID(ctx context.Context, userID string) (Account, bool, error)
+	MergeUserURLS(ctx context.Context, fromUserID, toUserID string) error
+}
+
+// Account registered user. Urls of account are saved with its UserID
+type Account struct {
+	Login        string    `json:"login"`
+	UserID       string    `json:"user_id"`
+	PasswordHash string    `json:"password_hash"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func (s *storage) createAccount(account Account) error {
+	if _, ok := s.accounts[account.Login]; ok {
+		return ErrConflict
+	}
+	// у пользователя может быть только один аккаунт
+	if _, ok := s.accountUsers[account.UserID]; ok {
+		return ErrConflict
+	}
+	s.accounts[account.Login] = &account
+	s.accountUsers[account.UserID] = account.Login
+	return nil
+}
+
+// CreateAccount save new account. Return ErrConflict if login or userid is taken
+func (s *storage) CreateAccount(ctx context.Context, account Account) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return s.createAccount(account)
+}
+
+// GetAccount return account by login
+func (s *storage) GetAccount(ctx context.Context, login string) (Account, bool, error) {
+	s.m.RLock()
+	defer s.m.RUnlock()
+	account, ok := s.accounts[login]
+	if !ok {
+		return Account{}, false, nil
+	}
+	return *account, true, nil
+}
+
+// GetAccountByUserID return account of user
+func (s *storage) GetAccountByUserID(ctx context.Context, userID string) (Account, bool, error) {
+	s.m.RLock()
+	defer s.m.RUnlock()
+	login, ok := s.accountUsers[userID]
+	if !ok {
+		return Account{}, false, nil
+	}
+	return *s.accounts[login], true, nil
+}
+
+func (s *storage) mergeUserURLS(fromUserID, toUserID string) {
+	urls, ok := s.users[fromUserID]
+	if !ok || fromUserID == toUserID {
+		return
+	}
+	if _, ok := s.users[toUserID]; !ok {
+		s.users[toUserID] = make(Short2orig, len(urls))
+	}
+	maps.Copy(s.users[toUserID], urls)
+	delete(s.users, fromUserID)
+}
+
+// MergeUserURLS move all urls of fromUserID to toUserID
+func (s *storage) MergeUserURLS(ctx context.Context, fromUserID, toUserID string) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.mergeUserURLS(fromUserID, toUserID)
+	return nil
+}
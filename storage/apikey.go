@@ -0,0 +1,132 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sort"
+	"time"
+)
+
+// ErrNotFound use this error when record is not found
+var ErrNotFound = errors.New("not found")
+
+// APIKeyStorage is implemented by storages which keep api keys of users
+type APIKeyStorage interface {
+	CreateAPIKey(ctx context.Context, key APIKey) error
+	GetAPIKey(ctx context.Context, id string) (APIKey, bool, error)
+	GetUserAPIKeys(ctx context.Context, userID string) ([]APIKey, error)
+	RevokeAPIKey(ctx context.Context, userID, id string, at time.Time) error
+	TouchAPIKey(ctx context.Context, id string, at time.Time) error
+}
+
+// APIKey key of service client. Secret of the key is not stored, only its hash
+type APIKey struct {
+	ID         string    `json:"id"`
+	UserID     string    `json:"user_id"`
+	Name       string    `json:"name"`
+	Hash       string    `json:"hash"`
+	Scopes     []string  `json:"scopes"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at,omitzero"`
+	RevokedAt  time.Time `json:"revoked_at,omitzero"`
+}
+
+// Revoked key can not be used any more
+func (k *APIKey) Revoked() bool {
+	return !k.RevokedAt.IsZero()
+}
+
+func (k *APIKey) clone() APIKey {
+	c := *k
+	c.Scopes = slices.Clone(k.Scopes)
+	return c
+}
+
+func (s *storage) createAPIKey(key APIKey) error {
+	if _, ok := s.apiKeys[key.ID]; ok {
+		return ErrConflict
+	}
+	c := key.clone()
+	s.apiKeys[key.ID] = &c
+	return nil
+}
+
+// CreateAPIKey save new api key
+func (s *storage) CreateAPIKey(ctx context.Context, key APIKey) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return s.createAPIKey(key)
+}
+
+// GetAPIKey return api key by id
+func (s *storage) GetAPIKey(ctx context.Context, id string) (APIKey, bool, error) {
+	s.m.RLock()
+	defer s.m.RUnlock()
+	key, ok := s.apiKeys[id]
+	if !ok {
+		return APIKey{}, false, nil
+	}
+	return key.clone(), true, nil
+}
+
+// GetUserAPIKeys return all api keys of user including revoked ones. Oldest first
+func (s *storage) GetUserAPIKeys(ctx context.Context, userID string) ([]APIKey, error) {
+	s.m.RLock()
+	defer s.m.RUnlock()
+	result := make([]APIKey, 0)
+	for _, key := range s.apiKeys {
+		if key.UserID == userID {
+			result = append(result, key.clone())
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].CreatedAt.Equal(result[j].CreatedAt) {
+			return result[i].ID < result[j].ID
+		}
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+	return result, nil
+}
+
+// revokeAPIKey return changed key. Key of other user is not found
+func (s *storage) revokeAPIKey(userID, id string, at time.Time) (*APIKey, error) {
+	key, ok := s.apiKeys[id]
+	if !ok || key.UserID != userID {
+		return nil, ErrNotFound
+	}
+	if key.Revoked() {
+		return nil, nil
+	}
+	key.RevokedAt = at
+	return key, nil
+}
+
+// RevokeAPIKey mark api key of user as revoked. Revoke of revoked key is not an error
+func (s *storage) RevokeAPIKey(ctx context.Context, userID, id string, at time.Time) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+	_, err := s.revokeAPIKey(userID, id, at)
+	return err
+}
+
+// touchAPIKey return changed key
+func (s *storage) touchAPIKey(id string, at time.Time) (*APIKey, error) {
+	key, ok := s.apiKeys[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if !at.After(key.LastUsedAt) {
+		return nil, nil
+	}
+	key.LastUsedAt = at
+	return key, nil
+}
+
+// TouchAPIKey save time of last usage of api key
+func (s *storage) TouchAPIKey(ctx context.Context, id string, at time.Time) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+	_, err := s.touchAPIKey(id, at)
+	return err
+}
@@ -0,0 +1,385 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/serg2014/shortener/internal/models"
+)
+
+const (
+	// DefaultBloomCapacity expected count of urls in the bloom filter
+	DefaultBloomCapacity = 1_000_000
+	// DefaultBloomFPRate expected false positive rate of the bloom filter
+	DefaultBloomFPRate = 0.01
+	// bloomListenTimeout max wait of listening of events before the first build of the filter
+	bloomListenTimeout = 10 * time.Second
+)
+
+// ErrNoKeyLister storage can not list its urls to build the bloom filter
+var ErrNoKeyLister = errors.New("storage can not list keys")
+
+// KeyLister is implemented by storages which can list all short urls
+type KeyLister interface {
+	// ForEachKey calls fn for every short url including deleted ones
+	ForEachKey(ctx context.Context, fn func(key string)) error
+}
+
+// bloomFilter set of keys with false positives and without false negatives
+type bloomFilter struct {
+	bits []uint64
+	m    uint64
+	k    uint64
+	// n count of keys for which the filter is sized
+	n     uint64
+	count uint64
+	mu    sync.RWMutex
+}
+
+// newBloomFilter create filter for n keys with false positive rate p
+func newBloomFilter(n uint, p float64) *bloomFilter {
+	n = max(n, 1)
+	m := uint64(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	m = max((m+63)/64*64, 64)
+	k := uint64(math.Round(float64(m) / float64(n) * math.Ln2))
+	return &bloomFilter{
+		bits: make([]uint64, m/64),
+		m:    m,
+		k:    max(k, 1),
+		n:    uint64(n),
+	}
+}
+
+// hashes two hashes of the key for double hashing
+func bloomHashes(key string) (uint64, uint64) {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	h1 := h.Sum64()
+	// второй хеш нечетный, чтобы не зацикливаться на части битов
+	h2 := h1>>33 | h1<<31 | 1
+	return h1, h2
+}
+
+func (f *bloomFilter) add(key string) {
+	h1, h2 := bloomHashes(key)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := uint64(0); i < f.k; i++ {
+		pos := (h1 + i*h2) % f.m
+		f.bits[pos/64] |= 1 << (pos % 64)
+	}
+	f.count++
+}
+
+// test false - key is definitely absent
+func (f *bloomFilter) test(key string) bool {
+	h1, h2 := bloomHashes(key)
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	for i := uint64(0); i < f.k; i++ {
+		pos := (h1 + i*h2) % f.m
+		if f.bits[pos/64]&(1<<(pos%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// full more keys are added than the filter is sized for, false positive rate is higher than expected
+func (f *bloomFilter) full() bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.count > f.n
+}
+
+// fpRate estimated false positive rate for added keys
+func (f *bloomFilter) fpRate() float64 {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return math.Pow(1-math.Exp(-float64(f.k*f.count)/float64(f.m)), float64(f.k))
+}
+
+// bloomStorage reject Get of unknown urls without request to storage.
+// Urls are never removed from the filter, so deleted urls still get ErrDeleted
+type bloomStorage struct {
+	Storager
+	lister   KeyLister
+	capacity uint
+	p        float64
+	mu       sync.RWMutex
+	filter   *bloomFilter
+	// rebuilding filter which is filled now. Added urls go into both filters
+	rebuilding *bloomFilter
+	// inflight urls added into the filter which are being saved now. Scan of storage by rebuild
+	// could miss them, so they are added into the rebuilt filter
+	inflight   map[string]int
+	inflightMu sync.Mutex
+	// rebuildMu only one rebuild at a time
+	rebuildMu sync.Mutex
+	// grow signal to rebuild the filter after growth of storage
+	grow     chan struct{}
+	rejected atomic.Uint64
+	// stop stop background work: listening of Invalidation events and rebuilds after growth
+	stop context.CancelFunc
+	wg   sync.WaitGroup
+}
+
+// NewBloomStorage wrap store with bloom filter of all short urls.
+// capacity and p <= 0 mean DefaultBloomCapacity and DefaultBloomFPRate.
+// The filter is rebuilt with bigger size when storage grows over its capacity.
+// Store (or storage wrapped by it) must implement KeyLister.
+// If it implements Invalidator, urls created by other instances are added to the filter,
+// the filter is built after listening of events starts.
+// Background work is stopped when ctx is done or the storage is closed
+func NewBloomStorage(ctx context.Context, store Storager, capacity uint, p float64) (Storager, error) {
+	lister, ok := findStorage[KeyLister](store)
+	if !ok {
+		return nil, ErrNoKeyLister
+	}
+	if capacity == 0 {
+		capacity = DefaultBloomCapacity
+	}
+	if p <= 0 || p >= 1 {
+		p = DefaultBloomFPRate
+	}
+	b := &bloomStorage{
+		Storager: store,
+		lister:   lister,
+		capacity: capacity,
+		p:        p,
+		inflight: make(map[string]int),
+		grow:     make(chan struct{}, 1),
+	}
+	ctx, b.stop = context.WithCancel(ctx)
+	inv, ok := findStorage[Invalidator](store)
+	if !ok {
+		if err := b.rebuild(ctx); err != nil {
+			b.stop()
+			return nil, err
+		}
+		b.startGrowth(ctx)
+		return b, nil
+	}
+
+	// подписка начинается до обхода хранилища, иначе урлы других инстансов,
+	// сохраненные между обходом и LISTEN, никогда не попадут в фильтр
+	ready := make(chan error, 1)
+	var listening atomic.Bool
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		inv.Subscribe(ctx, func(event Invalidation) {
+			if event.Op == InvalidationReset && listening.CompareAndSwap(false, true) {
+				err := b.rebuild(ctx)
+				if err != nil {
+					storageLog(ctx).Error("build bloom filter", zap.Error(err))
+				}
+				ready <- err
+				return
+			}
+			b.onInvalidation(ctx, event)
+		})
+	}()
+	var err error
+	select {
+	case err = <-ready:
+	case <-time.After(bloomListenTimeout):
+		// фильтр строится без подписки и перестраивается после подключения
+		storageLog(ctx).Warn("bloom filter is built before listening of invalidations")
+		err = b.rebuild(ctx)
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	if err != nil {
+		b.stop()
+		b.wg.Wait()
+		return nil, err
+	}
+	b.startGrowth(ctx)
+	return b, nil
+}
+
+// startGrowth start rebuilds of the filter after growth of storage
+func (b *bloomStorage) startGrowth(ctx context.Context) {
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		b.rebuildOnGrowth(ctx)
+	}()
+}
+
+// rebuild fill new filter from storage. Urls added meanwhile are not lost
+func (b *bloomStorage) rebuild(ctx context.Context) error {
+	b.rebuildMu.Lock()
+	defer b.rebuildMu.Unlock()
+
+	stats, err := b.Storager.InternalStats(ctx)
+	if err != nil {
+		return err
+	}
+	// запас на рост, чтобы не превысить заданный false positive rate
+	capacity := max(b.capacity, 2*stats.Urls)
+
+	next := newBloomFilter(capacity, b.p)
+	b.mu.Lock()
+	b.rebuilding = next
+	// сохранение этих урлов могло начаться до перестройки и закончиться после обхода хранилища
+	b.inflightMu.Lock()
+	for key := range b.inflight {
+		next.add(key)
+	}
+	b.inflightMu.Unlock()
+	b.mu.Unlock()
+
+	err = b.lister.ForEachKey(ctx, next.add)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rebuilding = nil
+	if err != nil {
+		return err
+	}
+	b.filter = next
+	return nil
+}
+
+// rebuildOnGrowth rebuild the filter with bigger size when it is full until ctx is done
+func (b *bloomStorage) rebuildOnGrowth(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-b.grow:
+			if err := b.rebuild(ctx); err != nil {
+				storageLog(ctx).Error("rebuild bloom filter", zap.Error(err))
+			}
+		}
+	}
+}
+
+// addLocked put keys into the filter and signal rebuild when it is full. b.mu must be held
+func (b *bloomStorage) addLocked(keys ...string) {
+	for _, key := range keys {
+		if b.filter != nil {
+			b.filter.add(key)
+		}
+		if b.rebuilding != nil {
+			b.rebuilding.add(key)
+		}
+	}
+	if b.filter != nil && b.filter.full() {
+		select {
+		case b.grow <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// add put saved keys into the filter
+func (b *bloomStorage) add(keys ...string) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	b.addLocked(keys...)
+}
+
+// begin put keys into the filter before saving. Call done after saving
+func (b *bloomStorage) begin(keys ...string) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	b.addLocked(keys...)
+	b.inflightMu.Lock()
+	defer b.inflightMu.Unlock()
+	for _, key := range keys {
+		b.inflight[key]++
+	}
+}
+
+// done saving of keys is finished
+func (b *bloomStorage) done(keys ...string) {
+	b.inflightMu.Lock()
+	defer b.inflightMu.Unlock()
+	for _, key := range keys {
+		if b.inflight[key] <= 1 {
+			delete(b.inflight, key)
+			continue
+		}
+		b.inflight[key]--
+	}
+}
+
+// onInvalidation add urls created by any instance
+func (b *bloomStorage) onInvalidation(ctx context.Context, event Invalidation) {
+	switch event.Op {
+	case InvalidationSet:
+		b.add(event.Keys...)
+	case InvalidationReset:
+		if err := b.rebuild(ctx); err != nil {
+			storageLog(ctx).Error("rebuild bloom filter", zap.Error(err))
+		}
+	}
+}
+
+// Unwrap return wrapped storage
+func (b *bloomStorage) Unwrap() Storager {
+	return b.Storager
+}
+
+// Close stop background work and close storage
+func (b *bloomStorage) Close() error {
+	b.stop()
+	b.wg.Wait()
+	return b.Storager.Close()
+}
+
+// Get return orig url by short. Unknown urls are rejected by the filter
+func (b *bloomStorage) Get(ctx context.Context, key string) (string, bool, error) {
+	b.mu.RLock()
+	maybe := b.filter.test(key)
+	b.mu.RUnlock()
+	if !maybe {
+		b.rejected.Add(1)
+		return "", false, nil
+	}
+	return b.Storager.Get(ctx, key)
+}
+
+// Set add url into the filter and save record in storage.
+// Url is added before saving, so concurrent Get can not miss it
+func (b *bloomStorage) Set(ctx context.Context, key string, value string, userID string) error {
+	b.begin(key)
+	defer b.done(key)
+	return b.Storager.Set(ctx, key, value, userID)
+}
+
+// SetBatch add urls into the filter and save records in storage
+func (b *bloomStorage) SetBatch(ctx context.Context, data Short2orig, userID string) error {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	b.begin(keys...)
+	defer b.done(keys...)
+	return b.Storager.SetBatch(ctx, data, userID)
+}
+
+// InternalStats get stat of storage with size of the filter
+func (b *bloomStorage) InternalStats(ctx context.Context) (*models.InternalStats, error) {
+	stats, err := b.Storager.InternalStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	b.mu.RLock()
+	filter := b.filter
+	b.mu.RUnlock()
+	stats.BloomBits = filter.m
+	stats.BloomFPRate = filter.fpRate()
+	stats.BloomRejected = b.rejected.Load()
+	return stats, nil
+}
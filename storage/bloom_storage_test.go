@@ -0,0 +1,227 @@
+package storage_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/serg2014/shortener/internal/models"
+	"github.com/serg2014/shortener/storage"
+	"github.com/serg2014/shortener/storage/mock"
+)
+
+func TestBloomStorage(t *testing.T) {
+	s, err := storage.NewStorageMemory()
+	require.NoError(t, err)
+	require.NoError(t, s.Set(t.Context(), "a1234567", "http://one.ru", "user1"))
+
+	bloom, err := storage.NewBloomStorage(t.Context(), s, 100, 0.01)
+	require.NoError(t, err)
+
+	// ключ из хранилища попал в фильтр при старте
+	val, ok, err := bloom.Get(t.Context(), "a1234567")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "http://one.ru", val)
+
+	_, ok, err = bloom.Get(t.Context(), "unknown1")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, bloom.SetBatch(t.Context(), storage.Short2orig{"a1234568": "http://two.ru"}, "user1"))
+	_, ok, err = bloom.Get(t.Context(), "a1234568")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// удаленные урлы остаются в фильтре
+	err = bloom.DeleteUserURLS(t.Context(), []storage.Message{{UserID: "user1", ShortURL: []string{"a1234567"}}})
+	require.NoError(t, err)
+	_, _, err = bloom.Get(t.Context(), "a1234567")
+	assert.ErrorIs(t, err, storage.ErrDeleted)
+
+	stats, err := bloom.InternalStats(t.Context())
+	require.NoError(t, err)
+	assert.NotZero(t, stats.BloomBits)
+	assert.Greater(t, stats.BloomFPRate, 0.0)
+	assert.Equal(t, uint64(1), stats.BloomRejected)
+}
+
+func TestBloomStorage_rejectWithoutStorage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mock.NewMockStorager(ctrl)
+
+	// mock не умеет перечислять ключи
+	_, err := storage.NewBloomStorage(t.Context(), store, 100, 0.01)
+	assert.ErrorIs(t, err, storage.ErrNoKeyLister)
+
+	s, err := storage.NewStorageMemory()
+	require.NoError(t, err)
+	store.EXPECT().InternalStats(gomock.Any()).Return(&models.InternalStats{}, nil)
+	// обертка над mock находит KeyLister через Unwrap
+	bloom, err := storage.NewBloomStorage(t.Context(), &listerStorage{Storager: store, lister: s.(storage.KeyLister)}, 100, 0.01)
+	require.NoError(t, err)
+	store.EXPECT().Get(gomock.Any(), gomock.Any()).Times(0)
+	for _, key := range []string{"unknown1", "unknown2", "unknown3"} {
+		_, ok, err := bloom.Get(t.Context(), key)
+		require.NoError(t, err)
+		assert.False(t, ok)
+	}
+}
+
+func TestBloomStorage_invalidator(t *testing.T) {
+	shared, err := storage.NewStorageMemory()
+	require.NoError(t, err)
+	inv := &invalidatorStorage{
+		Storager:  shared,
+		events:    make(chan storage.Invalidation),
+		processed: make(chan struct{}),
+	}
+	bloom, err := storage.NewBloomStorage(t.Context(), inv, 100, 0.01)
+	require.NoError(t, err)
+	defer bloom.Close()
+
+	// другой инстанс создает урл в обход фильтра
+	require.NoError(t, shared.Set(t.Context(), "a1234567", "http://one.ru", "user1"))
+	require.NoError(t, shared.Set(t.Context(), "a1234568", "http://two.ru", "user1"))
+	_, ok, err := bloom.Get(t.Context(), "a1234567")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	inv.send(storage.Invalidation{Op: storage.InvalidationSet, Keys: []string{"a1234567"}})
+	_, ok, err = bloom.Get(t.Context(), "a1234567")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// события могли потеряться: фильтр строится заново
+	inv.send(storage.Invalidation{Op: storage.InvalidationReset})
+	_, ok, err = bloom.Get(t.Context(), "a1234568")
+	require.NoError(t, err)
+	require.True(t, ok)
+}
+
+// startupStorage shared storage. Other instance saves url after subscription and before LISTEN,
+// so event about it is never sent
+type startupStorage struct {
+	storage.Storager
+	beforeListen func()
+}
+
+func (s *startupStorage) Subscribe(ctx context.Context, fn func(storage.Invalidation)) {
+	s.beforeListen()
+	fn(storage.Invalidation{Op: storage.InvalidationReset})
+	<-ctx.Done()
+}
+
+func (s *startupStorage) Unwrap() storage.Storager {
+	return s.Storager
+}
+
+func TestBloomStorage_saveDuringStartup(t *testing.T) {
+	shared, err := storage.NewStorageMemory()
+	require.NoError(t, err)
+	require.NoError(t, shared.Set(t.Context(), "a1234567", "http://one.ru", "user1"))
+	startup := &startupStorage{Storager: shared, beforeListen: func() {
+		require.NoError(t, shared.Set(t.Context(), "a1234568", "http://two.ru", "user2"))
+	}}
+
+	bloom, err := storage.NewBloomStorage(t.Context(), startup, 100, 0.01)
+	require.NoError(t, err)
+	defer bloom.Close()
+
+	// фильтр строится после начала прослушивания и видит оба урла
+	for _, key := range []string{"a1234567", "a1234568"} {
+		_, ok, err := bloom.Get(t.Context(), key)
+		require.NoError(t, err)
+		assert.True(t, ok, key)
+	}
+}
+
+// listerStorage storage which can list keys only
+type listerStorage struct {
+	storage.Storager
+	lister storage.KeyLister
+}
+
+func (s *listerStorage) Unwrap() storage.Storager {
+	return s.lister.(storage.Storager)
+}
+
+// blockingStorage storage whose Set waits for release before saving
+type blockingStorage struct {
+	storage.Storager
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingStorage) Set(ctx context.Context, key string, value string, userID string) error {
+	s.started <- struct{}{}
+	<-s.release
+	return s.Storager.Set(ctx, key, value, userID)
+}
+
+func (s *blockingStorage) Unwrap() storage.Storager {
+	return s.Storager
+}
+
+func TestBloomStorage_rebuildDuringSet(t *testing.T) {
+	shared, err := storage.NewStorageMemory()
+	require.NoError(t, err)
+	inv := &invalidatorStorage{
+		Storager:  shared,
+		events:    make(chan storage.Invalidation),
+		processed: make(chan struct{}),
+	}
+	blocking := &blockingStorage{Storager: inv, started: make(chan struct{}), release: make(chan struct{})}
+	bloom, err := storage.NewBloomStorage(t.Context(), blocking, 100, 0.01)
+	require.NoError(t, err)
+	defer bloom.Close()
+
+	setDone := make(chan error)
+	go func() {
+		setDone <- bloom.Set(t.Context(), "a1234567", "http://one.ru", "user1")
+	}()
+	// урл уже в фильтре, но еще не сохранен
+	<-blocking.started
+	// перестройка не видит урл в хранилище
+	inv.send(storage.Invalidation{Op: storage.InvalidationReset})
+	close(blocking.release)
+	require.NoError(t, <-setDone)
+
+	_, ok, err := bloom.Get(t.Context(), "a1234567")
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestBloomStorage_grow(t *testing.T) {
+	s, err := storage.NewStorageMemory()
+	require.NoError(t, err)
+	bloom, err := storage.NewBloomStorage(t.Context(), s, 10, 0.01)
+	require.NoError(t, err)
+	defer bloom.Close()
+
+	stats, err := bloom.InternalStats(t.Context())
+	require.NoError(t, err)
+	bits := stats.BloomBits
+
+	keys := make([]string, 100)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("a%07d", i)
+		require.NoError(t, bloom.Set(t.Context(), keys[i], "http://"+keys[i], "user1"))
+	}
+	// фильтр перестраивается под выросшее хранилище
+	assert.Eventually(t, func() bool {
+		stats, err := bloom.InternalStats(t.Context())
+		require.NoError(t, err)
+		return stats.BloomBits > bits && stats.BloomFPRate < 0.02
+	}, time.Second, time.Millisecond)
+	for _, key := range keys {
+		_, ok, err := bloom.Get(t.Context(), key)
+		require.NoError(t, err)
+		assert.True(t, ok, key)
+	}
+}
@@ -0,0 +1,28 @@
+package storage
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_bloomFilter(t *testing.T) {
+	const n = 10000
+	f := newBloomFilter(n, 0.01)
+	for i := 0; i < n; i++ {
+		f.add(fmt.Sprintf("a%07d", i))
+	}
+	// ложноотрицательных срабатываний не бывает
+	for i := 0; i < n; i++ {
+		assert.True(t, f.test(fmt.Sprintf("a%07d", i)))
+	}
+	fp := 0
+	for i := 0; i < n; i++ {
+		if f.test(fmt.Sprintf("b%07d", i)) {
+			fp++
+		}
+	}
+	assert.Less(t, float64(fp)/n, 0.02)
+	assert.InDelta(t, 0.01, f.fpRate(), 0.005)
+}
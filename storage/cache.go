@@ -0,0 +1,302 @@
+package storage
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/serg2014/shortener/internal/models"
+)
+
+const (
+	// DefaultCacheTTL time while found url lives in the cache
+	DefaultCacheTTL = time.Minute
+	// DefaultCacheNegativeTTL time while unknown url lives in the cache
+	DefaultCacheNegativeTTL = 10 * time.Second
+)
+
+// cacheState result of Get saved in the cache
+type cacheState int
+
+const (
+	cacheFound cacheState = iota
+	cacheNotFound
+	cacheDeleted
+	cacheDisabled
+	cacheDisabledLegal
+)
+
+type cacheEntry struct {
+	expire time.Time
+	key    string
+	value  string
+	state  cacheState
+}
+
+// result return values of Get for saved entry
+func (e *cacheEntry) result() (string, bool, error) {
+	switch e.state {
+	case cacheFound:
+		return e.value, true, nil
+	case cacheDeleted:
+		return "", false, ErrDeleted
+	case cacheDisabled:
+		return "", false, ErrDisabled
+	case cacheDisabledLegal:
+		return "", false, ErrDisabledLegal
+	default:
+		return "", false, nil
+	}
+}
+
+// cachedStorage read-through cache for Get. Size of the cache is bounded, old entries are evicted by LRU.
+// Unknown and deleted urls are cached too. Concurrent Get of the same key make one request to storage
+type cachedStorage struct {
+	Storager
+	group       singleflight.Group
+	entries     map[string]*list.Element
+	lru         *list.List
+	m           sync.Mutex
+	size        int
+	ttl         time.Duration
+	negativeTTL time.Duration
+	// generation увеличивается при каждой инвалидации.
+	// Результат запроса, начатого до инвалидации, в кеш не сохраняется
+	generation uint64
+	hits       atomic.Uint64
+	misses     atomic.Uint64
+	// stopSubscribe stop listening of Invalidation events from other instances
+	stopSubscribe context.CancelFunc
+	subscribeDone chan struct{}
+}
+
+// NewCachedStorage wrap store with cache for size entries.
+// ttl and negativeTTL <= 0 mean DefaultCacheTTL and DefaultCacheNegativeTTL.
+// If store implements Invalidator, changes made by other instances are evicted from the cache
+// until ctx is done or the storage is closed
+func NewCachedStorage(ctx context.Context, store Storager, size int, ttl, negativeTTL time.Duration) Storager {
+	if ttl <= 0 {
+		ttl = DefaultCacheTTL
+	}
+	if negativeTTL <= 0 {
+		negativeTTL = DefaultCacheNegativeTTL
+	}
+	c := &cachedStorage{
+		Storager:    store,
+		entries:     make(map[string]*list.Element, size),
+		lru:         list.New(),
+		size:        size,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+	}
+	if inv, ok := findStorage[Invalidator](store); ok {
+		ctx, cancel := context.WithCancel(ctx)
+		c.stopSubscribe = cancel
+		c.subscribeDone = make(chan struct{})
+		go func() {
+			defer close(c.subscribeDone)
+			inv.Subscribe(ctx, c.onInvalidation)
+		}()
+	}
+	return c
+}
+
+// onInvalidation evict urls changed by any instance
+func (c *cachedStorage) onInvalidation(event Invalidation) {
+	if event.Op == InvalidationReset {
+		c.Purge()
+		return
+	}
+	c.Invalidate(event.Keys...)
+}
+
+// Unwrap return wrapped storage
+func (c *cachedStorage) Unwrap() Storager {
+	return c.Storager
+}
+
+// Close stop listening of events and close storage
+func (c *cachedStorage) Close() error {
+	if c.stopSubscribe != nil {
+		c.stopSubscribe()
+		<-c.subscribeDone
+	}
+	return c.Storager.Close()
+}
+
+// lookup return not expired entry from the cache
+func (c *cachedStorage) lookup(key string) (*cacheEntry, bool) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*cacheEntry)
+	if time.Now().After(entry.expire) {
+		c.lru.Remove(elem)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.lru.MoveToFront(elem)
+	return entry, true
+}
+
+// save put entry into the cache if there was no invalidation after generation
+func (c *cachedStorage) save(entry *cacheEntry, generation uint64) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	if c.generation != generation {
+		return
+	}
+	if elem, ok := c.entries[entry.key]; ok {
+		elem.Value = entry
+		c.lru.MoveToFront(elem)
+		return
+	}
+	c.entries[entry.key] = c.lru.PushFront(entry)
+	for c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// Invalidate remove keys from the cache
+func (c *cachedStorage) Invalidate(keys ...string) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	c.generation++
+	for _, key := range keys {
+		if elem, ok := c.entries[key]; ok {
+			c.lru.Remove(elem)
+			delete(c.entries, key)
+		}
+	}
+}
+
+// Purge remove all entries from the cache
+func (c *cachedStorage) Purge() {
+	c.m.Lock()
+	defer c.m.Unlock()
+	c.generation++
+	clear(c.entries)
+	c.lru.Init()
+}
+
+// Get return orig url by short from the cache or storage
+func (c *cachedStorage) Get(ctx context.Context, key string) (string, bool, error) {
+	if entry, ok := c.lookup(key); ok {
+		c.hits.Add(1)
+		return entry.result()
+	}
+	c.misses.Add(1)
+
+	res, err, _ := c.group.Do(key, func() (any, error) {
+		c.m.Lock()
+		generation := c.generation
+		c.m.Unlock()
+
+		// запрос общий для всех ожидающих, поэтому не зависит от отмены контекста первого
+		value, ok, err := c.Storager.Get(context.WithoutCancel(ctx), key)
+		entry := &cacheEntry{key: key, value: value, state: cacheFound, expire: time.Now().Add(c.ttl)}
+		switch {
+		case errors.Is(err, ErrDeleted):
+			entry.state = cacheDeleted
+		case errors.Is(err, ErrDisabled):
+			entry.state = cacheDisabled
+		case errors.Is(err, ErrDisabledLegal):
+			entry.state = cacheDisabledLegal
+		case err != nil:
+			return nil, err
+		case !ok:
+			entry.state = cacheNotFound
+			entry.expire = time.Now().Add(c.negativeTTL)
+		}
+		c.save(entry, generation)
+		return entry, nil
+	})
+	if err != nil {
+		return "", false, err
+	}
+	return res.(*cacheEntry).result()
+}
+
+// Set save record in storage and invalidate cached negative result
+func (c *cachedStorage) Set(ctx context.Context, key string, value string, userID string) error {
+	defer c.Invalidate(key)
+	return c.Storager.Set(ctx, key, value, userID)
+}
+
+// SetBatch save records in storage and invalidate cached negative results
+func (c *cachedStorage) SetBatch(ctx context.Context, data Short2orig, userID string) error {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	defer c.Invalidate(keys...)
+	return c.Storager.SetBatch(ctx, data, userID)
+}
+
+// DeleteUserURLS delete urls in storage and remove them from the cache
+func (c *cachedStorage) DeleteUserURLS(ctx context.Context, batch []Message) error {
+	keys := make([]string, 0, len(batch))
+	for i := range batch {
+		keys = append(keys, batch[i].ShortURL...)
+	}
+	defer c.Invalidate(keys...)
+	return c.Storager.DeleteUserURLS(ctx, batch)
+}
+
+// DisableURL disable url in storage and remove it from the cache
+func (c *cachedStorage) DisableURL(ctx context.Context, key, reason string) error {
+	store, err := optional[ModerationStorage](c.Storager)
+	if err != nil {
+		return err
+	}
+	defer c.Invalidate(key)
+	return store.DisableURL(ctx, key, reason)
+}
+
+// SearchURLS implement SearchURLS of ModerationStorage. Cache is not used
+func (c *cachedStorage) SearchURLS(ctx context.Context, filter URLFilter) ([]URLInfo, error) {
+	store, err := optional[ModerationStorage](c.Storager)
+	if err != nil {
+		return nil, err
+	}
+	return store.SearchURLS(ctx, filter)
+}
+
+// SetUserBanned implement SetUserBanned of ModerationStorage. Cache is not used
+func (c *cachedStorage) SetUserBanned(ctx context.Context, userID string, banned bool) error {
+	store, err := optional[ModerationStorage](c.Storager)
+	if err != nil {
+		return err
+	}
+	return store.SetUserBanned(ctx, userID, banned)
+}
+
+// IsUserBanned implement IsUserBanned of ModerationStorage. Cache is not used
+func (c *cachedStorage) IsUserBanned(ctx context.Context, userID string) (bool, error) {
+	store, err := optional[ModerationStorage](c.Storager)
+	if err != nil {
+		return false, err
+	}
+	return store.IsUserBanned(ctx, userID)
+}
+
+// InternalStats get stat of storage with cache hits and misses
+func (c *cachedStorage) InternalStats(ctx context.Context) (*models.InternalStats, error) {
+	stats, err := c.Storager.InternalStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stats.CacheHits = c.hits.Load()
+	stats.CacheMisses = c.misses.Load()
+	return stats, nil
+}
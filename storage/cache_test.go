@@ -0,0 +1,255 @@
+package storage_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/serg2014/shortener/internal/models"
+	"github.com/serg2014/shortener/storage"
+	"github.com/serg2014/shortener/storage/mock"
+)
+
+func TestCachedStorage_Get(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mock.NewMockStorager(ctrl)
+	cache := storage.NewCachedStorage(t.Context(), store, 10, time.Minute, time.Minute)
+
+	// в хранилище идем один раз на каждый ключ
+	store.EXPECT().Get(gomock.Any(), "a1234567").Return("http://one.ru", true, nil).Times(1)
+	store.EXPECT().Get(gomock.Any(), "unknown1").Return("", false, nil).Times(1)
+	store.EXPECT().Get(gomock.Any(), "deleted1").Return("", false, storage.ErrDeleted).Times(1)
+
+	for i := 0; i < 3; i++ {
+		val, ok, err := cache.Get(t.Context(), "a1234567")
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, "http://one.ru", val)
+
+		val, ok, err = cache.Get(t.Context(), "unknown1")
+		require.NoError(t, err)
+		assert.False(t, ok)
+		assert.Equal(t, "", val)
+
+		_, ok, err = cache.Get(t.Context(), "deleted1")
+		assert.ErrorIs(t, err, storage.ErrDeleted)
+		assert.False(t, ok)
+	}
+
+	store.EXPECT().InternalStats(gomock.Any()).Return(&models.InternalStats{Urls: 1, Users: 1}, nil)
+	stats, err := cache.InternalStats(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, &models.InternalStats{Urls: 1, Users: 1, CacheHits: 6, CacheMisses: 3}, stats)
+}
+
+func TestCachedStorage_invalidate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mock.NewMockStorager(ctrl)
+	cache := storage.NewCachedStorage(t.Context(), store, 10, time.Minute, time.Minute)
+
+	gomock.InOrder(
+		store.EXPECT().Get(gomock.Any(), "a1234567").Return("", false, nil),
+		store.EXPECT().Set(gomock.Any(), "a1234567", "http://one.ru", "user1").Return(nil),
+		store.EXPECT().Get(gomock.Any(), "a1234567").Return("http://one.ru", true, nil),
+		store.EXPECT().DeleteUserURLS(gomock.Any(), gomock.Any()).Return(nil),
+		store.EXPECT().Get(gomock.Any(), "a1234567").Return("", false, storage.ErrDeleted),
+	)
+
+	_, ok, err := cache.Get(t.Context(), "a1234567")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// новая запись сбрасывает закешированное отсутствие ключа
+	require.NoError(t, cache.Set(t.Context(), "a1234567", "http://one.ru", "user1"))
+	val, ok, err := cache.Get(t.Context(), "a1234567")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "http://one.ru", val)
+
+	// удаление сбрасывает закешированную запись
+	err = cache.DeleteUserURLS(t.Context(), []storage.Message{{UserID: "user1", ShortURL: []string{"a1234567"}}})
+	require.NoError(t, err)
+	_, _, err = cache.Get(t.Context(), "a1234567")
+	assert.ErrorIs(t, err, storage.ErrDeleted)
+}
+
+func TestCachedStorage_ttlAndSize(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mock.NewMockStorager(ctrl)
+	cache := storage.NewCachedStorage(t.Context(), store, 1, time.Minute, time.Nanosecond)
+
+	store.EXPECT().Get(gomock.Any(), "unknown1").Return("", false, nil).Times(2)
+	store.EXPECT().Get(gomock.Any(), "a1234567").Return("http://one.ru", true, nil).Times(2)
+	store.EXPECT().Get(gomock.Any(), "a1234568").Return("http://two.ru", true, nil).Times(1)
+
+	// отрицательный результат протух
+	for i := 0; i < 2; i++ {
+		_, _, err := cache.Get(t.Context(), "unknown1")
+		require.NoError(t, err)
+		time.Sleep(time.Millisecond)
+	}
+	// в кеш помещается одна запись: a1234567 вытесняется
+	for _, key := range []string{"a1234567", "a1234568", "a1234568", "a1234567"} {
+		_, ok, err := cache.Get(t.Context(), key)
+		require.NoError(t, err)
+		assert.True(t, ok)
+	}
+}
+
+func TestCachedStorage_singleflight(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mock.NewMockStorager(ctrl)
+	cache := storage.NewCachedStorage(t.Context(), store, 10, time.Minute, time.Minute)
+
+	release := make(chan struct{})
+	store.EXPECT().Get(gomock.Any(), "a1234567").
+		DoAndReturn(func(ctx context.Context, key string) (string, bool, error) {
+			<-release
+			return "http://one.ru", true, nil
+		}).Times(1)
+
+	const clients = 10
+	var wg sync.WaitGroup
+	for i := 0; i < clients; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			val, ok, err := cache.Get(context.Background(), "a1234567")
+			assert.NoError(t, err)
+			assert.True(t, ok)
+			assert.Equal(t, "http://one.ru", val)
+		}()
+	}
+	// даем клиентам встать в очередь за первым запросом
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+}
+
+// invalidatorStorage storage shared with other instances. Events are sent by test
+type invalidatorStorage struct {
+	storage.Storager
+	events    chan storage.Invalidation
+	processed chan struct{}
+	// listening gets signal after InvalidationReset of subscription, if it is set
+	listening chan struct{}
+	// subscribers count of running Subscribe
+	subscribers atomic.Int32
+	closed      atomic.Bool
+}
+
+func (s *invalidatorStorage) Subscribe(ctx context.Context, fn func(storage.Invalidation)) {
+	s.subscribers.Add(1)
+	defer s.subscribers.Add(-1)
+	// как у хранилища в базе: подписчик узнает о начале прослушивания
+	fn(storage.Invalidation{Op: storage.InvalidationReset})
+	if s.listening != nil {
+		s.listening <- struct{}{}
+	}
+	for {
+		select {
+		case event := <-s.events:
+			fn(event)
+			s.processed <- struct{}{}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *invalidatorStorage) Close() error {
+	s.closed.Store(true)
+	return s.Storager.Close()
+}
+
+func (s *invalidatorStorage) Unwrap() storage.Storager {
+	return s.Storager
+}
+
+// send event and wait while it is processed
+func (s *invalidatorStorage) send(event storage.Invalidation) {
+	s.events <- event
+	<-s.processed
+}
+
+func TestCachedStorage_invalidator(t *testing.T) {
+	shared, err := storage.NewStorageMemory()
+	require.NoError(t, err)
+	inv := &invalidatorStorage{
+		Storager:  shared,
+		events:    make(chan storage.Invalidation),
+		processed: make(chan struct{}),
+		listening: make(chan struct{}, 1),
+	}
+	cache := storage.NewCachedStorage(t.Context(), inv, 10, time.Minute, time.Minute)
+	defer cache.Close()
+	<-inv.listening
+
+	require.NoError(t, cache.Set(t.Context(), "a1234567", "http://one.ru", "user1"))
+	require.NoError(t, cache.Set(t.Context(), "a1234568", "http://two.ru", "user1"))
+	for _, key := range []string{"a1234567", "a1234568"} {
+		_, ok, err := cache.Get(t.Context(), key)
+		require.NoError(t, err)
+		require.True(t, ok)
+	}
+
+	// другой инстанс удаляет урлы в обход кеша
+	err = shared.DeleteUserURLS(t.Context(), []storage.Message{{UserID: "user1", ShortURL: []string{"a1234567", "a1234568"}}})
+	require.NoError(t, err)
+	_, ok, err := cache.Get(t.Context(), "a1234567")
+	require.NoError(t, err)
+	require.True(t, ok, "value from cache")
+
+	inv.send(storage.Invalidation{Op: storage.InvalidationDelete, Keys: []string{"a1234567"}})
+	_, _, err = cache.Get(t.Context(), "a1234567")
+	assert.ErrorIs(t, err, storage.ErrDeleted)
+	_, ok, err = cache.Get(t.Context(), "a1234568")
+	require.NoError(t, err)
+	require.True(t, ok, "value from cache")
+
+	// события могли потеряться: сбрасывается весь кеш
+	inv.send(storage.Invalidation{Op: storage.InvalidationReset})
+	_, _, err = cache.Get(t.Context(), "a1234568")
+	assert.ErrorIs(t, err, storage.ErrDeleted)
+}
+
+func TestWrappers_lifetime(t *testing.T) {
+	newInvalidator := func() *invalidatorStorage {
+		shared, err := storage.NewStorageMemory()
+		require.NoError(t, err)
+		return &invalidatorStorage{
+			Storager:  shared,
+			events:    make(chan storage.Invalidation),
+			processed: make(chan struct{}),
+		}
+	}
+	wrap := func(ctx context.Context, inv *invalidatorStorage) storage.Storager {
+		store, err := storage.NewBloomStorage(ctx, storage.NewCachedStorage(ctx, inv, 10, time.Minute, time.Minute), 100, 0.01)
+		require.NoError(t, err)
+		return storage.NewTracedStorage(store)
+	}
+
+	// Close внешней обертки останавливает подписки и закрывает хранилище
+	inv := newInvalidator()
+	store := wrap(t.Context(), inv)
+	assert.Eventually(t, func() bool { return inv.subscribers.Load() == 2 }, time.Second, time.Millisecond)
+	require.NoError(t, store.Close())
+	assert.Equal(t, int32(0), inv.subscribers.Load())
+	assert.True(t, inv.closed.Load())
+
+	// подписки живут, пока не отменен контекст владельца
+	inv = newInvalidator()
+	ctx, cancel := context.WithCancel(t.Context())
+	store = wrap(ctx, inv)
+	assert.Eventually(t, func() bool { return inv.subscribers.Load() == 2 }, time.Second, time.Millisecond)
+	cancel()
+	assert.Eventually(t, func() bool { return inv.subscribers.Load() == 0 }, time.Second, time.Millisecond)
+	assert.False(t, inv.closed.Load())
+	require.NoError(t, store.Close())
+}
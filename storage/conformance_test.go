@@ -0,0 +1,106 @@
+package storage_test
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/serg2014/shortener/storage"
+	"github.com/serg2014/shortener/storage/storagetest"
+)
+
+// envTestDatabaseDSN name of env with dsn of test database.
+// Tests truncate tables, do not use production database
+const envTestDatabaseDSN = "TEST_DATABASE_DSN"
+
+func TestConformance_memory(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storager {
+		s, err := storage.NewStorageMemory()
+		require.NoError(t, err)
+		return s
+	})
+}
+
+func TestConformance_file(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storager {
+		s, err := storage.NewStorageFile(filepath.Join(t.TempDir(), "storage.json"))
+		require.NoError(t, err)
+		return s
+	})
+}
+
+func TestConformance_database(t *testing.T) {
+	dsn := os.Getenv(envTestDatabaseDSN)
+	if dsn == "" {
+		t.Skipf("%s is not set", envTestDatabaseDSN)
+	}
+	// миграции ищутся относительно cwd
+	t.Chdir("../..")
+
+	storagetest.Run(t, func(t *testing.T) storage.Storager {
+		s, err := storage.NewStorageDB(t.Context(), dsn)
+		require.NoError(t, err)
+
+		db, err := sql.Open("pgx", dsn)
+		require.NoError(t, err)
+		defer db.Close()
+		_, err = db.ExecContext(t.Context(), "TRUNCATE short2orig, api_keys, accounts, banned_users")
+		require.NoError(t, err)
+		return s
+	})
+}
+
+func TestConformance_cache(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storager {
+		s, err := storage.NewStorageMemory()
+		require.NoError(t, err)
+		return storage.NewCachedStorage(t.Context(), s, 100, time.Minute, time.Minute)
+	})
+}
+
+func TestConformance_bloom(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storager {
+		s, err := storage.NewStorageMemory()
+		require.NoError(t, err)
+		s, err = storage.NewBloomStorage(t.Context(), storage.NewCachedStorage(t.Context(), s, 100, time.Minute, time.Minute), 100, 0.01)
+		require.NoError(t, err)
+		return s
+	})
+}
+
+// basicStorage storage with methods of Storager only, without optional interfaces
+type basicStorage struct {
+	storage.Storager
+}
+
+func TestConformance_basic(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storager {
+		s, err := storage.NewStorageMemory()
+		require.NoError(t, err)
+		return storage.NewTracedStorage(storage.NewCachedStorage(t.Context(), basicStorage{s}, 100, time.Minute, time.Minute))
+	})
+}
+
+func TestFind(t *testing.T) {
+	s, err := storage.NewStorageMemory()
+	require.NoError(t, err)
+	traced := storage.NewTracedStorage(storage.NewCachedStorage(t.Context(), s, 100, time.Minute, time.Minute))
+	// находится внешняя обертка, вызовы проходят через кеш и трейсинг
+	moderation, ok := storage.Find[storage.ModerationStorage](traced)
+	require.True(t, ok)
+	assert.Same(t, traced, moderation)
+
+	// обертки не добавляют возможностей, которых нет у хранилища
+	basic := storage.NewTracedStorage(storage.NewCachedStorage(t.Context(), basicStorage{s}, 100, time.Minute, time.Minute))
+	_, ok = storage.Find[storage.ModerationStorage](basic)
+	assert.False(t, ok)
+	_, ok = storage.Find[storage.APIKeyStorage](basic)
+	assert.False(t, ok)
+	err = basic.(storage.ModerationStorage).DisableURL(t.Context(), "a1234567", storage.DisabledGone)
+	assert.ErrorIs(t, err, storage.ErrNotSupported)
+}
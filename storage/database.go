@@ -0,0 +1,695 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-migrate/migrate"
+	"github.com/golang-migrate/migrate/database/postgres"
+	_ "github.com/golang-migrate/migrate/source/file"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/stdlib"
+	"go.uber.org/zap"
+
+	"github.com/serg2014/shortener/internal/models"
+)
+
+// KeyLength length of shorten url
+// must correspond to column short_url in he table short2orig
+const KeyLength = 8
+
+// ErrConflict use this error when save already exist url
+var ErrConflict = errors.New("data conflict")
+
+// ErrDeleted use this error for request deleted url
+var ErrDeleted = errors.New("data deleted")
+
+// ErrMigrations schema of db does not match migrations of app
+var ErrMigrations = errors.New("migrations are not applied")
+
+// ErrKeyExists use this error when save url with already used short key
+var ErrKeyExists = errors.New("short key already exists")
+
+const (
+	// invalidationChannel channel of postgres LISTEN/NOTIFY for Invalidation events
+	invalidationChannel = "shortener_invalidation"
+	// uniqueViolation code of postgres error unique_violation
+	uniqueViolation = "23505"
+	// short2origPkey primary key of short2orig by short_url
+	short2origPkey = "short2orig_pkey"
+)
+
+type storageDB struct {
+	db *sql.DB
+	// migrationsVersion version of schema after migrations at start
+	migrationsVersion uint
+	// invalidations one LISTEN connection for all subscribers
+	invalidations invalidationHub
+}
+
+// NewStorageDB create db storage type *storageDB
+func NewStorageDB(ctx context.Context, dsn string) (Storager, error) {
+	// dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s sslmode=disable",
+	//  `localhost`, `video`, `XXXXXXXX`, `video`)
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return nil, err
+	}
+	// проверяем подключение к бд
+	if err = db.Ping(); err != nil {
+		return nil, fmt.Errorf("failed to ping db: %v", err)
+	}
+	storageLog(ctx).Info("Connected to db")
+
+	// миграции
+	driver, err := postgres.WithInstance(db, &postgres.Config{})
+	if err != nil {
+		return nil, err
+	}
+
+	// TODO file://migrations путь задается относительно cwd
+	// предполагается что запуск бинаря происходит в корне репозитория
+	m, err := migrate.NewWithDatabaseInstance(
+		"file://migrations",
+		dsn,
+		driver,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err = m.Up(); err != nil && err != migrate.ErrNoChange {
+		storageLog(ctx).Fatal("failed to apply migrations", zap.Error(err))
+		return nil, err
+	}
+	version, _, err := m.Version()
+	if err != nil {
+		return nil, err
+	}
+
+	storage := &storageDB{db: db, migrationsVersion: version}
+	storage.invalidations.listen = storage.listen
+	return storage, nil
+}
+
+// Get return orig url by short
+func (storage *storageDB) Get(ctx context.Context, key string) (string, bool, error) {
+	query := "SELECT orig_url, is_deleted, disabled FROM short2orig WHERE short_url = $1"
+	row := storage.db.QueryRowContext(ctx, query, key)
+	var value, disabled string
+	var deleted bool
+	err := row.Scan(&value, &deleted, &disabled)
+	if err == nil {
+		if deleted {
+			return "", false, ErrDeleted
+		}
+		if disabled != "" {
+			return "", false, disabledError(disabled)
+		}
+		return value, true, nil
+	}
+
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	return "", false, fmt.Errorf("failed get shorturl: %w", err)
+}
+
+// GetUserURLS find all user data in storage
+func (storage *storageDB) GetUserURLS(ctx context.Context, userID string) ([]Item, error) {
+	query := "SELECT short_url, orig_url FROM short2orig WHERE user_id = $1 AND NOT is_deleted"
+	rows, err := storage.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		storageLog(ctx).Info("select user urls", zap.Error(err))
+		return nil, fmt.Errorf("failed GetUserURLS: %w", err)
+	}
+	// обязательно закрываем перед возвратом функции
+	defer rows.Close()
+
+	result := make([]Item, 0, 1)
+	// пробегаем по всем записям
+	for rows.Next() {
+		var item Item
+		err = rows.Scan(&item.ShortURL, &item.OriginalURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed GetUserURLS: %w", err)
+		}
+		result = append(result, item)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("failed GetUserURLS: %w", err)
+	}
+	return result, nil
+}
+
+// GetShort return short url by orig from storage
+func (storage *storageDB) GetShort(ctx context.Context, url string) (string, bool, error) {
+	query := "SELECT short_url FROM short2orig WHERE orig_url = $1"
+	row := storage.db.QueryRowContext(ctx, query, url)
+	var value string
+	err := row.Scan(&value)
+	if err == nil {
+		return value, true, nil
+	}
+
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	return "", false, fmt.Errorf("failed GetShort: %w", err)
+}
+
+// Set save record in db
+func (storage *storageDB) Set(ctx context.Context, key string, value string, userID string) error {
+	tx, err := storage.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed Set: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO short2orig (short_url, orig_url, user_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (orig_url) DO NOTHING
+	`
+	result, err := tx.ExecContext(ctx, query, key, value, userID)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation && pgErr.ConstraintName == short2origPkey {
+			return ErrKeyExists
+		}
+		return fmt.Errorf("failed Set: %w", err)
+	}
+	ra, _ := result.RowsAffected()
+	if ra == 0 {
+		return ErrConflict
+	}
+	if err = notifyTx(ctx, tx, InvalidationSet, []string{key}); err != nil {
+		return fmt.Errorf("failed Set: %w", err)
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed Set: %w", err)
+	}
+	return nil
+}
+
+// SetBatch save records in db
+func (storage *storageDB) SetBatch(ctx context.Context, data Short2orig, userID string) error {
+	// начать транзакцию
+	tx, err := storage.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed SetBatch: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO short2orig (short_url, orig_url, user_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (orig_url) DO NOTHING
+	`
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed SetBatch: %w", err)
+	}
+	defer stmt.Close()
+
+	keys := make([]string, 0, len(data))
+	for key, value := range data {
+		_, err := stmt.ExecContext(ctx, key, value, userID)
+		if err != nil {
+			return fmt.Errorf("failed SetBatch: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	if err = notifyTx(ctx, tx, InvalidationSet, keys); err != nil {
+		return fmt.Errorf("failed SetBatch: %w", err)
+	}
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed SetBatch: %w", err)
+	}
+	return nil
+}
+
+// Close close connect to db
+func (storage *storageDB) Close() error {
+	return storage.db.Close()
+}
+
+// Ping check connect ot db
+func (storage *storageDB) Ping(ctx context.Context) error {
+	err := storage.db.PingContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed Ping: %w", err)
+	}
+	return nil
+}
+
+// CheckMigrations check that schema is not dirty and is not older than at start
+func (storage *storageDB) CheckMigrations(ctx context.Context) error {
+	var version uint
+	var dirty bool
+	row := storage.db.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1")
+	if err := row.Scan(&version, &dirty); err != nil {
+		return fmt.Errorf("%w: %w", ErrMigrations, err)
+	}
+	if dirty || version < storage.migrationsVersion {
+		return fmt.Errorf("%w: version %d, dirty %t, want %d", ErrMigrations, version, dirty, storage.migrationsVersion)
+	}
+	return nil
+}
+
+// DeleteUserURLS delete urls for users. One UPDATE per user in the batch.
+// Other instances are notified only about urls really deleted by this call
+func (storage *storageDB) DeleteUserURLS(ctx context.Context, batch []Message) error {
+	// начать транзакцию
+	tx, err := storage.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed DeleteUserURLS: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE short2orig SET is_deleted=true
+		WHERE short_url = ANY($1) and user_id=$2 and not is_deleted
+		RETURNING short_url
+	`
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed DeleteUserURLS: %w", err)
+	}
+	defer stmt.Close()
+
+	keys := make([]string, 0, len(batch))
+	for i := range batch {
+		keys, err = deleteReturning(ctx, stmt, batch[i], keys)
+		if err != nil {
+			return fmt.Errorf("failed DeleteUserURLS: %w", err)
+		}
+	}
+	if err = notifyTx(ctx, tx, InvalidationDelete, keys); err != nil {
+		return fmt.Errorf("failed DeleteUserURLS: %w", err)
+	}
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed DeleteUserURLS: %w", err)
+	}
+	return nil
+}
+
+// deleteReturning run delete of urls of one user and append deleted urls to keys
+func deleteReturning(ctx context.Context, stmt *sql.Stmt, mes Message, keys []string) ([]string, error) {
+	rows, err := stmt.QueryContext(ctx, mes.ShortURL, mes.UserID)
+	if err != nil {
+		return keys, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return keys, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// ForEachKey call fn for every short url including deleted ones
+func (storage *storageDB) ForEachKey(ctx context.Context, fn func(key string)) error {
+	rows, err := storage.db.QueryContext(ctx, "SELECT short_url FROM short2orig")
+	if err != nil {
+		return fmt.Errorf("failed ForEachKey: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var key string
+		if err = rows.Scan(&key); err != nil {
+			return fmt.Errorf("failed ForEachKey: %w", err)
+		}
+		fn(key)
+	}
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("failed ForEachKey: %w", err)
+	}
+	return nil
+}
+
+// InternalStats get stat
+func (storage *storageDB) InternalStats(ctx context.Context) (*models.InternalStats, error) {
+	query := `SELECT count(short_url), count(distinct(user_id)) FROM short2orig`
+	row := storage.db.QueryRowContext(ctx, query)
+	rv := models.InternalStats{}
+	err := row.Scan(&rv.Urls, &rv.Users)
+	if err != nil {
+		return nil, err
+	}
+	return &rv, nil
+}
+
+// notifyTx publish event for other instances. Event is delivered after commit of tx
+func notifyTx(ctx context.Context, tx *sql.Tx, op string, keys []string) error {
+	payloads, err := invalidationPayloads(op, keys)
+	if err != nil {
+		return err
+	}
+	for _, payload := range payloads {
+		_, err := tx.ExecContext(ctx, "SELECT pg_notify($1, $2)", invalidationChannel, payload)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Subscribe listen events of all instances until ctx is done. All subscribers share one connection.
+// fn is called with InvalidationReset after LISTEN, also after restored connection
+func (storage *storageDB) Subscribe(ctx context.Context, fn func(Invalidation)) {
+	storage.invalidations.Subscribe(ctx, fn)
+}
+
+// listen wait events on the dedicated connection. onListen is called after LISTEN
+func (storage *storageDB) listen(ctx context.Context, onListen func(), fn func(Invalidation)) error {
+	conn, err := storage.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn any) error {
+		pgConn := driverConn.(*stdlib.Conn).Conn()
+		if _, err := pgConn.Exec(ctx, "LISTEN "+invalidationChannel); err != nil {
+			return err
+		}
+		// соединение вернется в пул, поэтому подписку надо снять
+		defer func() {
+			ctxT, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			pgConn.Exec(ctxT, "UNLISTEN *")
+		}()
+		onListen()
+
+		for {
+			notification, err := pgConn.WaitForNotification(ctx)
+			if err != nil {
+				return err
+			}
+			var event Invalidation
+			if err := json.Unmarshal([]byte(notification.Payload), &event); err != nil {
+				storageLog(ctx).Error("bad invalidation payload", zap.Error(err), zap.String("payload", notification.Payload))
+				continue
+			}
+			fn(event)
+		}
+	})
+}
+
+// apiKeyColumns columns of api_keys in order of scanAPIKey
+const apiKeyColumns = "id, user_id, name, hash, scopes, created_at, last_used_at, revoked_at"
+
+// scanAPIKey read api key from row. Scopes are saved as comma separated text
+func scanAPIKey(row interface{ Scan(dest ...any) error }) (APIKey, error) {
+	var (
+		key      APIKey
+		scopes   string
+		lastUsed sql.NullTime
+		revoked  sql.NullTime
+	)
+	err := row.Scan(&key.ID, &key.UserID, &key.Name, &key.Hash, &scopes, &key.CreatedAt, &lastUsed, &revoked)
+	if err != nil {
+		return APIKey{}, err
+	}
+	key.Scopes = make([]string, 0)
+	if scopes != "" {
+		key.Scopes = strings.Split(scopes, ",")
+	}
+	key.LastUsedAt = lastUsed.Time
+	key.RevokedAt = revoked.Time
+	return key, nil
+}
+
+// CreateAPIKey save new api key
+func (storage *storageDB) CreateAPIKey(ctx context.Context, key APIKey) error {
+	query := `INSERT INTO api_keys (id, user_id, name, hash, scopes, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (id) DO NOTHING
+	`
+	result, err := storage.db.ExecContext(ctx, query,
+		key.ID, key.UserID, key.Name, key.Hash, strings.Join(key.Scopes, ","), key.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed CreateAPIKey: %w", err)
+	}
+	ra, _ := result.RowsAffected()
+	if ra == 0 {
+		return ErrConflict
+	}
+	return nil
+}
+
+// GetAPIKey return api key by id
+func (storage *storageDB) GetAPIKey(ctx context.Context, id string) (APIKey, bool, error) {
+	query := "SELECT " + apiKeyColumns + " FROM api_keys WHERE id = $1"
+	key, err := scanAPIKey(storage.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return APIKey{}, false, nil
+		}
+		return APIKey{}, false, fmt.Errorf("failed GetAPIKey: %w", err)
+	}
+	return key, true, nil
+}
+
+// GetUserAPIKeys return all api keys of user including revoked ones. Oldest first
+func (storage *storageDB) GetUserAPIKeys(ctx context.Context, userID string) ([]APIKey, error) {
+	query := "SELECT " + apiKeyColumns + " FROM api_keys WHERE user_id = $1 ORDER BY created_at, id"
+	rows, err := storage.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed GetUserAPIKeys: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]APIKey, 0)
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed GetUserAPIKeys: %w", err)
+		}
+		result = append(result, key)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed GetUserAPIKeys: %w", err)
+	}
+	return result, nil
+}
+
+// RevokeAPIKey mark api key of user as revoked. Revoke of revoked key is not an error
+func (storage *storageDB) RevokeAPIKey(ctx context.Context, userID, id string, at time.Time) error {
+	query := `UPDATE api_keys SET revoked_at = COALESCE(revoked_at, $3)
+		WHERE id = $1 AND user_id = $2
+	`
+	result, err := storage.db.ExecContext(ctx, query, id, userID, at)
+	if err != nil {
+		return fmt.Errorf("failed RevokeAPIKey: %w", err)
+	}
+	ra, _ := result.RowsAffected()
+	if ra == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// TouchAPIKey save time of last usage of api key
+func (storage *storageDB) TouchAPIKey(ctx context.Context, id string, at time.Time) error {
+	query := `UPDATE api_keys SET last_used_at = $2
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < $2)
+	`
+	_, err := storage.db.ExecContext(ctx, query, id, at)
+	if err != nil {
+		return fmt.Errorf("failed TouchAPIKey: %w", err)
+	}
+	return nil
+}
+
+// CreateAccount save new account. Return ErrConflict if login or userid is taken
+func (storage *storageDB) CreateAccount(ctx context.Context, account Account) error {
+	query := `INSERT INTO accounts (login, user_id, password_hash, created_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT DO NOTHING
+	`
+	result, err := storage.db.ExecContext(ctx, query, account.Login, account.UserID, account.PasswordHash, account.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed CreateAccount: %w", err)
+	}
+	ra, _ := result.RowsAffected()
+	if ra == 0 {
+		return ErrConflict
+	}
+	return nil
+}
+
+// getAccount return account by condition on one column
+func (storage *storageDB) getAccount(ctx context.Context, column, value string) (Account, bool, error) {
+	query := "SELECT login, user_id, password_hash, created_at FROM accounts WHERE " + column + " = $1"
+	var account Account
+	err := storage.db.QueryRowContext(ctx, query, value).Scan(&account.Login, &account.UserID, &account.PasswordHash, &account.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Account{}, false, nil
+		}
+		return Account{}, false, fmt.Errorf("failed getAccount: %w", err)
+	}
+	return account, true, nil
+}
+
+// GetAccount return account by login
+func (storage *storageDB) GetAccount(ctx context.Context, login string) (Account, bool, error) {
+	return storage.getAccount(ctx, "login", login)
+}
+
+// GetAccountByUserID return account of user
+func (storage *storageDB) GetAccountByUserID(ctx context.Context, userID string) (Account, bool, error) {
+	return storage.getAccount(ctx, "user_id", userID)
+}
+
+// MergeUserURLS move all urls of fromUserID to toUserID
+func (storage *storageDB) MergeUserURLS(ctx context.Context, fromUserID, toUserID string) error {
+	query := "UPDATE short2orig SET user_id = $2 WHERE user_id = $1"
+	if _, err := storage.db.ExecContext(ctx, query, fromUserID, toUserID); err != nil {
+		return fmt.Errorf("failed MergeUserURLS: %w", err)
+	}
+	return nil
+}
+
+// urlInfoColumns columns of short2orig for scanURLInfo
+const urlInfoColumns = "short_url, orig_url, coalesce(user_id, ''), is_deleted, disabled, hits"
+
+// queryURLInfo return urls selected by query with urlInfoColumns
+func (storage *storageDB) queryURLInfo(ctx context.Context, query string, args ...any) ([]URLInfo, error) {
+	rows, err := storage.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]URLInfo, 0)
+	for rows.Next() {
+		var info URLInfo
+		var hits int64
+		if err := rows.Scan(&info.ShortURL, &info.OriginalURL, &info.UserID, &info.Deleted, &info.Disabled, &hits); err != nil {
+			return nil, err
+		}
+		info.Hits = uint64(hits)
+		result = append(result, info)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// SearchURLS return urls of all users matched by filter sorted by short url
+func (storage *storageDB) SearchURLS(ctx context.Context, filter URLFilter) ([]URLInfo, error) {
+	conds := []string{"true"}
+	args := make([]any, 0, 3)
+	if filter.UserID != "" {
+		args = append(args, filter.UserID)
+		conds = append(conds, fmt.Sprintf("user_id = $%d", len(args)))
+	}
+	if filter.Domain != "" {
+		args = append(args, domainPattern(filter.Domain))
+		conds = append(conds, fmt.Sprintf("orig_url ~* $%d", len(args)))
+	}
+	query := "SELECT " + urlInfoColumns + " FROM short2orig WHERE " + strings.Join(conds, " AND ") + " ORDER BY short_url"
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	result, err := storage.queryURLInfo(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed SearchURLS: %w", err)
+	}
+	return result, nil
+}
+
+// DisableURL disable url with reason. Empty reason enables url. Return ErrNotFound for unknown url
+func (storage *storageDB) DisableURL(ctx context.Context, key, reason string) error {
+	tx, err := storage.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed DisableURL: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, "UPDATE short2orig SET disabled = $2 WHERE short_url = $1", key, reason)
+	if err != nil {
+		return fmt.Errorf("failed DisableURL: %w", err)
+	}
+	ra, _ := result.RowsAffected()
+	if ra == 0 {
+		return ErrNotFound
+	}
+	// кеши других инстансов должны забыть урл
+	if err = notifyTx(ctx, tx, InvalidationUpdate, []string{key}); err != nil {
+		return fmt.Errorf("failed DisableURL: %w", err)
+	}
+	return tx.Commit()
+}
+
+// SetUserBanned ban or unban user. Banned user can not create urls
+func (storage *storageDB) SetUserBanned(ctx context.Context, userID string, banned bool) error {
+	query := "DELETE FROM banned_users WHERE user_id = $1"
+	if banned {
+		query = "INSERT INTO banned_users (user_id) VALUES ($1) ON CONFLICT DO NOTHING"
+	}
+	if _, err := storage.db.ExecContext(ctx, query, userID); err != nil {
+		return fmt.Errorf("failed SetUserBanned: %w", err)
+	}
+	return nil
+}
+
+// IsUserBanned user is banned
+func (storage *storageDB) IsUserBanned(ctx context.Context, userID string) (bool, error) {
+	var banned bool
+	query := "SELECT EXISTS (SELECT 1 FROM banned_users WHERE user_id = $1)"
+	if err := storage.db.QueryRowContext(ctx, query, userID).Scan(&banned); err != nil {
+		return false, fmt.Errorf("failed IsUserBanned: %w", err)
+	}
+	return banned, nil
+}
+
+// AddHits add counts of redirects to urls. Unknown urls are skipped
+func (storage *storageDB) AddHits(ctx context.Context, hits map[string]uint64) error {
+	if len(hits) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(hits))
+	counts := make([]int64, 0, len(hits))
+	for key, n := range hits {
+		keys = append(keys, key)
+		counts = append(counts, int64(n))
+	}
+	query := `UPDATE short2orig AS s SET hits = s.hits + v.n
+		FROM unnest($1::text[], $2::bigint[]) AS v(k, n)
+		WHERE s.short_url = v.k
+	`
+	if _, err := storage.db.ExecContext(ctx, query, keys, counts); err != nil {
+		return fmt.Errorf("failed AddHits: %w", err)
+	}
+	return nil
+}
+
+// TopURLS return limit urls with max count of redirects
+func (storage *storageDB) TopURLS(ctx context.Context, limit int) ([]URLInfo, error) {
+	query := "SELECT " + urlInfoColumns + " FROM short2orig WHERE hits > 0 ORDER BY hits DESC, short_url"
+	args := make([]any, 0, 1)
+	if limit > 0 {
+		args = append(args, limit)
+		query += " LIMIT $1"
+	}
+	result, err := storage.queryURLInfo(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed TopURLS: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,324 @@
+package storage
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/serg2014/shortener/internal/logger"
+)
+
+// Item one row file representation
+type Item struct {
+	ShortURL    string `json:"short_url"`
+	OriginalURL string `json:"original_url"`
+	UserID      string `json:"user_id,omitempty"`
+	// DeletedFlag row marks ShortURL of UserID as deleted
+	DeletedFlag bool `json:"is_deleted,omitempty"`
+}
+
+// Types of rows in file
+const (
+	// recordAPIKey row with state of api key
+	recordAPIKey = "api_key"
+	// recordAccount row with new account
+	recordAccount = "account"
+	// recordMerge row with merge of urls of users
+	recordMerge = "merge"
+	// recordDisable row with reason of disabled url, empty reason enables url
+	recordDisable = "disable"
+	// recordBan row with ban of user
+	recordBan = "ban"
+	// recordHits row with counts of redirects added to urls
+	recordHits = "hits"
+)
+
+// mergeRecord urls of From were moved to To
+type mergeRecord struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// record one row of file. Rows without type are urls
+type record struct {
+	Item
+	Type    string       `json:"type,omitempty"`
+	APIKey  *APIKey      `json:"api_key,omitempty"`
+	Account *Account     `json:"account,omitempty"`
+	Merge   *mergeRecord `json:"merge,omitempty"`
+	// Reason reason of disabled url ShortURL for recordDisable
+	Reason string `json:"reason,omitempty"`
+	// Banned ban or unban of UserID for recordBan
+	Banned bool              `json:"banned,omitempty"`
+	Hits   map[string]uint64 `json:"hits,omitempty"`
+}
+
+type storageFile struct {
+	file io.ReadWriter
+	*storage
+}
+
+func newStorageIO(file io.ReadWriter) (*storageFile, error) {
+	// файл читается при старте, вне запроса
+	ctx := context.Background()
+	scanner := bufio.NewScanner(file)
+	var rec record
+	s := storageFile{
+		file:    file,
+		storage: newStorageMemory(),
+	}
+
+	// TODO если строка будет длинной получим ошибку
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		rec = record{}
+		err := json.Unmarshal(line, &rec)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case rec.Type == recordAPIKey && rec.APIKey != nil:
+			// строка содержит последнее состояние ключа
+			s.apiKeys[rec.APIKey.ID] = rec.APIKey
+			continue
+		case rec.Type == recordAccount && rec.Account != nil:
+			if err := s.createAccount(*rec.Account); err != nil {
+				storageLog(ctx).Error("Duplicate account", logger.UserIDField("login", rec.Account.Login))
+			}
+			continue
+		case rec.Type == recordMerge && rec.Merge != nil:
+			s.mergeUserURLS(rec.Merge.From, rec.Merge.To)
+			continue
+		case rec.Type == recordDisable:
+			if err := s.disableURL(rec.ShortURL, rec.Reason); err != nil {
+				storageLog(ctx).Error("Disable unknown url", zap.String("short_url", rec.ShortURL))
+			}
+			continue
+		case rec.Type == recordBan:
+			s.setUserBanned(rec.UserID, rec.Banned)
+			continue
+		case rec.Type == recordHits:
+			s.addHits(rec.Hits)
+			continue
+		case rec.Type != "":
+			storageLog(ctx).Error("Unknown row type", zap.String("type", rec.Type))
+			continue
+		}
+		item := rec.Item
+		if item.DeletedFlag {
+			s.deleteUserURLS([]Message{{UserID: item.UserID, ShortURL: []string{item.ShortURL}}})
+			continue
+		}
+		if _, ok := s.short2orig[item.ShortURL]; ok {
+			storageLog(ctx).Error(
+				"Duplicate key ShortURL",
+				zap.String("ShortURL", item.ShortURL),
+				logger.URLField("OriginalURL", item.OriginalURL),
+			)
+			continue
+		}
+		if _, ok := s.orig2short[item.OriginalURL]; ok {
+			storageLog(ctx).Error(
+				"Duplicate key OriginalURL",
+				zap.String("ShortURL", item.ShortURL),
+				logger.URLField("OriginalURL", item.OriginalURL),
+			)
+			continue
+		}
+		s.short2orig[item.ShortURL] = item.OriginalURL
+		s.orig2short[item.OriginalURL] = item.ShortURL
+
+		if _, ok := s.users[item.UserID]; !ok {
+			s.users[item.UserID] = make(Short2orig)
+		}
+		s.users[item.UserID][item.ShortURL] = item.OriginalURL
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// NewStorageFile create file storage type *storageFile
+func NewStorageFile(filePath string) (Storager, error) {
+	// os.O_APPEND os.O_SYNC
+	file, err := os.OpenFile(filePath, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		return nil, err
+	}
+	s, err := newStorageIO(file)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Set save record in file
+func (s *storageFile) Set(ctx context.Context, key string, value string, userID string) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+	err := s.set(key, value, userID)
+	if err != nil {
+		return err
+	}
+
+	return s.saveRow(key, value, userID)
+}
+
+// SetBatch save range of data into file. Records with already stored orig url are skipped
+func (s *storageFile) SetBatch(ctx context.Context, data Short2orig, userID string) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	for key, value := range s.setBatch(data, userID) {
+		// TODO писать надо чанками
+		err := s.saveRow(key, value, userID)
+		if err != nil {
+			storageLog(ctx).Error("while save row in file", zap.Error(err))
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteUserURLS mark urls as deleted and save marks into file
+func (s *storageFile) DeleteUserURLS(ctx context.Context, batch []Message) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	for _, mes := range s.deleteUserURLS(batch) {
+		for _, key := range mes.ShortURL {
+			err := s.writeItem(Item{ShortURL: key, UserID: mes.UserID, DeletedFlag: true})
+			if err != nil {
+				storageLog(ctx).Error("while save row in file", zap.Error(err))
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// CreateAPIKey save new api key into file
+func (s *storageFile) CreateAPIKey(ctx context.Context, key APIKey) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+	if err := s.createAPIKey(key); err != nil {
+		return err
+	}
+	return s.writeAPIKey(s.apiKeys[key.ID])
+}
+
+// RevokeAPIKey mark api key of user as revoked and save it into file
+func (s *storageFile) RevokeAPIKey(ctx context.Context, userID, id string, at time.Time) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+	key, err := s.revokeAPIKey(userID, id, at)
+	if err != nil || key == nil {
+		return err
+	}
+	return s.writeAPIKey(key)
+}
+
+// TouchAPIKey save time of last usage of api key into file
+func (s *storageFile) TouchAPIKey(ctx context.Context, id string, at time.Time) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+	key, err := s.touchAPIKey(id, at)
+	if err != nil || key == nil {
+		return err
+	}
+	return s.writeAPIKey(key)
+}
+
+// CreateAccount save new account into file
+func (s *storageFile) CreateAccount(ctx context.Context, account Account) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+	if err := s.createAccount(account); err != nil {
+		return err
+	}
+	return s.writeRecord(record{Type: recordAccount, Account: &account})
+}
+
+// MergeUserURLS move all urls of fromUserID to toUserID and save it into file
+func (s *storageFile) MergeUserURLS(ctx context.Context, fromUserID, toUserID string) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+	if _, ok := s.users[fromUserID]; !ok || fromUserID == toUserID {
+		return nil
+	}
+	s.mergeUserURLS(fromUserID, toUserID)
+	return s.writeRecord(record{Type: recordMerge, Merge: &mergeRecord{From: fromUserID, To: toUserID}})
+}
+
+// DisableURL disable url with reason and save it into file. Empty reason enables url
+func (s *storageFile) DisableURL(ctx context.Context, key, reason string) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+	if err := s.disableURL(key, reason); err != nil {
+		return err
+	}
+	return s.writeRecord(record{Type: recordDisable, Item: Item{ShortURL: key}, Reason: reason})
+}
+
+// SetUserBanned ban or unban user and save it into file
+func (s *storageFile) SetUserBanned(ctx context.Context, userID string, banned bool) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.setUserBanned(userID, banned)
+	return s.writeRecord(record{Type: recordBan, Item: Item{UserID: userID}, Banned: banned})
+}
+
+// AddHits add counts of redirects to urls and save them into file
+func (s *storageFile) AddHits(ctx context.Context, hits map[string]uint64) error {
+	if len(hits) == 0 {
+		return nil
+	}
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.addHits(hits)
+	return s.writeRecord(record{Type: recordHits, Hits: hits})
+}
+
+// TODO flush
+func (s *storageFile) saveRow(shortURL, originalURL string, userID string) error {
+	return s.writeItem(Item{ShortURL: shortURL, OriginalURL: originalURL, UserID: userID})
+}
+
+// writeItem append one row into file
+func (s *storageFile) writeItem(itemData Item) error {
+	return s.writeRecord(itemData)
+}
+
+// writeAPIKey append state of api key into file
+func (s *storageFile) writeAPIKey(key *APIKey) error {
+	return s.writeRecord(record{Type: recordAPIKey, APIKey: key})
+}
+
+// writeRecord append one row into file
+func (s *storageFile) writeRecord(data any) error {
+	line, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = s.file.Write(line)
+	if err != nil {
+		return err
+	}
+	_, err = s.file.Write([]byte("\n"))
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Close close connect to file/db
+func (s *storageFile) Close() error {
+	// TODO flush
+	return nil
+}
@@ -0,0 +1,270 @@
+package storage
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_File_newStorageIO(t *testing.T) {
+	tests := []struct {
+		fileData io.ReadWriter
+		name     string
+	}{
+		{
+			name: "one",
+			fileData: bytes.NewBufferString(
+				`{"short_url":"short", "original_url":"orig","user_id":"user1"}
+				{"short_url":"short2", "original_url":"orig2","user_id":"user2"}`),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			storage, err := newStorageIO(test.fileData)
+			require.NoError(t, err)
+			val, ok, err := storage.Get(t.Context(), "short")
+			require.NoError(t, err)
+			assert.Equal(t, "orig", val)
+			assert.Equal(t, true, ok)
+
+			val, ok, err = storage.Get(t.Context(), "short2")
+			require.NoError(t, err)
+			assert.Equal(t, "orig2", val)
+			assert.Equal(t, true, ok)
+
+			val, ok, err = storage.Get(t.Context(), "short3")
+			require.NoError(t, err)
+			assert.Equal(t, "", val)
+			assert.Equal(t, false, ok)
+		})
+	}
+}
+
+func Test_File_Set(t *testing.T) {
+	tests := []struct {
+		name     string
+		fileData io.ReadWriter
+		expect   error
+		writen   string
+		setArgs  [][3]string
+	}{
+		{
+			name: "set without error",
+			fileData: bytes.NewBufferString(
+				`{"short_url":"short", "original_url":"orig","user_id":"user1"}
+				{"short_url":"short2", "original_url":"orig2","user_id":"user2"}`),
+			setArgs: [][3]string{
+				{"short3", "orig3", "user3"},
+				{"short4", "orig4", "user4"},
+				{"short5", "orig5", "user5"},
+			},
+			expect: nil,
+			writen: `{"short_url":"short3","original_url":"orig3","user_id":"user3"}
+{"short_url":"short4","original_url":"orig4","user_id":"user4"}
+{"short_url":"short5","original_url":"orig5","user_id":"user5"}
+`,
+		},
+		{
+			name: "set with error",
+			fileData: bytes.NewBufferString(
+				`{"short_url":"short", "original_url":"orig","user_id":"user1"}
+				{"short_url":"short2", "original_url":"orig2","user_id":"user2"}`),
+			setArgs: [][3]string{
+				{"short3", "orig", "user1"},
+			},
+			expect: ErrConflict,
+			writen: "",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			storage, err := newStorageIO(test.fileData)
+			require.NoError(t, err)
+
+			for _, insert := range test.setArgs {
+				err = storage.Set(t.Context(), insert[0], insert[1], insert[2])
+				assert.Equal(t, test.expect, err)
+			}
+			allRows, err := readAllDataInTest(test.fileData)
+			require.NoError(t, err)
+			assert.Equal(t, test.writen, allRows)
+		})
+	}
+}
+
+func readAllDataInTest(file io.ReadWriter) (string, error) {
+	allRows := make([]byte, 0)
+	p := make([]byte, 10)
+	for i := true; i == true; {
+		n, err := file.Read(p)
+		if err != nil {
+			if err != io.EOF {
+				return "", err
+			}
+			i = false
+		}
+		allRows = append(allRows, p[:n]...)
+	}
+	return string(allRows), nil
+}
+
+func Test_File_SetBatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		UserID   string
+		fileData io.ReadWriter
+		data     Short2orig
+		expect   string
+	}{
+		{
+			name:     "First",
+			UserID:   "user1",
+			fileData: bytes.NewBuffer(nil),
+			data: Short2orig{
+				"a1234567": "http://one.ru",
+				"a1234568": "http://two.ru",
+				"a1234569": "http://three.ru",
+			},
+			expect: `{"short_url":"a1234567","original_url":"http://one.ru","user_id":"user1"}
+{"short_url":"a1234568","original_url":"http://two.ru","user_id":"user1"}
+{"short_url":"a1234569","original_url":"http://three.ru","user_id":"user1"}
+`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fileStorage, err := newStorageIO(test.fileData)
+			require.NoError(t, err)
+
+			err = fileStorage.SetBatch(t.Context(), test.data, test.UserID)
+			require.NoError(t, err)
+
+			data, err := readAllDataInTest(test.fileData)
+			require.NoError(t, err)
+			assert.ElementsMatch(t, strings.Split(test.expect, "\n"), strings.Split(data, "\n"))
+		})
+	}
+}
+
+func Test_File_DeleteUserURLS(t *testing.T) {
+	fileData := bytes.NewBufferString(
+		`{"short_url":"short", "original_url":"orig","user_id":"user1"}
+		{"short_url":"short2", "original_url":"orig2","user_id":"user2"}
+`)
+	fileStorage, err := newStorageIO(fileData)
+	require.NoError(t, err)
+
+	err = fileStorage.DeleteUserURLS(t.Context(), []Message{
+		{UserID: "user1", ShortURL: []string{"short", "short2"}},
+	})
+	require.NoError(t, err)
+	// удалить можно только свой урл
+	assert.Equal(t, "{\"short_url\":\"short\",\"original_url\":\"\",\"user_id\":\"user1\",\"is_deleted\":true}\n", fileData.String())
+
+	// после перечитывания файла урл остается удаленным
+	reloaded, err := newStorageIO(bytes.NewBufferString(
+		`{"short_url":"short", "original_url":"orig","user_id":"user1"}
+		{"short_url":"short2", "original_url":"orig2","user_id":"user2"}
+` + fileData.String()))
+	require.NoError(t, err)
+	_, _, err = reloaded.Get(t.Context(), "short")
+	assert.ErrorIs(t, err, ErrDeleted)
+	val, ok, err := reloaded.Get(t.Context(), "short2")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "orig2", val)
+}
+
+func Test_File_APIKeys(t *testing.T) {
+	fileData := bytes.NewBufferString(`{"short_url":"short", "original_url":"orig","user_id":"user1"}
+`)
+	fileStorage, err := newStorageIO(fileData)
+	require.NoError(t, err)
+
+	created := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	err = fileStorage.CreateAPIKey(t.Context(), APIKey{
+		ID: "k1", UserID: "user1", Name: "backend", Hash: "hash1", Scopes: []string{"read"}, CreatedAt: created,
+	})
+	require.NoError(t, err)
+	require.NoError(t, fileStorage.TouchAPIKey(t.Context(), "k1", created.Add(time.Minute)))
+	require.NoError(t, fileStorage.RevokeAPIKey(t.Context(), "user1", "k1", created.Add(time.Hour)))
+	// повторный отзыв в файл не пишется
+	require.NoError(t, fileStorage.RevokeAPIKey(t.Context(), "user1", "k1", created.Add(2*time.Hour)))
+	assert.Equal(t, 3, strings.Count(fileData.String(), "\n"))
+
+	// после перечитывания файла ключ в последнем состоянии, урлы на месте
+	reloaded, err := newStorageIO(bytes.NewBufferString(`{"short_url":"short", "original_url":"orig","user_id":"user1"}
+` + fileData.String()))
+	require.NoError(t, err)
+	key, ok, err := reloaded.GetAPIKey(t.Context(), "k1")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, created.Add(time.Minute), key.LastUsedAt)
+	assert.Equal(t, created.Add(time.Hour), key.RevokedAt)
+	val, ok, err := reloaded.Get(t.Context(), "short")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "orig", val)
+}
+
+func Test_File_Accounts(t *testing.T) {
+	rows := `{"short_url":"short", "original_url":"orig","user_id":"anon"}
+`
+	fileData := bytes.NewBufferString(rows)
+	fileStorage, err := newStorageIO(fileData)
+	require.NoError(t, err)
+
+	account := Account{Login: "alice", UserID: "user1", PasswordHash: "hash1", CreatedAt: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	require.NoError(t, fileStorage.CreateAccount(t.Context(), account))
+	require.ErrorIs(t, fileStorage.CreateAccount(t.Context(), account), ErrConflict)
+	require.NoError(t, fileStorage.MergeUserURLS(t.Context(), "anon", "user1"))
+	// пустой перенос в файл не пишется
+	require.NoError(t, fileStorage.MergeUserURLS(t.Context(), "anon", "user1"))
+	assert.Equal(t, 2, strings.Count(fileData.String(), "\n"))
+
+	// после перечитывания файла аккаунт на месте, ссылки у его пользователя
+	reloaded, err := newStorageIO(bytes.NewBufferString(rows + fileData.String()))
+	require.NoError(t, err)
+	got, ok, err := reloaded.GetAccount(t.Context(), "alice")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, account, got)
+	items, err := reloaded.GetUserURLS(t.Context(), "user1")
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, "short", items[0].ShortURL)
+	items, err = reloaded.GetUserURLS(t.Context(), "anon")
+	require.NoError(t, err)
+	assert.Empty(t, items)
+}
+
+func Test_File_Moderation(t *testing.T) {
+	rows := `{"short_url":"short", "original_url":"orig","user_id":"user1"}
+`
+	fileData := bytes.NewBufferString(rows)
+	fileStorage, err := newStorageIO(fileData)
+	require.NoError(t, err)
+
+	require.NoError(t, fileStorage.DisableURL(t.Context(), "short", DisabledLegal))
+	require.NoError(t, fileStorage.SetUserBanned(t.Context(), "user1", true))
+	require.NoError(t, fileStorage.AddHits(t.Context(), map[string]uint64{"short": 2}))
+	require.NoError(t, fileStorage.AddHits(t.Context(), map[string]uint64{"short": 3}))
+
+	// после перечитывания файла состояние модерации на месте
+	reloaded, err := newStorageIO(bytes.NewBufferString(rows + fileData.String()))
+	require.NoError(t, err)
+	_, _, err = reloaded.Get(t.Context(), "short")
+	assert.ErrorIs(t, err, ErrDisabledLegal)
+	banned, err := reloaded.IsUserBanned(t.Context(), "user1")
+	require.NoError(t, err)
+	assert.True(t, banned)
+	top, err := reloaded.TopURLS(t.Context(), 1)
+	require.NoError(t, err)
+	require.Len(t, top, 1)
+	assert.Equal(t, uint64(5), top[0].Hits)
+}
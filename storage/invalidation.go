@@ -0,0 +1,199 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Operations of Invalidation
+const (
+	// InvalidationSet urls were created
+	InvalidationSet = "set"
+	// InvalidationDelete urls were deleted by users
+	InvalidationDelete = "delete"
+	// InvalidationUpdate urls still exist but their state was changed: disabled or enabled by moderator
+	InvalidationUpdate = "update"
+	// InvalidationReset events could be lost, all local data about urls is stale
+	InvalidationReset = "reset"
+)
+
+// Invalidation event about urls changed by any instance of the app
+type Invalidation struct {
+	Op   string   `json:"op"`
+	Keys []string `json:"keys,omitempty"`
+}
+
+// Invalidator is implemented by storages shared by several instances of the app
+type Invalidator interface {
+	// Subscribe calls fn for every event until ctx is done. Blocks.
+	// fn is called with InvalidationReset when events start to be listened: after subscription
+	// and after lost events. Data read before the first InvalidationReset may be stale
+	Subscribe(ctx context.Context, fn func(Invalidation))
+}
+
+const (
+	// listenRetryMin first delay before reconnect of listener
+	listenRetryMin = time.Second
+	// listenRetryMax max delay before reconnect of listener
+	listenRetryMax = 30 * time.Second
+)
+
+// listenFunc wait events until ctx is done or connection is lost. onListen is called when events are listened
+type listenFunc func(ctx context.Context, onListen func(), fn func(Invalidation)) error
+
+// invalidationHub share one listener of events between all subscribers of storage.
+// Listener runs while there are subscribers
+type invalidationHub struct {
+	listen      listenFunc
+	mu          sync.Mutex
+	subscribers map[int]func(Invalidation)
+	next        int
+	// listening listener is connected, new subscribers get InvalidationReset at once
+	listening bool
+	stop      context.CancelFunc
+	done      chan struct{}
+}
+
+// Subscribe calls fn for every event until ctx is done. Blocks.
+// fn is called with InvalidationReset when events start to be listened and after lost events
+func (h *invalidationHub) Subscribe(ctx context.Context, fn func(Invalidation)) {
+	h.mu.Lock()
+	if h.subscribers == nil {
+		h.subscribers = make(map[int]func(Invalidation))
+	}
+	id := h.next
+	h.next++
+	h.subscribers[id] = fn
+	if h.listening {
+		fn(Invalidation{Op: InvalidationReset})
+	}
+	if h.stop == nil {
+		listenCtx, stop := context.WithCancel(context.WithoutCancel(ctx))
+		done := make(chan struct{})
+		h.stop, h.done = stop, done
+		go func() {
+			defer close(done)
+			h.run(listenCtx)
+		}()
+	}
+	h.mu.Unlock()
+
+	<-ctx.Done()
+
+	h.mu.Lock()
+	delete(h.subscribers, id)
+	var stop context.CancelFunc
+	var done chan struct{}
+	if len(h.subscribers) == 0 {
+		// последний подписчик останавливает слушателя
+		stop, done = h.stop, h.done
+		h.stop, h.done = nil, nil
+	}
+	h.mu.Unlock()
+	if stop != nil {
+		stop()
+		<-done
+	}
+}
+
+// publish send event to all subscribers
+func (h *invalidationHub) publish(event Invalidation) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.publishLocked(event)
+}
+
+// publishLocked send event to all subscribers. h.mu must be held
+func (h *invalidationHub) publishLocked(event Invalidation) {
+	for _, fn := range h.subscribers {
+		fn(event)
+	}
+}
+
+// setListening mark listener connected or not. After connect InvalidationReset is published:
+// data read before it could miss events
+func (h *invalidationHub) setListening(listening bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.listening = listening
+	if listening {
+		h.publishLocked(Invalidation{Op: InvalidationReset})
+	}
+}
+
+// run listen events until ctx is done. After every connect InvalidationReset is published
+func (h *invalidationHub) run(ctx context.Context) {
+	delay := listenRetryMin
+	onListen := func() {
+		h.setListening(true)
+		delay = listenRetryMin
+	}
+	for {
+		err := h.listen(ctx, onListen, h.publish)
+		h.setListening(false)
+		if ctx.Err() != nil {
+			return
+		}
+		storageLog(ctx).Error("listen invalidations", zap.Error(err), zap.Duration("retry", delay))
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, listenRetryMax)
+	}
+}
+
+// invalidationPayloadSize max size of payload in bytes.
+// Payload of postgres NOTIFY must be shorter than 8000 bytes
+const invalidationPayloadSize = 8000 - 1
+
+// ErrPayloadTooLarge key does not fit into payload of event
+var ErrPayloadTooLarge = errors.New("key does not fit into invalidation payload")
+
+// invalidationPayloads encode event into one or several payloads shorter than invalidationPayloadSize bytes
+func invalidationPayloads(op string, keys []string) ([]string, error) {
+	// размер события без ключей: {"op":"...","keys":[]}
+	empty, err := json.Marshal(Invalidation{Op: op, Keys: []string{""}})
+	if err != nil {
+		return nil, err
+	}
+	base := len(empty) - len(`""`)
+
+	result := make([]string, 0, 1)
+	for len(keys) > 0 {
+		size := base
+		n := 0
+		for ; n < len(keys); n++ {
+			key, err := json.Marshal(keys[n])
+			if err != nil {
+				return nil, err
+			}
+			keySize := len(key)
+			if n > 0 {
+				// запятая между ключами
+				keySize++
+			}
+			if size+keySize > invalidationPayloadSize {
+				break
+			}
+			size += keySize
+		}
+		if n == 0 {
+			return nil, fmt.Errorf("%w: %d bytes", ErrPayloadTooLarge, len(keys[0]))
+		}
+		data, err := json.Marshal(Invalidation{Op: op, Keys: keys[:n]})
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, string(data))
+		keys = keys[n:]
+	}
+	return result, nil
+}
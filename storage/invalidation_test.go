@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_invalidationPayloads(t *testing.T) {
+	keys := make([]string, 1000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("a%07d", i)
+	}
+
+	payloads, err := invalidationPayloads(InvalidationDelete, keys)
+	require.NoError(t, err)
+	// ключ с кавычками и запятой занимает 11 байт
+	require.Len(t, payloads, 2)
+
+	got := make([]string, 0, len(keys))
+	for _, payload := range payloads {
+		// лимит postgres на размер payload
+		assert.Less(t, len(payload), 8000)
+		var event Invalidation
+		require.NoError(t, json.Unmarshal([]byte(payload), &event))
+		assert.Equal(t, InvalidationDelete, event.Op)
+		got = append(got, event.Keys...)
+	}
+	assert.Equal(t, keys, got)
+
+	payloads, err = invalidationPayloads(InvalidationSet, nil)
+	require.NoError(t, err)
+	assert.Empty(t, payloads)
+}
+
+func Test_invalidationPayloads_size(t *testing.T) {
+	// длинные ключи и ключи с экранированием: лимит считается по байтам, а не по числу ключей
+	long := strings.Repeat("x", 3000)
+	keys := []string{long, long, long, strings.Repeat("<", 1000), "a1234567"}
+	payloads, err := invalidationPayloads(InvalidationSet, keys)
+	require.NoError(t, err)
+	got := make([]string, 0, len(keys))
+	for _, payload := range payloads {
+		assert.Less(t, len(payload), 8000)
+		var event Invalidation
+		require.NoError(t, json.Unmarshal([]byte(payload), &event))
+		got = append(got, event.Keys...)
+	}
+	assert.Equal(t, keys, got)
+	assert.Len(t, payloads, 3)
+
+	// ровно по лимиту
+	empty, err := json.Marshal(Invalidation{Op: InvalidationSet, Keys: []string{""}})
+	require.NoError(t, err)
+	exact := strings.Repeat("x", invalidationPayloadSize-len(empty))
+	payloads, err = invalidationPayloads(InvalidationSet, []string{exact})
+	require.NoError(t, err)
+	require.Len(t, payloads, 1)
+	assert.Len(t, payloads[0], invalidationPayloadSize)
+
+	_, err = invalidationPayloads(InvalidationSet, []string{exact + "x"})
+	assert.ErrorIs(t, err, ErrPayloadTooLarge)
+}
+
+func Test_invalidationHub(t *testing.T) {
+	var connections atomic.Int32
+	events := make(chan Invalidation)
+	hub := &invalidationHub{listen: func(ctx context.Context, onListen func(), fn func(Invalidation)) error {
+		connections.Add(1)
+		defer connections.Add(-1)
+		onListen()
+		for {
+			select {
+			case event := <-events:
+				fn(event)
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	var wg sync.WaitGroup
+	got := make([]chan Invalidation, 2)
+	for i := range got {
+		got[i] = make(chan Invalidation, 1)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			hub.Subscribe(ctx, func(event Invalidation) { got[i] <- event })
+		}()
+	}
+	require.Eventually(t, func() bool {
+		hub.mu.Lock()
+		defer hub.mu.Unlock()
+		return len(hub.subscribers) == 2
+	}, time.Second, time.Millisecond)
+	// подписчик узнает о начале прослушивания
+	for i := range got {
+		assert.Equal(t, Invalidation{Op: InvalidationReset}, <-got[i])
+	}
+
+	// оба подписчика получают событие через одно соединение
+	event := Invalidation{Op: InvalidationUpdate, Keys: []string{"a0000001"}}
+	events <- event
+	for i := range got {
+		assert.Equal(t, event, <-got[i])
+	}
+	assert.Equal(t, int32(1), connections.Load())
+
+	// после ухода последнего подписчика соединение закрывается
+	cancel()
+	wg.Wait()
+	assert.Equal(t, int32(0), connections.Load())
+}
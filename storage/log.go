@@ -0,0 +1,17 @@
+package storage
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	"github.com/serg2014/shortener/internal/logger"
+)
+
+// componentStorage name of component in logs of storage
+const componentStorage = "storage"
+
+// storageLog return logger of ctx for storage
+func storageLog(ctx context.Context) *zap.Logger {
+	return logger.Component(ctx, componentStorage)
+}
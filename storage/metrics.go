@@ -0,0 +1,121 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/serg2014/shortener/internal/metrics"
+)
+
+// Backends of storage for label of metrics
+const (
+	BackendMemory   = "memory"
+	BackendFile     = "file"
+	BackendDatabase = "database"
+)
+
+// metricsStorage measure latency of operations of wrapped storage
+type metricsStorage struct {
+	Storager
+	backend string
+}
+
+// NewMetricsStorage wrap store with measuring of latency of main operations.
+// Should wrap backend directly, so hits of cache are not measured
+func NewMetricsStorage(store Storager) Storager {
+	return &metricsStorage{Storager: store, backend: backendName(store)}
+}
+
+// backendName return backend of store for label of metrics. Wrappers of storage are skipped
+func backendName(store Storager) string {
+	switch store.(type) {
+	case *storage:
+		return BackendMemory
+	case *storageFile:
+		return BackendFile
+	case *storageDB:
+		return BackendDatabase
+	}
+	if u, ok := store.(Unwrapper); ok {
+		return backendName(u.Unwrap())
+	}
+	return "unknown"
+}
+
+// isFailure err is failure of storage. Expected answers like ErrConflict are not failures
+func isFailure(err error) bool {
+	return err != nil && !errors.Is(err, ErrConflict) && !errors.Is(err, ErrKeyExists) &&
+		!errors.Is(err, ErrDeleted) && !errors.Is(err, ErrDisabled) && !errors.Is(err, ErrDisabledLegal) &&
+		!errors.Is(err, ErrNotFound)
+}
+
+// Unwrap return wrapped storage
+func (s *metricsStorage) Unwrap() Storager {
+	return s.Storager
+}
+
+// observe add latency of operation since start
+func (s *metricsStorage) observe(operation string, start time.Time, err error) {
+	result := "ok"
+	if isFailure(err) {
+		result = "error"
+	}
+	metrics.StorageDuration.WithLabelValues(s.backend, operation, result).Observe(time.Since(start).Seconds())
+}
+
+// Get implement Get of Storager
+func (s *metricsStorage) Get(ctx context.Context, key string) (string, bool, error) {
+	start := time.Now()
+	value, ok, err := s.Storager.Get(ctx, key)
+	s.observe("get", start, err)
+	return value, ok, err
+}
+
+// GetUserURLS implement GetUserURLS of Storager
+func (s *metricsStorage) GetUserURLS(ctx context.Context, userID string) ([]Item, error) {
+	start := time.Now()
+	items, err := s.Storager.GetUserURLS(ctx, userID)
+	s.observe("get_user_urls", start, err)
+	return items, err
+}
+
+// GetShort implement GetShort of Storager
+func (s *metricsStorage) GetShort(ctx context.Context, origURL string) (string, bool, error) {
+	start := time.Now()
+	key, ok, err := s.Storager.GetShort(ctx, origURL)
+	s.observe("get_short", start, err)
+	return key, ok, err
+}
+
+// Set implement Set of Storager
+func (s *metricsStorage) Set(ctx context.Context, key string, value string, userID string) error {
+	start := time.Now()
+	err := s.Storager.Set(ctx, key, value, userID)
+	s.observe("set", start, err)
+	return err
+}
+
+// SetBatch implement SetBatch of Storager
+func (s *metricsStorage) SetBatch(ctx context.Context, data Short2orig, userID string) error {
+	start := time.Now()
+	err := s.Storager.SetBatch(ctx, data, userID)
+	s.observe("set_batch", start, err)
+	return err
+}
+
+// DeleteUserURLS implement DeleteUserURLS of Storager
+func (s *metricsStorage) DeleteUserURLS(ctx context.Context, batch []Message) error {
+	start := time.Now()
+	err := s.Storager.DeleteUserURLS(ctx, batch)
+	s.observe("delete", start, err)
+	return err
+}
+
+// Ping implement Ping of Storager
+func (s *metricsStorage) Ping(ctx context.Context) error {
+	start := time.Now()
+	err := s.Storager.Ping(ctx)
+	s.observe("ping", start, err)
+	return err
+}
@@ -0,0 +1,39 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/serg2014/shortener/internal/metrics"
+)
+
+func TestMetricsStorage(t *testing.T) {
+	store, err := NewStorageMemory()
+	require.NoError(t, err)
+	m := NewMetricsStorage(store)
+	assert.Equal(t, store, m.(Unwrapper).Unwrap())
+
+	before := testutil.CollectAndCount(metrics.StorageDuration)
+	require.NoError(t, m.Set(t.Context(), "a1234567", "http://one.ru", "user1"))
+	// конфликт ожидаемый ответ, а не ошибка хранилища
+	require.ErrorIs(t, m.Set(t.Context(), "b1234567", "http://one.ru", "user1"), ErrConflict)
+	_, ok, err := m.Get(t.Context(), "a1234567")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// появились серии set/ok и get/ok, серии с ошибкой нет
+	assert.Equal(t, before+2, testutil.CollectAndCount(metrics.StorageDuration))
+	assert.False(t, metrics.StorageDuration.DeleteLabelValues(BackendMemory, "set", "error"))
+}
+
+func TestBackendName(t *testing.T) {
+	file, err := NewStorageFile(t.TempDir() + "/storage.json")
+	require.NoError(t, err)
+	assert.Equal(t, BackendFile, backendName(file))
+	assert.Equal(t, BackendMemory, backendName(newStorageMemory()))
+	assert.Equal(t, BackendFile, backendName(NewMetricsStorage(file)))
+	assert.Equal(t, "unknown", backendName(nil))
+}
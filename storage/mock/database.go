@@ -1,5 +1,5 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: internal/storage/storage.go
+// Source: storage/storage.go
 
 // Package mock is a generated GoMock package.
 package mock
@@ -9,8 +9,7 @@ import (
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
-	models "github.com/serg2014/shortener/internal/models"
-	storage "github.com/serg2014/shortener/internal/storage"
+	storage "github.com/serg2014/shortener/storage"
 )
 
 // MockStorager is a mock of Storager interface.
@@ -36,6 +35,20 @@ func (m *MockStorager) EXPECT() *MockStoragerMockRecorder {
 	return m.recorder
 }
 
+// CheckMigrations mocks base method.
+func (m *MockStorager) CheckMigrations(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckMigrations", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckMigrations indicates an expected call of CheckMigrations.
+func (mr *MockStoragerMockRecorder) CheckMigrations(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckMigrations", reflect.TypeOf((*MockStorager)(nil).CheckMigrations), ctx)
+}
+
 // Close mocks base method.
 func (m *MockStorager) Close() error {
 	m.ctrl.T.Helper()
@@ -51,17 +64,17 @@ func (mr *MockStoragerMockRecorder) Close() *gomock.Call {
 }
 
 // DeleteUserURLS mocks base method.
-func (m *MockStorager) DeleteUserURLS(ctx context.Context, req models.RequestForDeleteURLS, userID string) error {
+func (m *MockStorager) DeleteUserURLS(ctx context.Context, batch []storage.Message) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteUserURLS", ctx, req, userID)
+	ret := m.ctrl.Call(m, "DeleteUserURLS", ctx, batch)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // DeleteUserURLS indicates an expected call of DeleteUserURLS.
-func (mr *MockStoragerMockRecorder) DeleteUserURLS(ctx, req, userID interface{}) *gomock.Call {
+func (mr *MockStoragerMockRecorder) DeleteUserURLS(ctx, batch interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUserURLS", reflect.TypeOf((*MockStorager)(nil).DeleteUserURLS), ctx, req, userID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUserURLS", reflect.TypeOf((*MockStorager)(nil).DeleteUserURLS), ctx, batch)
 }
 
 // Get mocks base method.
@@ -112,10 +125,10 @@ func (mr *MockStoragerMockRecorder) GetUserURLS(ctx, userID interface{}) *gomock
 }
 
 // InternalStats mocks base method.
-func (m *MockStorager) InternalStats(ctx context.Context) (*models.InternalStats, error) {
+func (m *MockStorager) InternalStats(ctx context.Context) (*storage.InternalStats, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "InternalStats", ctx)
-	ret0, _ := ret[0].(*models.InternalStats)
+	ret0, _ := ret[0].(*storage.InternalStats)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -167,3 +180,40 @@ func (mr *MockStoragerMockRecorder) SetBatch(ctx, data, userID interface{}) *gom
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetBatch", reflect.TypeOf((*MockStorager)(nil).SetBatch), ctx, data, userID)
 }
+
+// MockUnwrapper is a mock of Unwrapper interface.
+type MockUnwrapper struct {
+	ctrl     *gomock.Controller
+	recorder *MockUnwrapperMockRecorder
+}
+
+// MockUnwrapperMockRecorder is the mock recorder for MockUnwrapper.
+type MockUnwrapperMockRecorder struct {
+	mock *MockUnwrapper
+}
+
+// NewMockUnwrapper creates a new mock instance.
+func NewMockUnwrapper(ctrl *gomock.Controller) *MockUnwrapper {
+	mock := &MockUnwrapper{ctrl: ctrl}
+	mock.recorder = &MockUnwrapperMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUnwrapper) EXPECT() *MockUnwrapperMockRecorder {
+	return m.recorder
+}
+
+// Unwrap mocks base method.
+func (m *MockUnwrapper) Unwrap() storage.Storager {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Unwrap")
+	ret0, _ := ret[0].(storage.Storager)
+	return ret0
+}
+
+// Unwrap indicates an expected call of Unwrap.
+func (mr *MockUnwrapperMockRecorder) Unwrap() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unwrap", reflect.TypeOf((*MockUnwrapper)(nil).Unwrap))
+}
@@ -0,0 +1,199 @@
+package storage
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"regexp"
+	"slices"
+)
+
+// Reasons of disabled url
+const (
+	// DisabledGone url is served as 410
+	DisabledGone = "gone"
+	// DisabledLegal url is served as 451
+	DisabledLegal = "legal"
+)
+
+// ErrDisabled use this error for request url disabled by moderator
+var ErrDisabled = errors.New("data disabled")
+
+// ErrDisabledLegal use this error for request url disabled by moderator for legal reasons
+var ErrDisabledLegal = errors.New("data disabled for legal reasons")
+
+// disabledError error of Get for reason of disabled url
+func disabledError(reason string) error {
+	if reason == DisabledLegal {
+		return ErrDisabledLegal
+	}
+	return ErrDisabled
+}
+
+// ModerationStorage is implemented by storages which support admin api: search, disabled urls and bans
+type ModerationStorage interface {
+	SearchURLS(ctx context.Context, filter URLFilter) ([]URLInfo, error)
+	DisableURL(ctx context.Context, key, reason string) error
+	SetUserBanned(ctx context.Context, userID string, banned bool) error
+	IsUserBanned(ctx context.Context, userID string) (bool, error)
+}
+
+// HitsStorage is implemented by storages which count redirects of urls
+type HitsStorage interface {
+	AddHits(ctx context.Context, hits map[string]uint64) error
+	TopURLS(ctx context.Context, limit int) ([]URLInfo, error)
+}
+
+// URLFilter filter of SearchURLS. Empty fields match all urls
+type URLFilter struct {
+	// Domain host of orig url. Subdomains match too
+	Domain string
+	UserID string
+	Limit  int
+}
+
+// URLInfo url with data for moderators
+type URLInfo struct {
+	ShortURL    string
+	OriginalURL string
+	UserID      string
+	Deleted     bool
+	// Disabled reason of disabling, empty for enabled url
+	Disabled string
+	// Hits count of redirects
+	Hits uint64
+}
+
+// domainPattern regexp matching urls with host domain or its subdomains.
+// Syntax is common for go and postgres
+func domainPattern(domain string) string {
+	return `^[a-z][a-z0-9+.-]*://([^/?#@]*@)?([^/?#@]*\.)?` + regexp.QuoteMeta(domain) + `(:[0-9]+)?([/?#]|$)`
+}
+
+// sortByHits sort urls by hits desc, then by short url
+func sortByHits(urls []URLInfo) {
+	slices.SortFunc(urls, func(a, b URLInfo) int {
+		if c := cmp.Compare(b.Hits, a.Hits); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ShortURL, b.ShortURL)
+	})
+}
+
+// urlInfo return data of url. Lock must be held
+func (s *storage) urlInfo(key, userID string) URLInfo {
+	_, deleted := s.deleted[key]
+	return URLInfo{
+		ShortURL:    key,
+		OriginalURL: s.short2orig[key],
+		UserID:      userID,
+		Deleted:     deleted,
+		Disabled:    s.disabled[key],
+		Hits:        s.hits[key],
+	}
+}
+
+// SearchURLS return urls of all users matched by filter sorted by short url
+func (s *storage) SearchURLS(ctx context.Context, filter URLFilter) ([]URLInfo, error) {
+	var domain *regexp.Regexp
+	if filter.Domain != "" {
+		domain = regexp.MustCompile("(?i)" + domainPattern(filter.Domain))
+	}
+	s.m.RLock()
+	defer s.m.RUnlock()
+	result := make([]URLInfo, 0)
+	for userID, urls := range s.users {
+		if filter.UserID != "" && filter.UserID != userID {
+			continue
+		}
+		for key, url := range urls {
+			if domain != nil && !domain.MatchString(url) {
+				continue
+			}
+			result = append(result, s.urlInfo(key, userID))
+		}
+	}
+	slices.SortFunc(result, func(a, b URLInfo) int { return cmp.Compare(a.ShortURL, b.ShortURL) })
+	if filter.Limit > 0 && len(result) > filter.Limit {
+		result = result[:filter.Limit]
+	}
+	return result, nil
+}
+
+func (s *storage) disableURL(key, reason string) error {
+	if _, ok := s.short2orig[key]; !ok {
+		return ErrNotFound
+	}
+	if reason == "" {
+		delete(s.disabled, key)
+		return nil
+	}
+	s.disabled[key] = reason
+	return nil
+}
+
+// DisableURL disable url with reason. Empty reason enables url. Return ErrNotFound for unknown url
+func (s *storage) DisableURL(ctx context.Context, key, reason string) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return s.disableURL(key, reason)
+}
+
+func (s *storage) setUserBanned(userID string, banned bool) {
+	if banned {
+		s.banned[userID] = struct{}{}
+		return
+	}
+	delete(s.banned, userID)
+}
+
+// SetUserBanned ban or unban user. Banned user can not create urls
+func (s *storage) SetUserBanned(ctx context.Context, userID string, banned bool) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.setUserBanned(userID, banned)
+	return nil
+}
+
+// IsUserBanned user is banned
+func (s *storage) IsUserBanned(ctx context.Context, userID string) (bool, error) {
+	s.m.RLock()
+	defer s.m.RUnlock()
+	_, ok := s.banned[userID]
+	return ok, nil
+}
+
+func (s *storage) addHits(hits map[string]uint64) {
+	for key, n := range hits {
+		if _, ok := s.short2orig[key]; ok {
+			s.hits[key] += n
+		}
+	}
+}
+
+// AddHits add counts of redirects to urls. Unknown urls are skipped
+func (s *storage) AddHits(ctx context.Context, hits map[string]uint64) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.addHits(hits)
+	return nil
+}
+
+// TopURLS return limit urls with max count of redirects
+func (s *storage) TopURLS(ctx context.Context, limit int) ([]URLInfo, error) {
+	s.m.RLock()
+	defer s.m.RUnlock()
+	result := make([]URLInfo, 0, len(s.hits))
+	for userID, urls := range s.users {
+		for key := range urls {
+			if s.hits[key] > 0 {
+				result = append(result, s.urlInfo(key, userID))
+			}
+		}
+	}
+	sortByHits(result)
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
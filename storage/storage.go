@@ -0,0 +1,305 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"sync"
+
+	"github.com/serg2014/shortener/internal/models"
+)
+
+// Short2orig stuct for memory storage
+type Short2orig map[string]string
+type orig2short map[string]string
+type users map[string]Short2orig
+type storage struct {
+	// TODO неоптимально по памяти
+	users      users
+	short2orig Short2orig
+	orig2short orig2short
+	deleted    map[string]struct{}
+	apiKeys    map[string]*APIKey
+	accounts   map[string]*Account
+	// accountUsers login of account by userid
+	accountUsers map[string]string
+	// disabled reason by short url disabled by moderator
+	disabled map[string]string
+	banned   map[string]struct{}
+	// hits count of redirects by short url
+	hits map[string]uint64
+	m    sync.RWMutex
+}
+
+// Message type. Request of user for delete his urls
+type Message struct {
+	UserID   string
+	ShortURL []string
+}
+
+// NewStorage create storage one of type *storage, *storageFile, *storageDB
+func NewStorage(ctx context.Context, filePath string, dsn string) (Storager, error) {
+	if dsn != "" {
+		return NewStorageDB(ctx, dsn)
+	}
+	if filePath != "" {
+		return NewStorageFile(filePath)
+	}
+	return NewStorageMemory()
+}
+
+// NewStorageMemory create memory storage type *storage
+func NewStorageMemory() (Storager, error) {
+	return newStorageMemory(), nil
+}
+
+func newStorageMemory() *storage {
+	return &storage{
+		short2orig:   make(Short2orig),
+		orig2short:   make(orig2short),
+		users:        make(users),
+		deleted:      make(map[string]struct{}),
+		apiKeys:      make(map[string]*APIKey),
+		accounts:     make(map[string]*Account),
+		accountUsers: make(map[string]string),
+		disabled:     make(map[string]string),
+		banned:       make(map[string]struct{}),
+		hits:         make(map[string]uint64),
+	}
+}
+
+// Get return orig url by short
+func (s *storage) Get(ctx context.Context, key string) (string, bool, error) {
+	s.m.RLock()
+	defer s.m.RUnlock()
+	if _, ok := s.deleted[key]; ok {
+		return "", false, ErrDeleted
+	}
+	if reason, ok := s.disabled[key]; ok {
+		return "", false, disabledError(reason)
+	}
+	v, ok := s.short2orig[key]
+	return v, ok, nil
+}
+
+// GetUserURLS find all user data in storage
+func (s *storage) GetUserURLS(ctx context.Context, userID string) ([]Item, error) {
+	s.m.RLock()
+	defer s.m.RUnlock()
+
+	v, ok := s.users[userID]
+	if !ok {
+		return make([]Item, 0), nil
+	}
+	result := make([]Item, 0, len(v))
+	for short, url := range v {
+		if _, ok := s.deleted[short]; ok {
+			continue
+		}
+		result = append(result, Item{ShortURL: short, OriginalURL: url})
+	}
+	return result, nil
+}
+
+// GetShort return short url by orig from storage
+func (s *storage) GetShort(ctx context.Context, origURL string) (string, bool, error) {
+	s.m.RLock()
+	defer s.m.RUnlock()
+	v, ok := s.orig2short[origURL]
+	return v, ok, nil
+}
+
+func (s *storage) set(key string, value string, userID string) error {
+	if _, ok := s.orig2short[value]; ok {
+		return ErrConflict
+	}
+	if _, ok := s.short2orig[key]; ok {
+		return ErrKeyExists
+	}
+
+	s.short2orig[key] = value
+	s.orig2short[value] = key
+	if _, ok := s.users[userID]; !ok {
+		s.users[userID] = make(Short2orig)
+	}
+	s.users[userID][key] = value
+	return nil
+}
+
+// Set save record in storage
+func (s *storage) Set(ctx context.Context, key string, value string, userID string) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return s.set(key, value, userID)
+}
+
+// setBatch save records which orig url is not stored yet. Return saved records
+func (s *storage) setBatch(data Short2orig, userID string) Short2orig {
+	saved := make(Short2orig, len(data))
+	for key, value := range data {
+		// как и в бд, уже сохраненные урлы пропускаем
+		if _, ok := s.orig2short[value]; ok {
+			continue
+		}
+		if _, ok := s.short2orig[key]; ok {
+			continue
+		}
+		s.short2orig[key] = value
+		s.orig2short[value] = key
+		saved[key] = value
+	}
+	if len(saved) == 0 {
+		return saved
+	}
+	if _, ok := s.users[userID]; !ok {
+		s.users[userID] = make(Short2orig)
+	}
+	maps.Copy(s.users[userID], saved)
+	return saved
+}
+
+// SetBatch save records in storage. Records with already stored orig url are skipped
+func (s *storage) SetBatch(ctx context.Context, data Short2orig, userID string) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.setBatch(data, userID)
+	return nil
+}
+
+// Close close connect to file/db
+func (s *storage) Close() error {
+	return nil
+}
+
+// Ping check connect ot db
+func (s *storage) Ping(ctx context.Context) error {
+	return nil
+}
+
+// CheckMigrations memory and file have no migrations
+func (s *storage) CheckMigrations(ctx context.Context) error {
+	return nil
+}
+
+// deleteUserURLS mark urls as deleted. Return deleted urls.
+// User can delete only his own urls
+func (s *storage) deleteUserURLS(batch []Message) []Message {
+	result := make([]Message, 0, len(batch))
+	for _, mes := range batch {
+		urls := s.users[mes.UserID]
+		deleted := Message{UserID: mes.UserID}
+		for _, key := range mes.ShortURL {
+			if _, ok := urls[key]; !ok {
+				continue
+			}
+			if _, ok := s.deleted[key]; ok {
+				continue
+			}
+			s.deleted[key] = struct{}{}
+			deleted.ShortURL = append(deleted.ShortURL, key)
+		}
+		if len(deleted.ShortURL) != 0 {
+			result = append(result, deleted)
+		}
+	}
+	return result
+}
+
+// DeleteUserURLS delete urls for users
+func (s *storage) DeleteUserURLS(ctx context.Context, batch []Message) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.deleteUserURLS(batch)
+	return nil
+}
+
+// ForEachKey call fn for every short url including deleted ones
+func (s *storage) ForEachKey(ctx context.Context, fn func(key string)) error {
+	s.m.RLock()
+	defer s.m.RUnlock()
+	for key := range s.short2orig {
+		fn(key)
+	}
+	return nil
+}
+
+// InternalStats get stat
+func (s *storage) InternalStats(ctx context.Context) (*models.InternalStats, error) {
+	s.m.RLock()
+	defer s.m.RUnlock()
+
+	return &models.InternalStats{
+		Urls:  uint(len(s.short2orig)),
+		Users: uint(len(s.users)),
+	}, nil
+}
+
+// InternalStats stats of storage. Alias lets backends outside of module implement Storager
+type InternalStats = models.InternalStats
+
+// Storager interface
+type Storager interface {
+	Get(ctx context.Context, key string) (string, bool, error)
+	GetUserURLS(ctx context.Context, userID string) ([]Item, error)
+	GetShort(ctx context.Context, origURL string) (string, bool, error)
+	Set(ctx context.Context, key string, value string, userID string) error
+	SetBatch(ctx context.Context, data Short2orig, userID string) error
+	Close() error
+	Ping(ctx context.Context) error
+	// CheckMigrations return ErrMigrations if schema is older than migrations of app or is dirty
+	CheckMigrations(ctx context.Context) error
+	DeleteUserURLS(ctx context.Context, batch []Message) error
+	InternalStats(ctx context.Context) (*InternalStats, error)
+}
+
+// ErrNotSupported storage does not implement optional interface, see Find
+var ErrNotSupported = errors.New("not supported by storage")
+
+// Unwrapper is implemented by storages which wrap other storage
+type Unwrapper interface {
+	Unwrap() Storager
+}
+
+// findStorage find storage implementing T in the chain of wrapped storages
+func findStorage[T any](store Storager) (T, bool) {
+	for store != nil {
+		if found, ok := store.(T); ok {
+			return found, true
+		}
+		u, ok := store.(Unwrapper)
+		if !ok {
+			break
+		}
+		store = u.Unwrap()
+	}
+	var zero T
+	return zero, false
+}
+
+// Find find storage implementing optional interface T (APIKeyStorage, AccountStorage,
+// ModerationStorage, HitsStorage) in the chain of wrapped storages.
+// Wrappers forward optional methods, so T is found only if the innermost storage implements it
+func Find[T any](store Storager) (T, bool) {
+	inner := store
+	for {
+		u, ok := inner.(Unwrapper)
+		if !ok {
+			break
+		}
+		inner = u.Unwrap()
+	}
+	if _, ok := inner.(T); !ok {
+		var zero T
+		return zero, false
+	}
+	return findStorage[T](store)
+}
+
+// optional return wrapped storage implementing T for forwarding by wrappers. ErrNotSupported if there is no one
+func optional[T any](store Storager) (T, error) {
+	found, ok := Find[T](store)
+	if !ok {
+		return found, ErrNotSupported
+	}
+	return found, nil
+}
@@ -0,0 +1,491 @@
+/*
+Package storagetest provide conformance tests for implementations of storage.Storager.
+The package is public, so backends outside of this module can run the suite too.
+
+Every backend should pass the same suite:
+
+	func TestMyStorage(t *testing.T) {
+		storagetest.Run(t, func(t *testing.T) storage.Storager {
+			return newEmptyMyStorage(t)
+		})
+	}
+
+Keys used by the suite have length storage.KeyLength. Tests of optional interfaces
+(storage.APIKeyStorage, storage.AccountStorage...) are skipped for backends which do not implement them.
+*/
+package storagetest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/serg2014/shortener/storage"
+)
+
+// Factory return new empty storage. Called once for every test of the suite
+type Factory func(t *testing.T) storage.Storager
+
+// Run runs all conformance tests for storages created by newStorage
+func Run(t *testing.T, newStorage Factory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s storage.Storager)
+	}{
+		{name: "Ping", fn: testPing},
+		{name: "GetUnknown", fn: testGetUnknown},
+		{name: "SetGet", fn: testSetGet},
+		{name: "SetConflict", fn: testSetConflict},
+		{name: "SetKeyExists", fn: testSetKeyExists},
+		{name: "SetBatch", fn: testSetBatch},
+		{name: "SetBatchConflict", fn: testSetBatchConflict},
+		{name: "GetUserURLS", fn: testGetUserURLS},
+		{name: "DeleteUserURLS", fn: testDeleteUserURLS},
+		{name: "DeleteForeignURLS", fn: testDeleteForeignURLS},
+		{name: "InternalStats", fn: testInternalStats},
+		{name: "APIKeys", fn: optional(testAPIKeys)},
+		{name: "RevokeForeignAPIKey", fn: optional(testRevokeForeignAPIKey)},
+		{name: "Accounts", fn: optional(testAccounts)},
+		{name: "MergeUserURLS", fn: optional(testMergeUserURLS)},
+		{name: "SearchURLS", fn: optional(testSearchURLS)},
+		{name: "DisableURL", fn: optional(testDisableURL)},
+		{name: "BanUser", fn: optional(testBanUser)},
+		{name: "TopURLS", fn: optional(testTopURLS)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newStorage(t)
+			t.Cleanup(func() { s.Close() })
+			test.fn(t, s)
+		})
+	}
+}
+
+// optional run fn with optional interface T of storage. Test is skipped if storage does not implement T
+func optional[T any](fn func(t *testing.T, s storage.Storager, c T)) func(t *testing.T, s storage.Storager) {
+	return func(t *testing.T, s storage.Storager) {
+		c, ok := storage.Find[T](s)
+		if !ok {
+			t.Skipf("storage does not implement %T", (*T)(nil))
+		}
+		fn(t, s, c)
+	}
+}
+
+func testPing(t *testing.T, s storage.Storager) {
+	require.NoError(t, s.Ping(t.Context()))
+}
+
+func testGetUnknown(t *testing.T, s storage.Storager) {
+	val, ok, err := s.Get(t.Context(), "unknown1")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, "", val)
+
+	short, ok, err := s.GetShort(t.Context(), "http://unknown.ru")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, "", short)
+}
+
+func testSetGet(t *testing.T, s storage.Storager) {
+	require.NoError(t, s.Set(t.Context(), "a1234567", "http://one.ru", "user1"))
+
+	val, ok, err := s.Get(t.Context(), "a1234567")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "http://one.ru", val)
+
+	short, ok, err := s.GetShort(t.Context(), "http://one.ru")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "a1234567", short)
+}
+
+func testSetConflict(t *testing.T, s storage.Storager) {
+	require.NoError(t, s.Set(t.Context(), "a1234567", "http://one.ru", "user1"))
+
+	err := s.Set(t.Context(), "b1234567", "http://one.ru", "user2")
+	require.ErrorIs(t, err, storage.ErrConflict)
+
+	// первая запись не изменилась, вторая не сохранилась
+	short, ok, err := s.GetShort(t.Context(), "http://one.ru")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "a1234567", short)
+
+	_, ok, err = s.Get(t.Context(), "b1234567")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func testSetKeyExists(t *testing.T, s storage.Storager) {
+	require.NoError(t, s.Set(t.Context(), "a1234567", "http://one.ru", "user1"))
+
+	err := s.Set(t.Context(), "a1234567", "http://two.ru", "user2")
+	require.ErrorIs(t, err, storage.ErrKeyExists)
+
+	// ключ по-прежнему указывает на первый урл
+	val, ok, err := s.Get(t.Context(), "a1234567")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "http://one.ru", val)
+
+	_, ok, err = s.GetShort(t.Context(), "http://two.ru")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func testSetBatch(t *testing.T, s storage.Storager) {
+	data := storage.Short2orig{
+		"a1234567": "http://one.ru",
+		"a1234568": "http://two.ru",
+		"a1234569": "http://three.ru",
+	}
+	require.NoError(t, s.SetBatch(t.Context(), data, "user1"))
+
+	for key, value := range data {
+		val, ok, err := s.Get(t.Context(), key)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, value, val)
+	}
+}
+
+func testSetBatchConflict(t *testing.T, s storage.Storager) {
+	require.NoError(t, s.Set(t.Context(), "a1234567", "http://one.ru", "user1"))
+
+	data := storage.Short2orig{
+		"b1234567": "http://one.ru",
+		"b1234568": "http://two.ru",
+	}
+	require.NoError(t, s.SetBatch(t.Context(), data, "user2"))
+
+	// уже сохраненный урл пропускается, остальные сохраняются
+	short, ok, err := s.GetShort(t.Context(), "http://one.ru")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "a1234567", short)
+
+	_, ok, err = s.Get(t.Context(), "b1234567")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	val, ok, err := s.Get(t.Context(), "b1234568")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "http://two.ru", val)
+}
+
+// userURLS return user urls without UserID, backends may not fill it
+func userURLS(t *testing.T, s storage.Storager, userID string) []storage.Item {
+	items, err := s.GetUserURLS(t.Context(), userID)
+	require.NoError(t, err)
+	for i := range items {
+		items[i].UserID = ""
+	}
+	return items
+}
+
+func testGetUserURLS(t *testing.T, s storage.Storager) {
+	require.NoError(t, s.Set(t.Context(), "a1234567", "http://one.ru", "user1"))
+	require.NoError(t, s.SetBatch(t.Context(), storage.Short2orig{"a1234568": "http://two.ru"}, "user1"))
+	require.NoError(t, s.Set(t.Context(), "b1234567", "http://three.ru", "user2"))
+
+	assert.ElementsMatch(t, []storage.Item{
+		{ShortURL: "a1234567", OriginalURL: "http://one.ru"},
+		{ShortURL: "a1234568", OriginalURL: "http://two.ru"},
+	}, userURLS(t, s, "user1"))
+	assert.ElementsMatch(t, []storage.Item{
+		{ShortURL: "b1234567", OriginalURL: "http://three.ru"},
+	}, userURLS(t, s, "user2"))
+	assert.Empty(t, userURLS(t, s, "user3"))
+}
+
+func testDeleteUserURLS(t *testing.T, s storage.Storager) {
+	require.NoError(t, s.SetBatch(t.Context(), storage.Short2orig{
+		"a1234567": "http://one.ru",
+		"a1234568": "http://two.ru",
+	}, "user1"))
+	require.NoError(t, s.Set(t.Context(), "b1234567", "http://three.ru", "user2"))
+
+	// в одном батче запросы разных пользователей
+	err := s.DeleteUserURLS(t.Context(), []storage.Message{
+		{UserID: "user1", ShortURL: []string{"a1234567"}},
+		{UserID: "user2", ShortURL: []string{"b1234567", "unknown1"}},
+	})
+	require.NoError(t, err)
+
+	for _, key := range []string{"a1234567", "b1234567"} {
+		_, ok, err := s.Get(t.Context(), key)
+		assert.ErrorIs(t, err, storage.ErrDeleted, key)
+		assert.False(t, ok, key)
+	}
+	val, ok, err := s.Get(t.Context(), "a1234568")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "http://two.ru", val)
+
+	// удаленные урлы не показываются пользователю
+	assert.ElementsMatch(t, []storage.Item{
+		{ShortURL: "a1234568", OriginalURL: "http://two.ru"},
+	}, userURLS(t, s, "user1"))
+	assert.Empty(t, userURLS(t, s, "user2"))
+
+	// повторное удаление не ошибка
+	err = s.DeleteUserURLS(t.Context(), []storage.Message{
+		{UserID: "user1", ShortURL: []string{"a1234567"}},
+	})
+	require.NoError(t, err)
+}
+
+func testDeleteForeignURLS(t *testing.T, s storage.Storager) {
+	require.NoError(t, s.Set(t.Context(), "a1234567", "http://one.ru", "user1"))
+
+	// пользователь не может удалить чужой урл
+	err := s.DeleteUserURLS(t.Context(), []storage.Message{
+		{UserID: "user2", ShortURL: []string{"a1234567"}},
+	})
+	require.NoError(t, err)
+
+	val, ok, err := s.Get(t.Context(), "a1234567")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "http://one.ru", val)
+}
+
+func testInternalStats(t *testing.T, s storage.Storager) {
+	stats, err := s.InternalStats(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, uint(0), stats.Urls)
+	assert.Equal(t, uint(0), stats.Users)
+
+	require.NoError(t, s.SetBatch(t.Context(), storage.Short2orig{
+		"a1234567": "http://one.ru",
+		"a1234568": "http://two.ru",
+	}, "user1"))
+	require.NoError(t, s.Set(t.Context(), "b1234567", "http://three.ru", "user2"))
+
+	stats, err = s.InternalStats(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, uint(3), stats.Urls)
+	assert.Equal(t, uint(2), stats.Users)
+}
+
+func testAPIKeys(t *testing.T, s storage.Storager, apiKeys storage.APIKeyStorage) {
+	// postgres хранит время с точностью до микросекунд
+	created := time.Now().UTC().Truncate(time.Second)
+	key := storage.APIKey{
+		ID:        "k1",
+		UserID:    "user1",
+		Name:      "backend",
+		Hash:      "hash1",
+		Scopes:    []string{"create", "read"},
+		CreatedAt: created,
+	}
+	require.NoError(t, apiKeys.CreateAPIKey(t.Context(), key))
+	assert.ErrorIs(t, apiKeys.CreateAPIKey(t.Context(), key), storage.ErrConflict)
+
+	_, ok, err := apiKeys.GetAPIKey(t.Context(), "unknown")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	got, ok, err := apiKeys.GetAPIKey(t.Context(), "k1")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, key.Name, got.Name)
+	assert.Equal(t, key.Hash, got.Hash)
+	assert.Equal(t, key.Scopes, got.Scopes)
+	assert.True(t, created.Equal(got.CreatedAt))
+	assert.True(t, got.LastUsedAt.IsZero())
+	assert.False(t, got.Revoked())
+
+	used := created.Add(time.Minute)
+	require.NoError(t, apiKeys.TouchAPIKey(t.Context(), "k1", used))
+	// более раннее время не затирает последнее использование
+	require.NoError(t, apiKeys.TouchAPIKey(t.Context(), "k1", created))
+	got, _, err = apiKeys.GetAPIKey(t.Context(), "k1")
+	require.NoError(t, err)
+	assert.True(t, used.Equal(got.LastUsedAt))
+
+	require.NoError(t, apiKeys.CreateAPIKey(t.Context(), storage.APIKey{
+		ID: "k2", UserID: "user1", Name: "stats", Hash: "hash2", Scopes: []string{"stats"}, CreatedAt: created.Add(time.Second),
+	}))
+	keys, err := apiKeys.GetUserAPIKeys(t.Context(), "user1")
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.Equal(t, "k1", keys[0].ID)
+	assert.Equal(t, "k2", keys[1].ID)
+
+	revoked := created.Add(2 * time.Minute)
+	require.NoError(t, apiKeys.RevokeAPIKey(t.Context(), "user1", "k1", revoked))
+	// повторный отзыв не ошибка и не меняет время отзыва
+	require.NoError(t, apiKeys.RevokeAPIKey(t.Context(), "user1", "k1", revoked.Add(time.Minute)))
+	got, _, err = apiKeys.GetAPIKey(t.Context(), "k1")
+	require.NoError(t, err)
+	assert.True(t, got.Revoked())
+	assert.True(t, revoked.Equal(got.RevokedAt))
+
+	keys, err = apiKeys.GetUserAPIKeys(t.Context(), "user2")
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+}
+
+func testRevokeForeignAPIKey(t *testing.T, s storage.Storager, apiKeys storage.APIKeyStorage) {
+	require.NoError(t, apiKeys.CreateAPIKey(t.Context(), storage.APIKey{
+		ID: "k1", UserID: "user1", Name: "backend", Hash: "hash1", Scopes: []string{"read"}, CreatedAt: time.Now(),
+	}))
+
+	// пользователь не может отозвать чужой ключ
+	assert.ErrorIs(t, apiKeys.RevokeAPIKey(t.Context(), "user2", "k1", time.Now()), storage.ErrNotFound)
+	assert.ErrorIs(t, apiKeys.RevokeAPIKey(t.Context(), "user1", "unknown", time.Now()), storage.ErrNotFound)
+
+	got, _, err := apiKeys.GetAPIKey(t.Context(), "k1")
+	require.NoError(t, err)
+	assert.False(t, got.Revoked())
+}
+
+func testAccounts(t *testing.T, s storage.Storager, accounts storage.AccountStorage) {
+	_, ok, err := accounts.GetAccount(t.Context(), "alice")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	account := storage.Account{
+		Login: "alice", UserID: "user1", PasswordHash: "hash1", CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+	}
+	require.NoError(t, accounts.CreateAccount(t.Context(), account))
+
+	got, ok, err := accounts.GetAccount(t.Context(), "alice")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, account.UserID, got.UserID)
+	assert.Equal(t, account.PasswordHash, got.PasswordHash)
+	assert.True(t, account.CreatedAt.Equal(got.CreatedAt))
+
+	got, ok, err = accounts.GetAccountByUserID(t.Context(), "user1")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, "alice", got.Login)
+	_, ok, err = accounts.GetAccountByUserID(t.Context(), "user2")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// логин и userid уникальны
+	assert.ErrorIs(t, accounts.CreateAccount(t.Context(), storage.Account{Login: "alice", UserID: "user2", PasswordHash: "hash2"}), storage.ErrConflict)
+	assert.ErrorIs(t, accounts.CreateAccount(t.Context(), storage.Account{Login: "bob", UserID: "user1", PasswordHash: "hash2"}), storage.ErrConflict)
+}
+
+func testMergeUserURLS(t *testing.T, s storage.Storager, accounts storage.AccountStorage) {
+	require.NoError(t, s.Set(t.Context(), "a1234567", "http://one.ru", "anon"))
+	require.NoError(t, s.Set(t.Context(), "b1234567", "http://two.ru", "user1"))
+
+	require.NoError(t, accounts.MergeUserURLS(t.Context(), "anon", "user1"))
+	// пользователь без ссылок
+	require.NoError(t, accounts.MergeUserURLS(t.Context(), "unknown", "user1"))
+
+	assert.ElementsMatch(t, []storage.Item{
+		{ShortURL: "a1234567", OriginalURL: "http://one.ru"},
+		{ShortURL: "b1234567", OriginalURL: "http://two.ru"},
+	}, userURLS(t, s, "user1"))
+	assert.Empty(t, userURLS(t, s, "anon"))
+
+	// перенесенные ссылки удаляет новый владелец
+	require.NoError(t, s.DeleteUserURLS(t.Context(), []storage.Message{{UserID: "user1", ShortURL: []string{"a1234567"}}}))
+	_, _, err := s.Get(t.Context(), "a1234567")
+	assert.ErrorIs(t, err, storage.ErrDeleted)
+}
+
+func testSearchURLS(t *testing.T, s storage.Storager, moderation storage.ModerationStorage) {
+	require.NoError(t, s.Set(t.Context(), "a1234567", "https://example.com/one", "user1"))
+	require.NoError(t, s.Set(t.Context(), "a1234568", "http://sub.Example.com:8080?q=1", "user2"))
+	require.NoError(t, s.Set(t.Context(), "a1234569", "https://notexample.com/", "user1"))
+	require.NoError(t, s.Set(t.Context(), "a1234570", "https://other.ru/example.com", "user1"))
+
+	shorts := func(filter storage.URLFilter) []string {
+		urls, err := moderation.SearchURLS(t.Context(), filter)
+		require.NoError(t, err)
+		result := make([]string, 0, len(urls))
+		for _, url := range urls {
+			result = append(result, url.ShortURL)
+		}
+		return result
+	}
+	assert.Equal(t, []string{"a1234567", "a1234568"}, shorts(storage.URLFilter{Domain: "example.com"}))
+	assert.Equal(t, []string{"a1234567", "a1234569", "a1234570"}, shorts(storage.URLFilter{UserID: "user1"}))
+	assert.Equal(t, []string{"a1234567"}, shorts(storage.URLFilter{Domain: "example.com", UserID: "user1"}))
+	assert.Equal(t, []string{"a1234567", "a1234568"}, shorts(storage.URLFilter{Limit: 2}))
+	assert.Empty(t, shorts(storage.URLFilter{UserID: "user3"}))
+
+	urls, err := moderation.SearchURLS(t.Context(), storage.URLFilter{Domain: "other.ru"})
+	require.NoError(t, err)
+	assert.Equal(t, []storage.URLInfo{{ShortURL: "a1234570", OriginalURL: "https://other.ru/example.com", UserID: "user1"}}, urls)
+}
+
+func testDisableURL(t *testing.T, s storage.Storager, moderation storage.ModerationStorage) {
+	require.NoError(t, s.Set(t.Context(), "a1234567", "http://one.ru", "user1"))
+	require.NoError(t, s.Set(t.Context(), "a1234568", "http://two.ru", "user1"))
+
+	require.NoError(t, moderation.DisableURL(t.Context(), "a1234567", storage.DisabledGone))
+	require.NoError(t, moderation.DisableURL(t.Context(), "a1234568", storage.DisabledLegal))
+	assert.ErrorIs(t, moderation.DisableURL(t.Context(), "unknown1", storage.DisabledGone), storage.ErrNotFound)
+
+	_, _, err := s.Get(t.Context(), "a1234567")
+	assert.ErrorIs(t, err, storage.ErrDisabled)
+	_, _, err = s.Get(t.Context(), "a1234568")
+	assert.ErrorIs(t, err, storage.ErrDisabledLegal)
+
+	urls, err := moderation.SearchURLS(t.Context(), storage.URLFilter{UserID: "user1"})
+	require.NoError(t, err)
+	require.Len(t, urls, 2)
+	assert.Equal(t, storage.DisabledGone, urls[0].Disabled)
+
+	// пустая причина включает урл
+	require.NoError(t, moderation.DisableURL(t.Context(), "a1234567", ""))
+	val, ok, err := s.Get(t.Context(), "a1234567")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "http://one.ru", val)
+}
+
+func testBanUser(t *testing.T, s storage.Storager, moderation storage.ModerationStorage) {
+	banned, err := moderation.IsUserBanned(t.Context(), "user1")
+	require.NoError(t, err)
+	assert.False(t, banned)
+
+	require.NoError(t, moderation.SetUserBanned(t.Context(), "user1", true))
+	require.NoError(t, moderation.SetUserBanned(t.Context(), "user1", true))
+	banned, err = moderation.IsUserBanned(t.Context(), "user1")
+	require.NoError(t, err)
+	assert.True(t, banned)
+	banned, err = moderation.IsUserBanned(t.Context(), "user2")
+	require.NoError(t, err)
+	assert.False(t, banned)
+
+	require.NoError(t, moderation.SetUserBanned(t.Context(), "user1", false))
+	banned, err = moderation.IsUserBanned(t.Context(), "user1")
+	require.NoError(t, err)
+	assert.False(t, banned)
+}
+
+func testTopURLS(t *testing.T, s storage.Storager, hits storage.HitsStorage) {
+	require.NoError(t, s.Set(t.Context(), "a1234567", "http://one.ru", "user1"))
+	require.NoError(t, s.Set(t.Context(), "a1234568", "http://two.ru", "user2"))
+	require.NoError(t, s.Set(t.Context(), "a1234569", "http://three.ru", "user2"))
+
+	require.NoError(t, hits.AddHits(t.Context(), map[string]uint64{"a1234567": 1, "a1234568": 2, "unknown1": 10}))
+	require.NoError(t, hits.AddHits(t.Context(), map[string]uint64{"a1234567": 2}))
+
+	top, err := hits.TopURLS(t.Context(), 10)
+	require.NoError(t, err)
+	assert.Equal(t, []storage.URLInfo{
+		{ShortURL: "a1234567", OriginalURL: "http://one.ru", UserID: "user1", Hits: 3},
+		{ShortURL: "a1234568", OriginalURL: "http://two.ru", UserID: "user2", Hits: 2},
+	}, top)
+
+	top, err = hits.TopURLS(t.Context(), 1)
+	require.NoError(t, err)
+	require.Len(t, top, 1)
+	assert.Equal(t, "a1234567", top[0].ShortURL)
+}
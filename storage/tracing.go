@@ -0,0 +1,304 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/serg2014/shortener/internal/models"
+	"github.com/serg2014/shortener/internal/tracing"
+)
+
+// tracedStorage create child span for every method of wrapped storage
+type tracedStorage struct {
+	Storager
+	backend string
+}
+
+// NewTracedStorage wrap store with spans of its methods
+func NewTracedStorage(store Storager) Storager {
+	return &tracedStorage{Storager: store, backend: backendName(store)}
+}
+
+// Unwrap return wrapped storage
+func (s *tracedStorage) Unwrap() Storager {
+	return s.Storager
+}
+
+// start start span of method of storage
+func (s *tracedStorage) start(ctx context.Context, method string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(ctx, "storage."+method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(append(attrs, backendAttr(s.backend))...),
+	)
+}
+
+// backendAttr attribute of span with backend of storage
+func backendAttr(backend string) attribute.KeyValue {
+	return attribute.String("storage.backend", backend)
+}
+
+// end end span. Expected answers of storage do not mark span as error
+func end(span trace.Span, err error) {
+	if !isFailure(err) {
+		err = nil
+	}
+	tracing.End(span, err)
+}
+
+// Get implement Get of Storager
+func (s *tracedStorage) Get(ctx context.Context, key string) (string, bool, error) {
+	ctx, span := s.start(ctx, "Get")
+	value, ok, err := s.Storager.Get(ctx, key)
+	span.SetAttributes(attribute.Bool("storage.found", ok))
+	end(span, err)
+	return value, ok, err
+}
+
+// GetUserURLS implement GetUserURLS of Storager
+func (s *tracedStorage) GetUserURLS(ctx context.Context, userID string) ([]Item, error) {
+	ctx, span := s.start(ctx, "GetUserURLS")
+	items, err := s.Storager.GetUserURLS(ctx, userID)
+	span.SetAttributes(attribute.Int("storage.urls", len(items)))
+	end(span, err)
+	return items, err
+}
+
+// GetShort implement GetShort of Storager
+func (s *tracedStorage) GetShort(ctx context.Context, origURL string) (string, bool, error) {
+	ctx, span := s.start(ctx, "GetShort")
+	key, ok, err := s.Storager.GetShort(ctx, origURL)
+	end(span, err)
+	return key, ok, err
+}
+
+// Set implement Set of Storager
+func (s *tracedStorage) Set(ctx context.Context, key string, value string, userID string) error {
+	ctx, span := s.start(ctx, "Set")
+	err := s.Storager.Set(ctx, key, value, userID)
+	end(span, err)
+	return err
+}
+
+// SetBatch implement SetBatch of Storager
+func (s *tracedStorage) SetBatch(ctx context.Context, data Short2orig, userID string) error {
+	ctx, span := s.start(ctx, "SetBatch", attribute.Int("storage.urls", len(data)))
+	err := s.Storager.SetBatch(ctx, data, userID)
+	end(span, err)
+	return err
+}
+
+// Ping implement Ping of Storager
+func (s *tracedStorage) Ping(ctx context.Context) error {
+	ctx, span := s.start(ctx, "Ping")
+	err := s.Storager.Ping(ctx)
+	end(span, err)
+	return err
+}
+
+// CheckMigrations implement CheckMigrations of Storager
+func (s *tracedStorage) CheckMigrations(ctx context.Context) error {
+	ctx, span := s.start(ctx, "CheckMigrations")
+	err := s.Storager.CheckMigrations(ctx)
+	end(span, err)
+	return err
+}
+
+// DeleteUserURLS implement DeleteUserURLS of Storager
+func (s *tracedStorage) DeleteUserURLS(ctx context.Context, batch []Message) error {
+	ctx, span := s.start(ctx, "DeleteUserURLS", attribute.Int("storage.messages", len(batch)))
+	err := s.Storager.DeleteUserURLS(ctx, batch)
+	end(span, err)
+	return err
+}
+
+// InternalStats implement InternalStats of Storager
+func (s *tracedStorage) InternalStats(ctx context.Context) (*models.InternalStats, error) {
+	ctx, span := s.start(ctx, "InternalStats")
+	stats, err := s.Storager.InternalStats(ctx)
+	end(span, err)
+	return stats, err
+}
+
+// CreateAPIKey implement CreateAPIKey of APIKeyStorage
+func (s *tracedStorage) CreateAPIKey(ctx context.Context, key APIKey) error {
+	store, err := optional[APIKeyStorage](s.Storager)
+	if err != nil {
+		return err
+	}
+	ctx, span := s.start(ctx, "CreateAPIKey")
+	err = store.CreateAPIKey(ctx, key)
+	end(span, err)
+	return err
+}
+
+// GetAPIKey implement GetAPIKey of APIKeyStorage
+func (s *tracedStorage) GetAPIKey(ctx context.Context, id string) (APIKey, bool, error) {
+	store, err := optional[APIKeyStorage](s.Storager)
+	if err != nil {
+		return APIKey{}, false, err
+	}
+	ctx, span := s.start(ctx, "GetAPIKey")
+	key, ok, err := store.GetAPIKey(ctx, id)
+	end(span, err)
+	return key, ok, err
+}
+
+// GetUserAPIKeys implement GetUserAPIKeys of APIKeyStorage
+func (s *tracedStorage) GetUserAPIKeys(ctx context.Context, userID string) ([]APIKey, error) {
+	store, err := optional[APIKeyStorage](s.Storager)
+	if err != nil {
+		return nil, err
+	}
+	ctx, span := s.start(ctx, "GetUserAPIKeys")
+	keys, err := store.GetUserAPIKeys(ctx, userID)
+	end(span, err)
+	return keys, err
+}
+
+// RevokeAPIKey implement RevokeAPIKey of APIKeyStorage
+func (s *tracedStorage) RevokeAPIKey(ctx context.Context, userID, id string, at time.Time) error {
+	store, err := optional[APIKeyStorage](s.Storager)
+	if err != nil {
+		return err
+	}
+	ctx, span := s.start(ctx, "RevokeAPIKey")
+	err = store.RevokeAPIKey(ctx, userID, id, at)
+	end(span, err)
+	return err
+}
+
+// TouchAPIKey implement TouchAPIKey of APIKeyStorage
+func (s *tracedStorage) TouchAPIKey(ctx context.Context, id string, at time.Time) error {
+	store, err := optional[APIKeyStorage](s.Storager)
+	if err != nil {
+		return err
+	}
+	ctx, span := s.start(ctx, "TouchAPIKey")
+	err = store.TouchAPIKey(ctx, id, at)
+	end(span, err)
+	return err
+}
+
+// CreateAccount implement CreateAccount of AccountStorage
+func (s *tracedStorage) CreateAccount(ctx context.Context, account Account) error {
+	store, err := optional[AccountStorage](s.Storager)
+	if err != nil {
+		return err
+	}
+	ctx, span := s.start(ctx, "CreateAccount")
+	err = store.CreateAccount(ctx, account)
+	end(span, err)
+	return err
+}
+
+// GetAccount implement GetAccount of AccountStorage
+func (s *tracedStorage) GetAccount(ctx context.Context, login string) (Account, bool, error) {
+	store, err := optional[AccountStorage](s.Storager)
+	if err != nil {
+		return Account{}, false, err
+	}
+	ctx, span := s.start(ctx, "GetAccount")
+	account, ok, err := store.GetAccount(ctx, login)
+	end(span, err)
+	return account, ok, err
+}
+
+// GetAccountByUserID implement GetAccountByUserID of AccountStorage
+func (s *tracedStorage) GetAccountByUserID(ctx context.Context, userID string) (Account, bool, error) {
+	store, err := optional[AccountStorage](s.Storager)
+	if err != nil {
+		return Account{}, false, err
+	}
+	ctx, span := s.start(ctx, "GetAccountByUserID")
+	account, ok, err := store.GetAccountByUserID(ctx, userID)
+	end(span, err)
+	return account, ok, err
+}
+
+// MergeUserURLS implement MergeUserURLS of AccountStorage
+func (s *tracedStorage) MergeUserURLS(ctx context.Context, fromUserID, toUserID string) error {
+	store, err := optional[AccountStorage](s.Storager)
+	if err != nil {
+		return err
+	}
+	ctx, span := s.start(ctx, "MergeUserURLS")
+	err = store.MergeUserURLS(ctx, fromUserID, toUserID)
+	end(span, err)
+	return err
+}
+
+// SearchURLS implement SearchURLS of ModerationStorage
+func (s *tracedStorage) SearchURLS(ctx context.Context, filter URLFilter) ([]URLInfo, error) {
+	store, err := optional[ModerationStorage](s.Storager)
+	if err != nil {
+		return nil, err
+	}
+	ctx, span := s.start(ctx, "SearchURLS")
+	urls, err := store.SearchURLS(ctx, filter)
+	span.SetAttributes(attribute.Int("storage.urls", len(urls)))
+	end(span, err)
+	return urls, err
+}
+
+// DisableURL implement DisableURL of ModerationStorage
+func (s *tracedStorage) DisableURL(ctx context.Context, key, reason string) error {
+	store, err := optional[ModerationStorage](s.Storager)
+	if err != nil {
+		return err
+	}
+	ctx, span := s.start(ctx, "DisableURL")
+	err = store.DisableURL(ctx, key, reason)
+	end(span, err)
+	return err
+}
+
+// SetUserBanned implement SetUserBanned of ModerationStorage
+func (s *tracedStorage) SetUserBanned(ctx context.Context, userID string, banned bool) error {
+	store, err := optional[ModerationStorage](s.Storager)
+	if err != nil {
+		return err
+	}
+	ctx, span := s.start(ctx, "SetUserBanned")
+	err = store.SetUserBanned(ctx, userID, banned)
+	end(span, err)
+	return err
+}
+
+// IsUserBanned implement IsUserBanned of ModerationStorage
+func (s *tracedStorage) IsUserBanned(ctx context.Context, userID string) (bool, error) {
+	store, err := optional[ModerationStorage](s.Storager)
+	if err != nil {
+		return false, err
+	}
+	ctx, span := s.start(ctx, "IsUserBanned")
+	banned, err := store.IsUserBanned(ctx, userID)
+	end(span, err)
+	return banned, err
+}
+
+// AddHits implement AddHits of HitsStorage
+func (s *tracedStorage) AddHits(ctx context.Context, hits map[string]uint64) error {
+	store, err := optional[HitsStorage](s.Storager)
+	if err != nil {
+		return err
+	}
+	ctx, span := s.start(ctx, "AddHits", attribute.Int("storage.urls", len(hits)))
+	err = store.AddHits(ctx, hits)
+	end(span, err)
+	return err
+}
+
+// TopURLS implement TopURLS of HitsStorage
+func (s *tracedStorage) TopURLS(ctx context.Context, limit int) ([]URLInfo, error) {
+	store, err := optional[HitsStorage](s.Storager)
+	if err != nil {
+		return nil, err
+	}
+	ctx, span := s.start(ctx, "TopURLS")
+	urls, err := store.TopURLS(ctx, limit)
+	end(span, err)
+	return urls, err
+}
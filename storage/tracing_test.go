@@ -0,0 +1,35 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracedStorage(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	defer otel.SetTracerProvider(prev)
+
+	s := NewTracedStorage(NewCachedStorage(t.Context(), newStorageMemory(), 10, 0, 0))
+	ctx, parent := otel.Tracer("test").Start(t.Context(), "request")
+	require.NoError(t, s.Set(ctx, "a1234567", "http://one.ru", "user1"))
+	require.ErrorIs(t, s.Set(ctx, "b1234567", "http://one.ru", "user1"), ErrConflict)
+	parent.End()
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 3)
+	for _, span := range spans[:2] {
+		assert.Equal(t, "storage.Set", span.Name)
+		// дочерний span запроса, конфликт не ошибка хранилища
+		assert.Equal(t, parent.SpanContext().SpanID(), span.Parent.SpanID())
+		assert.NotEqual(t, codes.Error, span.Status.Code)
+		assert.Contains(t, span.Attributes, backendAttr(BackendMemory))
+	}
+}